@@ -0,0 +1,250 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"time"
+
+	pb "github.com/wealdtech/eth2-signer-api/pb/v1"
+	"google.golang.org/grpc"
+)
+
+// ClearLockoutRequest is a request to clear a lockout caused by repeated failed attempts to unlock an account.
+type ClearLockoutRequest struct {
+	// Account is the account, or wallet, for which to clear the lockout.
+	Account string `json:"account"`
+	// Client is the client for which to clear the lockout; optional.
+	Client string `json:"client,omitempty"`
+}
+
+// ClearLockoutResponse is the response to a request to clear a lockout.
+type ClearLockoutResponse struct {
+	State pb.ResponseState `json:"state"`
+	// Cleared is true if a lockout or backoff was in place and has been cleared.
+	Cleared bool `json:"cleared"`
+}
+
+// ListApprovalsRequest is a request to list the requests awaiting approval.
+type ListApprovalsRequest struct{}
+
+// Approval is a request awaiting approval.
+type Approval struct {
+	ID      string    `json:"id"`
+	Client  string    `json:"client"`
+	Account string    `json:"account"`
+	Action  string    `json:"action"`
+	Root    []byte    `json:"root"`
+	Expires time.Time `json:"expires"`
+	// Required is the number of distinct approvals required for the request.
+	Required int `json:"required"`
+	// Approvers are the clients that have approved the request so far.
+	Approvers []string `json:"approvers"`
+}
+
+// ListApprovalsResponse is the response to a request to list the requests awaiting approval.
+// Only requests that the client is permitted to approve are returned.
+type ListApprovalsResponse struct {
+	State     pb.ResponseState `json:"state"`
+	Approvals []*Approval      `json:"approvals"`
+}
+
+// ApproveRequest is a request to approve a request awaiting approval.
+type ApproveRequest struct {
+	ID string `json:"id"`
+}
+
+// ApproveResponse is the response to a request to approve a request awaiting approval.
+type ApproveResponse struct {
+	State pb.ResponseState `json:"state"`
+	// Approved is true if the request has received all of its required approvals.
+	Approved bool `json:"approved"`
+}
+
+// RejectRequest is a request to reject a request awaiting approval.
+type RejectRequest struct {
+	ID string `json:"id"`
+}
+
+// RejectResponse is the response to a request to reject a request awaiting approval.
+type RejectResponse struct {
+	State pb.ResponseState `json:"state"`
+}
+
+// AdminServer is the server API for the admin service.
+type AdminServer interface {
+	ClearLockout(context.Context, *ClearLockoutRequest) (*ClearLockoutResponse, error)
+	ListApprovals(context.Context, *ListApprovalsRequest) (*ListApprovalsResponse, error)
+	Approve(context.Context, *ApproveRequest) (*ApproveResponse, error)
+	Reject(context.Context, *RejectRequest) (*RejectResponse, error)
+}
+
+// RegisterAdminServer registers an admin server with a gRPC server.
+func RegisterAdminServer(s *grpc.Server, srv AdminServer) {
+	s.RegisterService(&adminServiceDesc, srv)
+}
+
+// AdminClient is the client API for the admin service.
+type AdminClient interface {
+	ClearLockout(ctx context.Context, in *ClearLockoutRequest, opts ...grpc.CallOption) (*ClearLockoutResponse, error)
+	ListApprovals(ctx context.Context, in *ListApprovalsRequest, opts ...grpc.CallOption) (*ListApprovalsResponse, error)
+	Approve(ctx context.Context, in *ApproveRequest, opts ...grpc.CallOption) (*ApproveResponse, error)
+	Reject(ctx context.Context, in *RejectRequest, opts ...grpc.CallOption) (*RejectResponse, error)
+}
+
+type adminClient struct {
+	cc *grpc.ClientConn
+}
+
+// NewAdminClient creates a new client for the admin service.
+func NewAdminClient(cc *grpc.ClientConn) AdminClient {
+	return &adminClient{cc: cc}
+}
+
+// ClearLockout clears a lockout.
+func (c *adminClient) ClearLockout(ctx context.Context, in *ClearLockoutRequest, opts ...grpc.CallOption) (*ClearLockoutResponse, error) {
+	out := new(ClearLockoutResponse)
+	opts = append([]grpc.CallOption{grpc.CallContentSubtype(ContentSubtype)}, opts...)
+	if err := c.cc.Invoke(ctx, "/walletd.v1.Admin/ClearLockout", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListApprovals lists the requests awaiting approval.
+func (c *adminClient) ListApprovals(ctx context.Context, in *ListApprovalsRequest, opts ...grpc.CallOption) (*ListApprovalsResponse, error) {
+	out := new(ListApprovalsResponse)
+	opts = append([]grpc.CallOption{grpc.CallContentSubtype(ContentSubtype)}, opts...)
+	if err := c.cc.Invoke(ctx, "/walletd.v1.Admin/ListApprovals", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Approve approves a request awaiting approval.
+func (c *adminClient) Approve(ctx context.Context, in *ApproveRequest, opts ...grpc.CallOption) (*ApproveResponse, error) {
+	out := new(ApproveResponse)
+	opts = append([]grpc.CallOption{grpc.CallContentSubtype(ContentSubtype)}, opts...)
+	if err := c.cc.Invoke(ctx, "/walletd.v1.Admin/Approve", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Reject rejects a request awaiting approval.
+func (c *adminClient) Reject(ctx context.Context, in *RejectRequest, opts ...grpc.CallOption) (*RejectResponse, error) {
+	out := new(RejectResponse)
+	opts = append([]grpc.CallOption{grpc.CallContentSubtype(ContentSubtype)}, opts...)
+	if err := c.cc.Invoke(ctx, "/walletd.v1.Admin/Reject", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func adminClearLockoutHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClearLockoutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).ClearLockout(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/walletd.v1.Admin/ClearLockout",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).ClearLockout(ctx, req.(*ClearLockoutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func adminListApprovalsHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListApprovalsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).ListApprovals(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/walletd.v1.Admin/ListApprovals",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).ListApprovals(ctx, req.(*ListApprovalsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func adminApproveHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ApproveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).Approve(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/walletd.v1.Admin/Approve",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).Approve(ctx, req.(*ApproveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func adminRejectHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RejectRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).Reject(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/walletd.v1.Admin/Reject",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).Reject(ctx, req.(*RejectRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var adminServiceDesc = grpc.ServiceDesc{
+	ServiceName: "walletd.v1.Admin",
+	HandlerType: (*AdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ClearLockout",
+			Handler:    adminClearLockoutHandler,
+		},
+		{
+			MethodName: "ListApprovals",
+			Handler:    adminListApprovalsHandler,
+		},
+		{
+			MethodName: "Approve",
+			Handler:    adminApproveHandler,
+		},
+		{
+			MethodName: "Reject",
+			Handler:    adminRejectHandler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "admin",
+}
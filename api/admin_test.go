@@ -0,0 +1,119 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	pb "github.com/wealdtech/eth2-signer-api/pb/v1"
+	"github.com/wealdtech/walletd/api"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type adminServer struct {
+	req        *api.ClearLockoutRequest
+	approveReq *api.ApproveRequest
+	rejectReq  *api.RejectRequest
+}
+
+func (s *adminServer) ClearLockout(ctx context.Context, req *api.ClearLockoutRequest) (*api.ClearLockoutResponse, error) {
+	s.req = req
+	return &api.ClearLockoutResponse{
+		State:   pb.ResponseState_SUCCEEDED,
+		Cleared: true,
+	}, nil
+}
+
+func (s *adminServer) ListApprovals(ctx context.Context, req *api.ListApprovalsRequest) (*api.ListApprovalsResponse, error) {
+	return &api.ListApprovalsResponse{
+		State: pb.ResponseState_SUCCEEDED,
+		Approvals: []*api.Approval{
+			{
+				ID:      "1234",
+				Client:  "client1",
+				Account: "Wallet/Account",
+				Action:  "Sign voluntary exit",
+				Root:    []byte{0x01},
+				Expires: time.Unix(1600000000, 0).UTC(),
+			},
+		},
+	}, nil
+}
+
+func (s *adminServer) Approve(ctx context.Context, req *api.ApproveRequest) (*api.ApproveResponse, error) {
+	s.approveReq = req
+	return &api.ApproveResponse{
+		State:    pb.ResponseState_SUCCEEDED,
+		Approved: true,
+	}, nil
+}
+
+func (s *adminServer) Reject(ctx context.Context, req *api.RejectRequest) (*api.RejectResponse, error) {
+	s.rejectReq = req
+	return &api.RejectResponse{
+		State: pb.ResponseState_SUCCEEDED,
+	}, nil
+}
+
+func TestAdmin(t *testing.T) {
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	srv := &adminServer{}
+	api.RegisterAdminServer(server, srv)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	defer server.Stop()
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return listener.Dial() }),
+		grpc.WithInsecure(),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := api.NewAdminClient(conn)
+	res, err := client.ClearLockout(context.Background(), &api.ClearLockoutRequest{
+		Account: "Wallet/Account",
+		Client:  "client1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, pb.ResponseState_SUCCEEDED, res.State)
+	assert.True(t, res.Cleared)
+	assert.Equal(t, &api.ClearLockoutRequest{Account: "Wallet/Account", Client: "client1"}, srv.req)
+
+	listRes, err := client.ListApprovals(context.Background(), &api.ListApprovalsRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, pb.ResponseState_SUCCEEDED, listRes.State)
+	require.Len(t, listRes.Approvals, 1)
+	assert.Equal(t, "1234", listRes.Approvals[0].ID)
+	assert.Equal(t, time.Unix(1600000000, 0).UTC(), listRes.Approvals[0].Expires)
+
+	approveRes, err := client.Approve(context.Background(), &api.ApproveRequest{ID: "1234"})
+	require.NoError(t, err)
+	assert.Equal(t, pb.ResponseState_SUCCEEDED, approveRes.State)
+	assert.True(t, approveRes.Approved)
+	assert.Equal(t, &api.ApproveRequest{ID: "1234"}, srv.approveReq)
+
+	rejectRes, err := client.Reject(context.Background(), &api.RejectRequest{ID: "5678"})
+	require.NoError(t, err)
+	assert.Equal(t, pb.ResponseState_SUCCEEDED, rejectRes.State)
+	assert.Equal(t, &api.RejectRequest{ID: "5678"}, srv.rejectReq)
+}
@@ -0,0 +1,48 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+
+	"google.golang.org/grpc/encoding"
+)
+
+// ContentSubtype is the gRPC content subtype used by the services in this package.
+// Messages are encoded as JSON rather than protobuf, so that the services can be defined without generated code.
+// The codec is registered under a name private to walletd, so that it does not replace any other JSON codec used by
+// the process; clients select it for each call.
+const ContentSubtype = "walletd-json"
+
+func init() {
+	encoding.RegisterCodec(codec{})
+}
+
+// codec is a gRPC codec that encodes messages as JSON.
+type codec struct{}
+
+// Marshal encodes a message as JSON.
+func (codec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal decodes a message from JSON.
+func (codec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// Name returns the name of the codec.
+func (codec) Name() string {
+	return ContentSubtype
+}
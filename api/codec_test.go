@@ -0,0 +1,30 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wealdtech/walletd/api"
+	"google.golang.org/grpc/encoding"
+)
+
+func TestCodec(t *testing.T) {
+	codec := encoding.GetCodec(api.ContentSubtype)
+	assert.NotNil(t, codec)
+	assert.Equal(t, api.ContentSubtype, codec.Name())
+	// The codec must not replace the process-wide JSON codec.
+	assert.Nil(t, encoding.GetCodec("json"))
+}
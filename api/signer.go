@@ -0,0 +1,397 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+
+	pb "github.com/wealdtech/eth2-signer-api/pb/v1"
+	"google.golang.org/grpc"
+)
+
+// SignRANDAORevealRequest is a request to sign a RANDAO reveal.
+type SignRANDAORevealRequest struct {
+	// Account is the name of the account with which to sign; either this or PublicKey must be supplied.
+	Account string `json:"account,omitempty"`
+	// PublicKey is the public key of the account with which to sign; either this or Account must be supplied.
+	PublicKey []byte `json:"public_key,omitempty"`
+	Domain    []byte `json:"domain"`
+	Epoch     uint64 `json:"epoch"`
+}
+
+// SignSelectionProofRequest is a request to sign an aggregation selection proof.
+type SignSelectionProofRequest struct {
+	// Account is the name of the account with which to sign; either this or PublicKey must be supplied.
+	Account string `json:"account,omitempty"`
+	// PublicKey is the public key of the account with which to sign; either this or Account must be supplied.
+	PublicKey []byte `json:"public_key,omitempty"`
+	Domain    []byte `json:"domain"`
+	Slot      uint64 `json:"slot"`
+}
+
+// SignAggregateAndProofRequest is a request to sign an aggregate and proof.
+type SignAggregateAndProofRequest struct {
+	// Account is the name of the account with which to sign; either this or PublicKey must be supplied.
+	Account string `json:"account,omitempty"`
+	// PublicKey is the public key of the account with which to sign; either this or Account must be supplied.
+	PublicKey       []byte       `json:"public_key,omitempty"`
+	Domain          []byte       `json:"domain"`
+	AggregatorIndex uint64       `json:"aggregator_index"`
+	Aggregate       *Attestation `json:"aggregate"`
+	SelectionProof  []byte       `json:"selection_proof"`
+}
+
+// Attestation is an aggregate attestation.
+type Attestation struct {
+	AggregationBits []byte           `json:"aggregation_bits"`
+	Data            *AttestationData `json:"data"`
+	Signature       []byte           `json:"signature"`
+}
+
+// AttestationData is the data of an attestation.
+type AttestationData struct {
+	Slot            uint64      `json:"slot"`
+	CommitteeIndex  uint64      `json:"committee_index"`
+	BeaconBlockRoot []byte      `json:"beacon_block_root"`
+	Source          *Checkpoint `json:"source"`
+	Target          *Checkpoint `json:"target"`
+}
+
+// Checkpoint is an attestation checkpoint.
+type Checkpoint struct {
+	Epoch uint64 `json:"epoch"`
+	Root  []byte `json:"root"`
+}
+
+// SignVoluntaryExitRequest is a request to sign a voluntary exit.
+type SignVoluntaryExitRequest struct {
+	// Account is the name of the account with which to sign; either this or PublicKey must be supplied.
+	Account string `json:"account,omitempty"`
+	// PublicKey is the public key of the account with which to sign; either this or Account must be supplied.
+	PublicKey      []byte `json:"public_key,omitempty"`
+	Domain         []byte `json:"domain"`
+	Epoch          uint64 `json:"epoch"`
+	ValidatorIndex uint64 `json:"validator_index"`
+}
+
+// SignDepositRequest is a request to sign deposit data.
+type SignDepositRequest struct {
+	// Account is the name of the account with which to sign; either this or PublicKey must be supplied.
+	Account string `json:"account,omitempty"`
+	// PublicKey is the public key of the account with which to sign; either this or Account must be supplied.
+	PublicKey []byte `json:"public_key,omitempty"`
+	Domain    []byte `json:"domain"`
+	// ValidatorPublicKey is the public key of the validator being deposited.
+	ValidatorPublicKey    []byte `json:"validator_public_key"`
+	WithdrawalCredentials []byte `json:"withdrawal_credentials"`
+	Amount                uint64 `json:"amount"`
+}
+
+// SignBeaconAttestationRequest is a request to sign a beacon attestation.
+type SignBeaconAttestationRequest struct {
+	// Account is the name of the account with which to sign; either this or PublicKey must be supplied.
+	Account string `json:"account,omitempty"`
+	// PublicKey is the public key of the account with which to sign; either this or Account must be supplied.
+	PublicKey []byte           `json:"public_key,omitempty"`
+	Domain    []byte           `json:"domain"`
+	Data      *AttestationData `json:"data"`
+}
+
+// SignBeaconProposalRequest is a request to sign a beacon proposal.
+type SignBeaconProposalRequest struct {
+	// Account is the name of the account with which to sign; either this or PublicKey must be supplied.
+	Account string `json:"account,omitempty"`
+	// PublicKey is the public key of the account with which to sign; either this or Account must be supplied.
+	PublicKey []byte             `json:"public_key,omitempty"`
+	Domain    []byte             `json:"domain"`
+	Data      *BeaconBlockHeader `json:"data"`
+}
+
+// BeaconBlockHeader is the header of a beacon block.
+type BeaconBlockHeader struct {
+	Slot          uint64 `json:"slot"`
+	ProposerIndex uint64 `json:"proposer_index"`
+	ParentRoot    []byte `json:"parent_root"`
+	StateRoot     []byte `json:"state_root"`
+	BodyRoot      []byte `json:"body_root"`
+}
+
+// SignBatchRequest is a request to sign a number of beacon attestations and proposals.
+type SignBatchRequest struct {
+	Attestations []*SignBeaconAttestationRequest `json:"attestations,omitempty"`
+	Proposals    []*SignBeaconProposalRequest    `json:"proposals,omitempty"`
+}
+
+// SignBatchResponse is the response to a request to sign a number of beacon attestations and proposals.
+// Each request in the batch has its own response, in the same order as the requests.
+type SignBatchResponse struct {
+	Attestations []*SignResponse `json:"attestations"`
+	Proposals    []*SignResponse `json:"proposals"`
+}
+
+// ApprovalIDMetadataKey is the gRPC metadata key for the ID of an approval.
+// It is returned in the response header of signing requests that are awaiting approval, and can be supplied in the
+// request metadata when retrying the request to collect the signature.
+const ApprovalIDMetadataKey = "walletd-approval-id"
+
+// ResponseStatePending is the state of a request that is awaiting approval by an operator before it can complete.
+// It extends the response states of the signer API.
+const ResponseStatePending pb.ResponseState = 4
+
+// ResponseStateExpired is the state of a request that was cancelled, or whose deadline passed, before it completed.
+// It extends the response states of the signer API.
+const ResponseStateExpired pb.ResponseState = 5
+
+// SignResponse is the response to a signing request.
+type SignResponse struct {
+	State     pb.ResponseState `json:"state"`
+	Signature []byte           `json:"signature,omitempty"`
+	// ApprovalID is the ID of the approval required for the request to complete, if any.
+	ApprovalID string `json:"approval_id,omitempty"`
+}
+
+// SignerServer is the server API for the typed signer service.
+type SignerServer interface {
+	SignRANDAOReveal(context.Context, *SignRANDAORevealRequest) (*SignResponse, error)
+	SignSelectionProof(context.Context, *SignSelectionProofRequest) (*SignResponse, error)
+	SignAggregateAndProof(context.Context, *SignAggregateAndProofRequest) (*SignResponse, error)
+	SignVoluntaryExit(context.Context, *SignVoluntaryExitRequest) (*SignResponse, error)
+	SignDeposit(context.Context, *SignDepositRequest) (*SignResponse, error)
+	SignBatch(context.Context, *SignBatchRequest) (*SignBatchResponse, error)
+}
+
+// RegisterSignerServer registers a typed signer server with a gRPC server.
+func RegisterSignerServer(s *grpc.Server, srv SignerServer) {
+	s.RegisterService(&signerServiceDesc, srv)
+}
+
+// SignerClient is the client API for the typed signer service.
+type SignerClient interface {
+	SignRANDAOReveal(ctx context.Context, in *SignRANDAORevealRequest, opts ...grpc.CallOption) (*SignResponse, error)
+	SignSelectionProof(ctx context.Context, in *SignSelectionProofRequest, opts ...grpc.CallOption) (*SignResponse, error)
+	SignAggregateAndProof(ctx context.Context, in *SignAggregateAndProofRequest, opts ...grpc.CallOption) (*SignResponse, error)
+	SignVoluntaryExit(ctx context.Context, in *SignVoluntaryExitRequest, opts ...grpc.CallOption) (*SignResponse, error)
+	SignDeposit(ctx context.Context, in *SignDepositRequest, opts ...grpc.CallOption) (*SignResponse, error)
+	SignBatch(ctx context.Context, in *SignBatchRequest, opts ...grpc.CallOption) (*SignBatchResponse, error)
+}
+
+type signerClient struct {
+	cc *grpc.ClientConn
+}
+
+// NewSignerClient creates a new client for the typed signer service.
+func NewSignerClient(cc *grpc.ClientConn) SignerClient {
+	return &signerClient{cc: cc}
+}
+
+// SignRANDAOReveal signs a RANDAO reveal.
+func (c *signerClient) SignRANDAOReveal(ctx context.Context, in *SignRANDAORevealRequest, opts ...grpc.CallOption) (*SignResponse, error) {
+	out := new(SignResponse)
+	opts = append([]grpc.CallOption{grpc.CallContentSubtype(ContentSubtype)}, opts...)
+	if err := c.cc.Invoke(ctx, "/walletd.v1.Signer/SignRANDAOReveal", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SignSelectionProof signs an aggregation selection proof.
+func (c *signerClient) SignSelectionProof(ctx context.Context, in *SignSelectionProofRequest, opts ...grpc.CallOption) (*SignResponse, error) {
+	out := new(SignResponse)
+	opts = append([]grpc.CallOption{grpc.CallContentSubtype(ContentSubtype)}, opts...)
+	if err := c.cc.Invoke(ctx, "/walletd.v1.Signer/SignSelectionProof", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SignAggregateAndProof signs an aggregate and proof.
+func (c *signerClient) SignAggregateAndProof(ctx context.Context, in *SignAggregateAndProofRequest, opts ...grpc.CallOption) (*SignResponse, error) {
+	out := new(SignResponse)
+	opts = append([]grpc.CallOption{grpc.CallContentSubtype(ContentSubtype)}, opts...)
+	if err := c.cc.Invoke(ctx, "/walletd.v1.Signer/SignAggregateAndProof", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SignVoluntaryExit signs a voluntary exit.
+func (c *signerClient) SignVoluntaryExit(ctx context.Context, in *SignVoluntaryExitRequest, opts ...grpc.CallOption) (*SignResponse, error) {
+	out := new(SignResponse)
+	opts = append([]grpc.CallOption{grpc.CallContentSubtype(ContentSubtype)}, opts...)
+	if err := c.cc.Invoke(ctx, "/walletd.v1.Signer/SignVoluntaryExit", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SignDeposit signs deposit data.
+func (c *signerClient) SignDeposit(ctx context.Context, in *SignDepositRequest, opts ...grpc.CallOption) (*SignResponse, error) {
+	out := new(SignResponse)
+	opts = append([]grpc.CallOption{grpc.CallContentSubtype(ContentSubtype)}, opts...)
+	if err := c.cc.Invoke(ctx, "/walletd.v1.Signer/SignDeposit", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SignBatch signs a number of beacon attestations and proposals.
+func (c *signerClient) SignBatch(ctx context.Context, in *SignBatchRequest, opts ...grpc.CallOption) (*SignBatchResponse, error) {
+	out := new(SignBatchResponse)
+	opts = append([]grpc.CallOption{grpc.CallContentSubtype(ContentSubtype)}, opts...)
+	if err := c.cc.Invoke(ctx, "/walletd.v1.Signer/SignBatch", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func signerSignRANDAORevealHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SignRANDAORevealRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SignerServer).SignRANDAOReveal(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/walletd.v1.Signer/SignRANDAOReveal",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SignerServer).SignRANDAOReveal(ctx, req.(*SignRANDAORevealRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func signerSignSelectionProofHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SignSelectionProofRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SignerServer).SignSelectionProof(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/walletd.v1.Signer/SignSelectionProof",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SignerServer).SignSelectionProof(ctx, req.(*SignSelectionProofRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func signerSignAggregateAndProofHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SignAggregateAndProofRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SignerServer).SignAggregateAndProof(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/walletd.v1.Signer/SignAggregateAndProof",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SignerServer).SignAggregateAndProof(ctx, req.(*SignAggregateAndProofRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func signerSignVoluntaryExitHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SignVoluntaryExitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SignerServer).SignVoluntaryExit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/walletd.v1.Signer/SignVoluntaryExit",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SignerServer).SignVoluntaryExit(ctx, req.(*SignVoluntaryExitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func signerSignDepositHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SignDepositRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SignerServer).SignDeposit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/walletd.v1.Signer/SignDeposit",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SignerServer).SignDeposit(ctx, req.(*SignDepositRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func signerSignBatchHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SignBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SignerServer).SignBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/walletd.v1.Signer/SignBatch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SignerServer).SignBatch(ctx, req.(*SignBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var signerServiceDesc = grpc.ServiceDesc{
+	ServiceName: "walletd.v1.Signer",
+	HandlerType: (*SignerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SignRANDAOReveal",
+			Handler:    signerSignRANDAORevealHandler,
+		},
+		{
+			MethodName: "SignSelectionProof",
+			Handler:    signerSignSelectionProofHandler,
+		},
+		{
+			MethodName: "SignAggregateAndProof",
+			Handler:    signerSignAggregateAndProofHandler,
+		},
+		{
+			MethodName: "SignVoluntaryExit",
+			Handler:    signerSignVoluntaryExitHandler,
+		},
+		{
+			MethodName: "SignDeposit",
+			Handler:    signerSignDepositHandler,
+		},
+		{
+			MethodName: "SignBatch",
+			Handler:    signerSignBatchHandler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "signer",
+}
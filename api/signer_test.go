@@ -0,0 +1,162 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	pb "github.com/wealdtech/eth2-signer-api/pb/v1"
+	"github.com/wealdtech/walletd/api"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type signerServer struct {
+	req interface{}
+}
+
+func (s *signerServer) SignRANDAOReveal(ctx context.Context, req *api.SignRANDAORevealRequest) (*api.SignResponse, error) {
+	s.req = req
+	return &api.SignResponse{State: pb.ResponseState_SUCCEEDED, Signature: []byte{0x01}}, nil
+}
+
+func (s *signerServer) SignSelectionProof(ctx context.Context, req *api.SignSelectionProofRequest) (*api.SignResponse, error) {
+	s.req = req
+	return &api.SignResponse{State: pb.ResponseState_SUCCEEDED, Signature: []byte{0x02}}, nil
+}
+
+func (s *signerServer) SignAggregateAndProof(ctx context.Context, req *api.SignAggregateAndProofRequest) (*api.SignResponse, error) {
+	s.req = req
+	return &api.SignResponse{State: pb.ResponseState_SUCCEEDED, Signature: []byte{0x03}}, nil
+}
+
+func (s *signerServer) SignVoluntaryExit(ctx context.Context, req *api.SignVoluntaryExitRequest) (*api.SignResponse, error) {
+	s.req = req
+	return &api.SignResponse{State: api.ResponseStatePending, ApprovalID: "1234"}, nil
+}
+
+func (s *signerServer) SignDeposit(ctx context.Context, req *api.SignDepositRequest) (*api.SignResponse, error) {
+	s.req = req
+	return &api.SignResponse{State: pb.ResponseState_SUCCEEDED, Signature: []byte{0x05}}, nil
+}
+
+func (s *signerServer) SignBatch(ctx context.Context, req *api.SignBatchRequest) (*api.SignBatchResponse, error) {
+	s.req = req
+	return &api.SignBatchResponse{
+		Attestations: []*api.SignResponse{
+			{State: pb.ResponseState_SUCCEEDED, Signature: []byte{0x06}},
+			{State: pb.ResponseState_DENIED},
+		},
+		Proposals: []*api.SignResponse{
+			{State: pb.ResponseState_SUCCEEDED, Signature: []byte{0x07}},
+		},
+	}, nil
+}
+
+func TestSigner(t *testing.T) {
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	srv := &signerServer{}
+	api.RegisterSignerServer(server, srv)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	defer server.Stop()
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return listener.Dial() }),
+		grpc.WithInsecure(),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := api.NewSignerClient(conn)
+
+	aggregateReq := &api.SignAggregateAndProofRequest{
+		Account:         "Wallet/Account",
+		Domain:          []byte{0x06},
+		AggregatorIndex: 1,
+		Aggregate: &api.Attestation{
+			AggregationBits: []byte{0x01},
+			Data: &api.AttestationData{
+				Slot:   2,
+				Source: &api.Checkpoint{Epoch: 3},
+				Target: &api.Checkpoint{Epoch: 4},
+			},
+		},
+	}
+	res, err := client.SignAggregateAndProof(context.Background(), aggregateReq)
+	require.NoError(t, err)
+	assert.Equal(t, pb.ResponseState_SUCCEEDED, res.State)
+	assert.Equal(t, []byte{0x03}, res.Signature)
+	assert.Equal(t, aggregateReq, srv.req)
+
+	exitReq := &api.SignVoluntaryExitRequest{
+		PublicKey:      []byte{0x01, 0x02},
+		Epoch:          5,
+		ValidatorIndex: 6,
+	}
+	res, err = client.SignVoluntaryExit(context.Background(), exitReq)
+	require.NoError(t, err)
+	assert.Equal(t, api.ResponseStatePending, res.State)
+	assert.Nil(t, res.Signature)
+	assert.Equal(t, "1234", res.ApprovalID)
+	assert.Equal(t, exitReq, srv.req)
+
+	batchReq := &api.SignBatchRequest{
+		Attestations: []*api.SignBeaconAttestationRequest{
+			{
+				Account: "Wallet/Account 1",
+				Domain:  []byte{0x01},
+				Data: &api.AttestationData{
+					Slot:   7,
+					Source: &api.Checkpoint{Epoch: 0},
+					Target: &api.Checkpoint{Epoch: 1},
+				},
+			},
+			{
+				Account: "Wallet/Account 2",
+				Domain:  []byte{0x01},
+				Data: &api.AttestationData{
+					Slot:   7,
+					Source: &api.Checkpoint{Epoch: 0},
+					Target: &api.Checkpoint{Epoch: 1},
+				},
+			},
+		},
+		Proposals: []*api.SignBeaconProposalRequest{
+			{
+				Account: "Wallet/Account 1",
+				Domain:  []byte{0x00},
+				Data: &api.BeaconBlockHeader{
+					Slot:          8,
+					ProposerIndex: 9,
+				},
+			},
+		},
+	}
+	batchRes, err := client.SignBatch(context.Background(), batchReq)
+	require.NoError(t, err)
+	require.Len(t, batchRes.Attestations, 2)
+	assert.Equal(t, pb.ResponseState_SUCCEEDED, batchRes.Attestations[0].State)
+	assert.Equal(t, []byte{0x06}, batchRes.Attestations[0].Signature)
+	assert.Equal(t, pb.ResponseState_DENIED, batchRes.Attestations[1].State)
+	require.Len(t, batchRes.Proposals, 1)
+	assert.Equal(t, []byte{0x07}, batchRes.Proposals[0].Signature)
+	assert.Equal(t, batchReq, srv.req)
+}
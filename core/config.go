@@ -15,6 +15,7 @@ package core
 
 import (
 	"path/filepath"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/shibukawa/configdir"
@@ -23,10 +24,16 @@ import (
 
 // Config is the configuration for the daemon.
 type Config struct {
-	Verbosity string            `json:"verbosity"`
-	Server    *ServerConfig     `json:"server"`
-	Stores    []*Store          `json:"stores"`
-	Rules     []*RuleDefinition `json:"rules"`
+	Verbosity   string             `json:"verbosity"`
+	Server      *ServerConfig      `json:"server"`
+	Stores      []*Store           `json:"stores"`
+	Rules       []*RuleDefinition  `json:"rules"`
+	Helper      *HelperConfig      `json:"helper"`
+	Relock      *RelockConfig      `json:"relock"`
+	UnlockGuard *UnlockGuardConfig `json:"unlock_guard" mapstructure:"unlock_guard"`
+	Networks    []*NetworkConfig   `json:"networks"`
+	Sign        *SignConfig        `json:"sign"`
+	Approvals   *ApprovalsConfig   `json:"approvals"`
 }
 
 // ServerConfig contains configuration for the server.
@@ -35,6 +42,86 @@ type ServerConfig struct {
 	Port        int    `json:"port"`
 	CertPath    string `json:"certificate_path"`
 	StoragePath string `json:"storage_path"`
+	// IdentitySource is the part of the client certificate used to identify the client.
+	// One of "cn" (the default), "dns-san", "uri-san", "spiffe" or "fingerprint".
+	IdentitySource string `json:"identity_source" mapstructure:"identity_source"`
+	// CRLPath is the path to a certificate revocation list for client certificates.
+	CRLPath string `json:"crl_path" mapstructure:"crl_path"`
+	// DenyListPath is the path to a list of serial numbers and fingerprints of revoked client certificates.
+	DenyListPath string `json:"deny_list_path" mapstructure:"deny_list_path"`
+	// Web3Signer enables the Web3Signer-compatible HTTP API, if present.
+	Web3Signer *Web3SignerConfig `json:"web3signer"`
+}
+
+// Web3SignerConfig contains configuration for the Web3Signer-compatible HTTP API.
+// It uses the same certificates as the gRPC server.
+type Web3SignerConfig struct {
+	Port int `json:"port"`
+}
+
+// NetworkConfig contains the information required to calculate signing domains for a network.
+// Fork versions should be supplied in order, starting with the genesis fork version.
+// Slots per epoch defaults to 32 if not supplied.
+type NetworkConfig struct {
+	Name                  string   `json:"name"`
+	GenesisValidatorsRoot string   `json:"genesis_validators_root" mapstructure:"genesis_validators_root"`
+	ForkVersions          []string `json:"fork_versions" mapstructure:"fork_versions"`
+	SlotsPerEpoch         uint64   `json:"slots_per_epoch" mapstructure:"slots_per_epoch"`
+}
+
+// SignConfig contains configuration for signing requests.
+// AllowedDomainTypes are domain types reserved for beacon chain messages that can be signed by generic signing
+// requests, as hex strings.
+type SignConfig struct {
+	AllowedDomainTypes []string `json:"allowed_domain_types" mapstructure:"allowed_domain_types"`
+	// SignatureCache configures the cache of recent signatures; if not present the defaults are used.
+	SignatureCache *SignatureCacheConfig `json:"signature_cache" mapstructure:"signature_cache"`
+}
+
+// SignatureCacheConfig contains configuration for the cache of recent signatures.
+// Window is the time for which signatures are cached, and Size the number of signatures cached for each account.
+// A window or size of 0 disables the cache.
+type SignatureCacheConfig struct {
+	Window time.Duration `json:"window"`
+	Size   int           `json:"size"`
+}
+
+// HelperConfig contains configuration for an external helper that supplies passphrases.
+// Either an executable path or a Unix socket should be supplied.
+type HelperConfig struct {
+	Path     string        `json:"path"`
+	Args     []string      `json:"args"`
+	Socket   string        `json:"socket"`
+	Timeout  time.Duration `json:"timeout"`
+	CacheTTL time.Duration `json:"cache_ttl" mapstructure:"cache_ttl"`
+}
+
+// RelockConfig contains configuration for relocking unlocked accounts.
+// Accounts are relocked when they have not been used for the idle timeout, or when they have been unlocked for the
+// maximum lifetime, whichever comes first.  A zero value disables the relevant check.
+type RelockConfig struct {
+	IdleTimeout time.Duration `json:"idle_timeout" mapstructure:"idle_timeout"`
+	MaxLifetime time.Duration `json:"max_lifetime" mapstructure:"max_lifetime"`
+}
+
+// UnlockGuardConfig contains configuration for protection against repeated failed attempts to unlock accounts.
+// Failed attempts are tracked for both the client and the account.  After a number of free attempts, each further
+// failure blocks attempts for an exponentially increasing backoff period; after the lockout number of attempts,
+// attempts are blocked for the lockout duration.
+type UnlockGuardConfig struct {
+	FreeAttempts    int           `json:"free_attempts" mapstructure:"free_attempts"`
+	Backoff         time.Duration `json:"backoff"`
+	MaxBackoff      time.Duration `json:"max_backoff" mapstructure:"max_backoff"`
+	LockoutAttempts int           `json:"lockout_attempts" mapstructure:"lockout_attempts"`
+	LockoutDuration time.Duration `json:"lockout_duration" mapstructure:"lockout_duration"`
+}
+
+// ApprovalsConfig contains configuration for requests that require approval by operators before they complete.
+// Window is the time from the initial request within which the request must be approved and the result collected.
+// Required is the number of distinct operators that must approve each request.
+type ApprovalsConfig struct {
+	Window   time.Duration `json:"window"`
+	Required int           `json:"required"`
 }
 
 const (
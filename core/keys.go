@@ -23,7 +23,19 @@ import (
 
 // KeysConfig provides information about keys for automatic unlocking.
 type KeysConfig struct {
+	// Keys are tried against any account.
 	Keys []string `json:"keys"`
+	// Accounts are tried against specific accounts, before the general keys.
+	Accounts []*AccountKey `json:"accounts"`
+}
+
+// AccountKey provides a key for automatic unlocking of specific accounts.
+// Accounts are selected by either a path, which can contain regular expressions for the wallet and account names,
+// or a public key.
+type AccountKey struct {
+	Path       string `json:"path"`
+	PubKey     string `json:"pubkey"`
+	Passphrase string `json:"passphrase"`
 }
 
 // FetchKeysConfig fetches keys from the JSON configuration file.
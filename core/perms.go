@@ -15,10 +15,9 @@ package core
 
 import (
 	"encoding/json"
-	"fmt"
 	"io/ioutil"
 	"path/filepath"
-	"strings"
+	"time"
 
 	"github.com/shibukawa/configdir"
 )
@@ -29,15 +28,26 @@ type Permissions struct {
 }
 
 // CertificateInfo contains information related to client certificates.
+// If Fingerprint is set then the client's certificate must have the given SHA-256 fingerprint.
+// If Networks is set then the client can only sign for the named networks.
 type CertificateInfo struct {
-	Name  string              `json:"name"`
-	Perms []*CertificatePerms `json:"permissions"`
+	Name        string              `json:"name"`
+	Fingerprint string              `json:"fingerprint,omitempty"`
+	Networks    []string            `json:"networks,omitempty"`
+	Perms       []*CertificatePerms `json:"permissions"`
 }
 
 // CertificatePerms contains information about the operations allowed by the certificate.
+// If Deny is set then the operations are denied, regardless of any other permissions that allow them.
+// If NotBefore, NotAfter or SourceIPs are set then the permission only applies within the given times and to
+// requests from the given source IP addresses or CIDR ranges.
 type CertificatePerms struct {
-	Path       string   `json:"path"`
-	Operations []string `json:"operations"`
+	Path       string     `json:"path"`
+	Operations []string   `json:"operations"`
+	Deny       bool       `json:"deny,omitempty"`
+	NotBefore  *time.Time `json:"not_before,omitempty"`
+	NotAfter   *time.Time `json:"not_after,omitempty"`
+	SourceIPs  []string   `json:"source_ips,omitempty"`
 }
 
 // FetchPermissions fetches permissions from the JSON configuration file.
@@ -56,21 +66,3 @@ func FetchPermissions() (*Permissions, error) {
 	}
 	return perms, nil
 }
-
-// DumpPerms dumps information about our permissions to stdout.
-func DumpPerms(perms *Permissions) {
-	for i, certInfo := range perms.Certs {
-		if certInfo.Name == "" {
-			fmt.Printf("ERROR: certificate %d does not have a name\n", i)
-		} else {
-			fmt.Printf("Permissions for %q:\n", certInfo.Name)
-			for _, perm := range certInfo.Perms {
-				if len(perm.Operations) == 1 && perm.Operations[0] == "All" {
-					fmt.Printf("\t- accounts matching the path %q can carry out all operations\n", perm.Path)
-				} else {
-					fmt.Printf("\t- accounts matching the path %q can carry out operations: %s\n", perm.Path, strings.Join(perm.Operations, ", "))
-				}
-			}
-		}
-	}
-}
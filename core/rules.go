@@ -34,6 +34,8 @@ const (
 	APPROVED
 	DENIED
 	FAILED
+	PENDING
+	EXPIRED
 )
 
 // RuleDefinition defines a rule.
@@ -0,0 +1,149 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// Secret references.  A secret in the configuration can be supplied directly, or as a reference with one of the
+// following prefixes:
+//   - file:<path> reads the secret from the named file, for example a tmpfs or Kubernetes secret mount
+//   - env:<name> reads the secret from the named environment variable
+//   - prompt: or prompt:<text> prompts for the secret on standard input at startup
+const (
+	secretFilePrefix   = "file:"
+	secretEnvPrefix    = "env:"
+	secretPromptPrefix = "prompt:"
+)
+
+var (
+	stdinReader   *bufio.Reader
+	stdinReaderMx sync.Mutex
+	envSecrets    = make(map[string][]byte)
+	envSecretsMx  sync.Mutex
+)
+
+// ResolveSecret resolves a secret from its configuration value, which can be a literal secret or a reference.
+// The description is used in error messages and prompts.
+func ResolveSecret(ctx context.Context, description string, value string) ([]byte, error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "core.ResolveSecret")
+	defer span.Finish()
+
+	switch {
+	case strings.HasPrefix(value, secretFilePrefix):
+		path := strings.TrimPrefix(value, secretFilePrefix)
+		if path == "" {
+			return nil, fmt.Errorf("no file supplied for %s", description)
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("failed to read %s", description))
+		}
+		return trimSecret(data), nil
+	case strings.HasPrefix(value, secretEnvPrefix):
+		name := strings.TrimPrefix(value, secretEnvPrefix)
+		if name == "" {
+			return nil, fmt.Errorf("no environment variable supplied for %s", description)
+		}
+		return envSecret(name, description)
+	case strings.HasPrefix(value, secretPromptPrefix):
+		prompt := strings.TrimPrefix(value, secretPromptPrefix)
+		if prompt == "" {
+			prompt = fmt.Sprintf("Enter %s", description)
+		}
+		return promptSecret(description, prompt)
+	default:
+		return []byte(value), nil
+	}
+}
+
+// envSecret reads a secret from an environment variable.
+// The variable is removed from the environment once read, so that it is not inherited by child processes or visible
+// in the process environment.  Secrets read this way are remembered, so the same variable can be referenced again.
+func envSecret(name string, description string) ([]byte, error) {
+	envSecretsMx.Lock()
+	defer envSecretsMx.Unlock()
+
+	secret, exists := envSecrets[name]
+	if !exists {
+		value, exists := os.LookupEnv(name)
+		if !exists {
+			return nil, fmt.Errorf("environment variable %s for %s not set", name, description)
+		}
+		if err := os.Unsetenv(name); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("failed to remove environment variable %s", name))
+		}
+		secret = []byte(value)
+		envSecrets[name] = secret
+	}
+
+	res := make([]byte, len(secret))
+	copy(res, secret)
+	return res, nil
+}
+
+// promptSecret prompts for a secret on standard input.
+func promptSecret(description string, prompt string) ([]byte, error) {
+	fmt.Fprintf(os.Stderr, "%s: ", prompt)
+
+	fd := int(os.Stdin.Fd())
+	if terminal.IsTerminal(fd) {
+		secret, err := terminal.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("failed to read %s", description))
+		}
+		return secret, nil
+	}
+
+	// Not a terminal; read a line.
+	stdinReaderMx.Lock()
+	defer stdinReaderMx.Unlock()
+	if stdinReader == nil {
+		stdinReader = bufio.NewReader(os.Stdin)
+	}
+	line, err := stdinReader.ReadBytes('\n')
+	if err != nil && len(line) == 0 {
+		return nil, errors.Wrap(err, fmt.Sprintf("failed to read %s", description))
+	}
+	return trimSecret(line), nil
+}
+
+// trimSecret removes trailing line endings from a secret, zeroing the original data.
+func trimSecret(data []byte) []byte {
+	trimmed := bytes.TrimRight(data, "\r\n")
+	secret := make([]byte, len(trimmed))
+	copy(secret, trimmed)
+	ZeroBytes(data)
+	return secret
+}
+
+// ZeroBytes overwrites the contents of a byte slice.
+func ZeroBytes(data []byte) {
+	for i := range data {
+		data[i] = 0
+	}
+}
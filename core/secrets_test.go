@@ -0,0 +1,114 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wealdtech/walletd/core"
+)
+
+func TestResolveSecret(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "secret"), []byte("file secret\n"), 0600))
+	require.NoError(t, os.Setenv("WALLETD_TEST_SECRET", "env secret"))
+	defer os.Unsetenv("WALLETD_TEST_SECRET")
+
+	tests := []struct {
+		name   string
+		value  string
+		secret []byte
+		err    string
+	}{
+		{
+			name:   "Empty",
+			value:  "",
+			secret: []byte{},
+		},
+		{
+			name:   "Literal",
+			value:  "secret",
+			secret: []byte("secret"),
+		},
+		{
+			name:   "File",
+			value:  "file:" + filepath.Join(dir, "secret"),
+			secret: []byte("file secret"),
+		},
+		{
+			name:  "FileMissing",
+			value: "file:",
+			err:   "no file supplied for test secret",
+		},
+		{
+			name:  "FileNotFound",
+			value: "file:" + filepath.Join(dir, "missing"),
+			err:   "failed to read test secret: open " + filepath.Join(dir, "missing") + ": no such file or directory",
+		},
+		{
+			name:   "Env",
+			value:  "env:WALLETD_TEST_SECRET",
+			secret: []byte("env secret"),
+		},
+		{
+			name:  "EnvMissing",
+			value: "env:",
+			err:   "no environment variable supplied for test secret",
+		},
+		{
+			name:  "EnvNotSet",
+			value: "env:WALLETD_TEST_SECRET_UNSET",
+			err:   "environment variable WALLETD_TEST_SECRET_UNSET for test secret not set",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			secret, err := core.ResolveSecret(context.Background(), "test secret", test.value)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.secret, secret)
+			}
+		})
+	}
+}
+
+func TestResolveEnvSecret(t *testing.T) {
+	require.NoError(t, os.Setenv("WALLETD_TEST_ENV_SECRET", "env secret"))
+	defer os.Unsetenv("WALLETD_TEST_ENV_SECRET")
+
+	secret, err := core.ResolveSecret(context.Background(), "test secret", "env:WALLETD_TEST_ENV_SECRET")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("env secret"), secret)
+
+	// The variable is removed from the environment.
+	_, exists := os.LookupEnv("WALLETD_TEST_ENV_SECRET")
+	assert.False(t, exists)
+
+	// The secret can be referenced again, and is not shared with earlier callers.
+	core.ZeroBytes(secret)
+	secret, err = core.ResolveSecret(context.Background(), "test secret", "env:WALLETD_TEST_ENV_SECRET")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("env secret"), secret)
+}
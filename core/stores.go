@@ -21,9 +21,11 @@ import (
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 	filesystem "github.com/wealdtech/go-eth2-wallet-store-filesystem"
-	s3 "github.com/wealdtech/go-eth2-wallet-store-s3"
 	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
 	e2wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+	"github.com/wealdtech/walletd/stores/keystores"
+	"github.com/wealdtech/walletd/stores/protected"
+	"github.com/wealdtech/walletd/stores/s3"
 )
 
 // Store defines a store within the configuration
@@ -32,11 +34,18 @@ type Store struct {
 	Type       string `json:"type"`
 	Protected  bool   `json:"protected"`
 	Passphrase string `json:"passphrase"`
+	Location   string `json:"location"`
+	// S3-specific configuration.
+	Bucket          string `json:"bucket"`
+	Region          string `json:"region"`
+	Endpoint        string `json:"endpoint"`
+	AccessKeyID     string `json:"access_key_id" mapstructure:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key" mapstructure:"secret_access_key"`
 }
 
 // InitStores initialises the stores from a configuration.
 func InitStores(ctx context.Context, stores []*Store) ([]e2wtypes.Store, error) {
-	span, _ := opentracing.StartSpanFromContext(ctx, "core.InitStores")
+	span, ctx := opentracing.StartSpanFromContext(ctx, "core.InitStores")
 	defer span.Finish()
 
 	if len(stores) == 0 {
@@ -45,6 +54,14 @@ func InitStores(ctx context.Context, stores []*Store) ([]e2wtypes.Store, error)
 	}
 	res := make([]e2wtypes.Store, 0, len(stores))
 	for i, store := range stores {
+		passphrase, err := ResolveSecret(ctx, fmt.Sprintf("passphrase for store %q", store.Name), store.Passphrase)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("failed to obtain passphrase for store %d", i))
+		}
+		// Remove the passphrase from the configuration so that it is not resolved or exposed again.  This does not
+		// remove it from memory.
+		store.Passphrase = ""
+
 		if store.Name == "" {
 			return nil, fmt.Errorf("store %d has no name", i)
 		}
@@ -54,24 +71,72 @@ func InitStores(ctx context.Context, stores []*Store) ([]e2wtypes.Store, error)
 		switch store.Type {
 		case "filesystem":
 			log.Debug().Str("name", store.Name).Msg("Adding filesystem store")
-			res = append(res, filesystem.New(filesystem.WithPassphrase([]byte(store.Passphrase))))
+			res = append(res, filesystem.New(filesystem.WithPassphrase(passphrase)))
 		case "s3":
 			log.Debug().Str("name", store.Name).Msg("Adding S3 store")
-			s3Store, err := s3.New(s3.WithPassphrase([]byte(store.Passphrase)))
+			s3Store, err := initS3Store(ctx, store, passphrase)
 			if err != nil {
 				return nil, errors.Wrap(err, fmt.Sprintf("failed to access store %d", i))
 			}
 			res = append(res, s3Store)
+		case "keystores":
+			log.Debug().Str("name", store.Name).Str("location", store.Location).Msg("Adding keystores store")
+			keystoresStore, err := keystores.New(store.Name, store.Location)
+			if err != nil {
+				return nil, errors.Wrap(err, fmt.Sprintf("failed to access store %d", i))
+			}
+			res = append(res, keystoresStore)
 		case "scratch":
 			log.Debug().Msg("Adding scratch store")
 			res = append(res, scratch.New())
 		default:
 			return nil, fmt.Errorf("store %d has unhandled type %q", i, store.Type)
 		}
+		if store.Protected {
+			log.Debug().Str("name", store.Name).Msg("Protecting store")
+			protectedStore, err := protected.New(res[len(res)-1])
+			if err != nil {
+				return nil, errors.Wrap(err, fmt.Sprintf("failed to protect store %d", i))
+			}
+			res[len(res)-1] = protectedStore
+		}
 	}
 	return res, nil
 }
 
+// initS3Store initialises an S3 store.
+func initS3Store(ctx context.Context, store *Store, passphrase []byte) (e2wtypes.Store, error) {
+	opts := []s3.Option{
+		s3.WithPassphrase(passphrase),
+		// A protected store is not altered, so its bucket must already exist.
+		s3.WithReadOnly(store.Protected),
+	}
+	if store.Region != "" {
+		opts = append(opts, s3.WithRegion(store.Region))
+	}
+	if store.Bucket != "" {
+		opts = append(opts, s3.WithBucket(store.Bucket))
+	}
+	if store.Endpoint != "" {
+		opts = append(opts, s3.WithEndpoint(store.Endpoint))
+	}
+	if store.AccessKeyID != "" || store.SecretAccessKey != "" {
+		accessKeyID, err := ResolveSecret(ctx, fmt.Sprintf("access key ID for store %q", store.Name), store.AccessKeyID)
+		if err != nil {
+			return nil, err
+		}
+		secretAccessKey, err := ResolveSecret(ctx, fmt.Sprintf("secret access key for store %q", store.Name), store.SecretAccessKey)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, s3.WithCredentials(string(accessKeyID), string(secretAccessKey)))
+		// Remove the credentials from the configuration so that they are not resolved or exposed again.
+		store.AccessKeyID = ""
+		store.SecretAccessKey = ""
+	}
+	return s3.New(opts...)
+}
+
 // initDefaultStores initialises the default stores.
 func initDefaultStores() []e2wtypes.Store {
 	res := make([]e2wtypes.Store, 1)
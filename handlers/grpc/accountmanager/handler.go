@@ -14,20 +14,29 @@
 package accountmanager
 
 import (
+	"github.com/wealdtech/walletd/services/checker"
 	"github.com/wealdtech/walletd/services/fetcher"
+	"github.com/wealdtech/walletd/services/relocker"
 	"github.com/wealdtech/walletd/services/ruler"
+	"github.com/wealdtech/walletd/services/unlockguard"
 )
 
 // Handler is the account manager handler.
 type Handler struct {
-	fetcher fetcher.Service
-	ruler   ruler.Service
+	checker  checker.Service
+	fetcher  fetcher.Service
+	ruler    ruler.Service
+	relocker relocker.Service
+	guard    *unlockguard.Service
 }
 
 // New creates a new account manager handler.
-func New(fetcher fetcher.Service, ruler ruler.Service) *Handler {
+func New(checker checker.Service, fetcher fetcher.Service, ruler ruler.Service, relocker relocker.Service, guard *unlockguard.Service) *Handler {
 	return &Handler{
-		fetcher: fetcher,
-		ruler:   ruler,
+		checker:  checker,
+		fetcher:  fetcher,
+		ruler:    ruler,
+		relocker: relocker,
+		guard:    guard,
 	}
 }
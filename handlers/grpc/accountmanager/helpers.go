@@ -0,0 +1,37 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package accountmanager
+
+import (
+	context "context"
+
+	"github.com/wealdtech/walletd/interceptors"
+	"github.com/wealdtech/walletd/services/checker"
+)
+
+// generateCredentials generates credentials from the request information.
+func (h *Handler) generateCredentials(ctx context.Context) *checker.Credentials {
+	res := &checker.Credentials{}
+
+	if client, ok := ctx.Value(&interceptors.ClientName{}).(string); ok {
+		res.Client = client
+	}
+	if fingerprint, ok := ctx.Value(&interceptors.ClientFingerprint{}).(string); ok {
+		res.Fingerprint = fingerprint
+	}
+	if ip, ok := ctx.Value(&interceptors.ExternalIP{}).(string); ok {
+		res.SourceIP = ip
+	}
+	return res
+}
@@ -15,23 +15,40 @@ package accountmanager
 
 import (
 	context "context"
+	"fmt"
 
 	pb "github.com/wealdtech/eth2-signer-api/pb/v1"
+	"github.com/wealdtech/walletd/services/ruler"
+	"github.com/wealdtech/walletd/stores/protected"
 )
 
 // Lock locks an account.
 func (h *Handler) Lock(ctx context.Context, req *pb.LockAccountRequest) (*pb.LockAccountResponse, error) {
+	credentials := h.generateCredentials(ctx)
+	log := log.With().Str("client", credentials.Client).Logger()
 	log.Info().Str("account", req.GetAccount()).Msg("Lock account received")
 	res := &pb.LockAccountResponse{}
 
-	_, account, err := h.fetcher.FetchAccount(req.Account)
+	wallet, account, err := h.fetcher.FetchAccount(ctx, req.Account)
 	if err != nil {
 		log.Info().Err(err).Str("result", "denied").Msg("Failed to fetch account")
 		res.State = pb.ResponseState_DENIED
-	} else {
-		account.Lock()
-		log.Info().Str("result", "succeeded").Msg("Account locked")
-		res.State = pb.ResponseState_SUCCEEDED
+		return res, nil
 	}
+	accountName := fmt.Sprintf("%s/%s", wallet.Name(), account.Name())
+	if !h.checker.Check(ctx, credentials, accountName, ruler.ActionLockAccount) {
+		log.Info().Str("result", "denied").Msg("Client does not have permission to lock account")
+		res.State = pb.ResponseState_DENIED
+		return res, nil
+	}
+	if protected.IsProtected(wallet) {
+		log.Info().Str("result", "denied").Msg("Wallet is protected")
+		res.State = pb.ResponseState_DENIED
+		return res, nil
+	}
+
+	account.Lock()
+	log.Info().Str("result", "succeeded").Msg("Account locked")
+	res.State = pb.ResponseState_SUCCEEDED
 	return res, nil
 }
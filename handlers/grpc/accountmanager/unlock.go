@@ -15,27 +15,52 @@ package accountmanager
 
 import (
 	context "context"
+	"fmt"
 
 	pb "github.com/wealdtech/eth2-signer-api/pb/v1"
+	"github.com/wealdtech/walletd/services/ruler"
+	"github.com/wealdtech/walletd/stores/protected"
 )
 
 // Unlock unlocks an account.
 func (h *Handler) Unlock(ctx context.Context, req *pb.UnlockAccountRequest) (*pb.UnlockAccountResponse, error) {
+	credentials := h.generateCredentials(ctx)
+	log := log.With().Str("client", credentials.Client).Logger()
 	log.Info().Str("account", req.GetAccount()).Msg("Unlock account received")
 	res := &pb.UnlockAccountResponse{}
 
-	_, account, err := h.fetcher.FetchAccount(req.Account)
+	wallet, account, err := h.fetcher.FetchAccount(ctx, req.Account)
 	if err != nil {
 		log.Info().Err(err).Str("result", "denied").Msg("Failed to fetch account")
 		res.State = pb.ResponseState_DENIED
-	} else {
-		err = account.Unlock(req.Passphrase)
-		if err != nil {
-			log.Info().Err(err).Str("result", "denied").Msg("Failed to unlock")
-			res.State = pb.ResponseState_DENIED
-		} else {
-			res.State = pb.ResponseState_SUCCEEDED
-		}
+		return res, nil
 	}
+	accountName := fmt.Sprintf("%s/%s", wallet.Name(), account.Name())
+	if !h.checker.Check(ctx, credentials, accountName, ruler.ActionUnlockAccount) {
+		log.Info().Str("result", "denied").Msg("Client does not have permission to unlock account")
+		res.State = pb.ResponseState_DENIED
+		return res, nil
+	}
+	if protected.IsProtected(wallet) {
+		log.Info().Str("result", "denied").Msg("Wallet is protected")
+		res.State = pb.ResponseState_DENIED
+		return res, nil
+	}
+
+	if !h.guard.Allowed(credentials.Client, accountName) {
+		log.Info().Str("result", "denied").Msg("Unlock attempts blocked")
+		res.State = pb.ResponseState_DENIED
+		return res, nil
+	}
+
+	if err := account.Unlock(req.Passphrase); err != nil {
+		h.guard.Failed(credentials.Client, accountName)
+		log.Info().Err(err).Str("result", "denied").Msg("Failed to unlock")
+		res.State = pb.ResponseState_DENIED
+		return res, nil
+	}
+	h.guard.Succeeded(credentials.Client, accountName)
+	h.relocker.Unlocked(ctx, account)
+	res.State = pb.ResponseState_SUCCEEDED
 	return res, nil
 }
@@ -0,0 +1,134 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin_test
+
+import (
+	context "context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	pb "github.com/wealdtech/eth2-signer-api/pb/v1"
+	"github.com/wealdtech/walletd/api"
+	"github.com/wealdtech/walletd/core"
+	"github.com/wealdtech/walletd/handlers/grpc/admin"
+	"github.com/wealdtech/walletd/interceptors"
+	"github.com/wealdtech/walletd/services/approvals"
+	"github.com/wealdtech/walletd/services/checker"
+	staticchecker "github.com/wealdtech/walletd/services/checker/static"
+	"github.com/wealdtech/walletd/services/ruler"
+	"github.com/wealdtech/walletd/services/unlockguard"
+)
+
+func TestApprove(t *testing.T) {
+	checkerSvc, err := staticchecker.New(context.Background(), &core.Permissions{
+		Certs: []*core.CertificateInfo{
+			{
+				Name: "client1",
+				Perms: []*core.CertificatePerms{
+					{
+						Path:       "Wallet 1",
+						Operations: []string{"All"},
+					},
+				},
+			},
+			{
+				Name: "operator",
+				Perms: []*core.CertificatePerms{
+					{
+						Path:       "Wallet 1",
+						Operations: []string{ruler.ActionApproveVoluntaryExit},
+					},
+				},
+			},
+			{
+				Name: "other",
+				Perms: []*core.CertificatePerms{
+					{
+						Path:       "Wallet 2",
+						Operations: []string{ruler.ActionApproveVoluntaryExit},
+					},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+	guard, err := unlockguard.New(context.Background(), nil)
+	require.NoError(t, err)
+	approvalsSvc, err := approvals.New(context.Background(), nil)
+	require.NoError(t, err)
+	handler := admin.New(checkerSvc, guard, approvalsSvc)
+
+	approval, _ := approvalsSvc.Request("", &checker.Credentials{Client: "client1"}, "Wallet 1/Account 1", ruler.ActionSignVoluntaryExit, []byte{0x01})
+
+	// Only clients with permission to approve see the approval.
+	for _, client := range []string{"client1", "other"} {
+		ctx := context.WithValue(context.Background(), &interceptors.ClientName{}, client)
+		res, err := handler.ListApprovals(ctx, &api.ListApprovalsRequest{})
+		require.NoError(t, err)
+		assert.Len(t, res.Approvals, 0)
+	}
+	ctx := context.WithValue(context.Background(), &interceptors.ClientName{}, "operator")
+	listRes, err := handler.ListApprovals(ctx, &api.ListApprovalsRequest{})
+	require.NoError(t, err)
+	require.Len(t, listRes.Approvals, 1)
+	assert.Equal(t, approval.ID, listRes.Approvals[0].ID)
+
+	tests := []struct {
+		name   string
+		client string
+		id     string
+		state  pb.ResponseState
+	}{
+		{
+			name:   "Unknown",
+			client: "operator",
+			id:     "unknown",
+			state:  pb.ResponseState_DENIED,
+		},
+		{
+			name:   "RequestingClient",
+			client: "client1",
+			id:     approval.ID,
+			state:  pb.ResponseState_DENIED,
+		},
+		{
+			name:   "NoPermission",
+			client: "other",
+			id:     approval.ID,
+			state:  pb.ResponseState_DENIED,
+		},
+		{
+			name:   "Good",
+			client: "operator",
+			id:     approval.ID,
+			state:  pb.ResponseState_SUCCEEDED,
+		},
+		{
+			name:   "AlreadyApproved",
+			client: "operator",
+			id:     approval.ID,
+			state:  pb.ResponseState_DENIED,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), &interceptors.ClientName{}, test.client)
+			res, err := handler.Approve(ctx, &api.ApproveRequest{ID: test.id})
+			require.NoError(t, err)
+			assert.Equal(t, test.state, res.State)
+		})
+	}
+}
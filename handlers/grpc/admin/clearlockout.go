@@ -0,0 +1,46 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	context "context"
+
+	pb "github.com/wealdtech/eth2-signer-api/pb/v1"
+	"github.com/wealdtech/walletd/api"
+	"github.com/wealdtech/walletd/services/ruler"
+)
+
+// ClearLockout clears a lockout caused by failed unlock attempts.
+func (h *Handler) ClearLockout(ctx context.Context, req *api.ClearLockoutRequest) (*api.ClearLockoutResponse, error) {
+	credentials := h.generateCredentials(ctx)
+	log := log.With().Str("client", credentials.Client).Str("account", req.Account).Str("locked_client", req.Client).Logger()
+	log.Info().Msg("Clear lockout received")
+	res := &api.ClearLockoutResponse{}
+
+	if req.Account == "" {
+		log.Info().Str("result", "denied").Msg("No account supplied")
+		res.State = pb.ResponseState_DENIED
+		return res, nil
+	}
+	if !h.checker.Check(ctx, credentials, req.Account, ruler.ActionClearLockout) {
+		log.Info().Str("result", "denied").Msg("Client does not have permission to clear lockout")
+		res.State = pb.ResponseState_DENIED
+		return res, nil
+	}
+
+	res.Cleared = h.guard.Clear(req.Client, req.Account)
+	log.Info().Str("result", "succeeded").Bool("cleared", res.Cleared).Msg("Lockout cleared")
+	res.State = pb.ResponseState_SUCCEEDED
+	return res, nil
+}
@@ -0,0 +1,36 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"github.com/wealdtech/walletd/services/approvals"
+	"github.com/wealdtech/walletd/services/checker"
+	"github.com/wealdtech/walletd/services/unlockguard"
+)
+
+// Handler is the admin handler.
+type Handler struct {
+	checker   checker.Service
+	guard     *unlockguard.Service
+	approvals *approvals.Service
+}
+
+// New creates a new admin handler.
+func New(checker checker.Service, guard *unlockguard.Service, approvals *approvals.Service) *Handler {
+	return &Handler{
+		checker:   checker,
+		guard:     guard,
+		approvals: approvals,
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	context "context"
+
+	pb "github.com/wealdtech/eth2-signer-api/pb/v1"
+	"github.com/wealdtech/walletd/api"
+)
+
+// ListApprovals lists the requests awaiting approval that the client is permitted to approve.
+// Clients cannot approve their own requests, nor approve a request twice, so these are not listed.
+func (h *Handler) ListApprovals(ctx context.Context, req *api.ListApprovalsRequest) (*api.ListApprovalsResponse, error) {
+	credentials := h.generateCredentials(ctx)
+	log := log.With().Str("client", credentials.Client).Logger()
+	log.Debug().Msg("List approvals received")
+	res := &api.ListApprovalsResponse{
+		Approvals: make([]*api.Approval, 0),
+	}
+
+	for _, approval := range h.approvals.Pending() {
+		if approval.RequestedBy(credentials) || approval.ApprovedBy(credentials) || !h.canApprove(ctx, credentials, approval) {
+			continue
+		}
+		res.Approvals = append(res.Approvals, &api.Approval{
+			ID:        approval.ID,
+			Client:    approval.Client,
+			Account:   approval.Account,
+			Action:    approval.Action,
+			Root:      approval.Root,
+			Expires:   approval.Expires,
+			Required:  approval.Required,
+			Approvers: approval.Approvers,
+		})
+	}
+
+	log.Debug().Int("approvals", len(res.Approvals)).Str("result", "succeeded").Msg("Success")
+	res.State = pb.ResponseState_SUCCEEDED
+	return res, nil
+}
@@ -0,0 +1,52 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	context "context"
+
+	pb "github.com/wealdtech/eth2-signer-api/pb/v1"
+	"github.com/wealdtech/walletd/api"
+)
+
+// Reject rejects a request awaiting approval.
+func (h *Handler) Reject(ctx context.Context, req *api.RejectRequest) (*api.RejectResponse, error) {
+	credentials := h.generateCredentials(ctx)
+	log := log.With().Str("client", credentials.Client).Str("id", req.ID).Logger()
+	log.Info().Msg("Reject received")
+	res := &api.RejectResponse{}
+
+	pending := h.approvals.Approval(req.ID)
+	if pending == nil {
+		log.Info().Str("result", "denied").Msg("Unknown or expired approval")
+		res.State = pb.ResponseState_DENIED
+		return res, nil
+	}
+	log = log.With().Str("account", pending.Account).Str("action", pending.Action).Str("requesting_client", pending.Client).Logger()
+	if !h.canApprove(ctx, credentials, pending) {
+		log.Info().Str("result", "denied").Msg("Client does not have permission to reject request")
+		res.State = pb.ResponseState_DENIED
+		return res, nil
+	}
+
+	if _, err := h.approvals.Reject(req.ID, credentials); err != nil {
+		log.Info().Err(err).Str("result", "denied").Msg("Failed to reject request")
+		res.State = pb.ResponseState_DENIED
+		return res, nil
+	}
+
+	log.Info().Str("result", "succeeded").Msg("Request rejected")
+	res.State = pb.ResponseState_SUCCEEDED
+	return res, nil
+}
@@ -60,7 +60,7 @@ func (h *Handler) ListAccounts(ctx context.Context, req *pb.ListAccountsRequest)
 			continue
 		}
 
-		wallet, err := h.fetcher.FetchWallet(path)
+		wallet, err := h.fetcher.FetchWallet(ctx, path)
 		if err != nil {
 			log.Info().Err(err).Msg("Failed to obtain wallet")
 			continue
@@ -103,10 +103,15 @@ func (h *Handler) ListAccounts(ctx context.Context, req *pb.ListAccountsRequest)
 
 // checkClientAccess returns true if the client can access the account.
 func (h *Handler) checkClientAccess(ctx context.Context, accountName string, operation string) (bool, error) {
-	client := ""
-	val := ctx.Value(&interceptors.ClientName{})
-	if val != nil {
-		client = val.(string)
+	credentials := &checker.Credentials{}
+	if client, ok := ctx.Value(&interceptors.ClientName{}).(string); ok {
+		credentials.Client = client
 	}
-	return h.checker.Check(ctx, &checker.Credentials{Client: client}, accountName, operation), nil
+	if fingerprint, ok := ctx.Value(&interceptors.ClientFingerprint{}).(string); ok {
+		credentials.Fingerprint = fingerprint
+	}
+	if ip, ok := ctx.Value(&interceptors.ExternalIP{}).(string); ok {
+		credentials.SourceIP = ip
+	}
+	return h.checker.Check(ctx, credentials, accountName, operation), nil
 }
@@ -156,7 +156,7 @@ func Setup() (*lister.Handler, error) {
 	if err != nil {
 		return nil, err
 	}
-	fetcher, err := memfetcher.New([]e2wtypes.Store{store})
+	fetcher, err := memfetcher.New(context.Background(), []e2wtypes.Store{store})
 	if err != nil {
 		return nil, err
 	}
@@ -16,8 +16,13 @@ package signer
 import (
 	context "context"
 
+	pb "github.com/wealdtech/eth2-signer-api/pb/v1"
+	"github.com/wealdtech/walletd/api"
+	"github.com/wealdtech/walletd/core"
 	"github.com/wealdtech/walletd/interceptors"
 	"github.com/wealdtech/walletd/services/checker"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 // generateCredentials generates credentials from the request information.
@@ -27,5 +32,40 @@ func (h *Handler) generateCredentials(ctx context.Context) *checker.Credentials
 	if client, ok := ctx.Value(&interceptors.ClientName{}).(string); ok {
 		res.Client = client
 	}
+	if fingerprint, ok := ctx.Value(&interceptors.ClientFingerprint{}).(string); ok {
+		res.Fingerprint = fingerprint
+	}
+	if ip, ok := ctx.Value(&interceptors.ExternalIP{}).(string); ok {
+		res.SourceIP = ip
+	}
 	return res
 }
+
+// generateSignResponse generates a typed signing response from the result of a signing request.
+func generateSignResponse(result core.RulesResult, signature []byte) *api.SignResponse {
+	res := &api.SignResponse{}
+	switch result {
+	case core.APPROVED:
+		res.State = pb.ResponseState_SUCCEEDED
+		res.Signature = signature
+	case core.DENIED:
+		res.State = pb.ResponseState_DENIED
+	case core.FAILED:
+		res.State = pb.ResponseState_FAILED
+	case core.PENDING:
+		res.State = api.ResponseStatePending
+	case core.EXPIRED:
+		res.State = api.ResponseStateExpired
+	default:
+		res.State = pb.ResponseState_UNKNOWN
+	}
+	return res
+}
+
+// setApprovalID sets the ID of the approval for the request, if any, in the response header.
+func setApprovalID(ctx context.Context, approvalID string) error {
+	if approvalID == "" {
+		return nil
+	}
+	return grpc.SetHeader(ctx, metadata.Pairs(api.ApprovalIDMetadataKey, approvalID))
+}
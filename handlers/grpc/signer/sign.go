@@ -18,6 +18,7 @@ import (
 
 	"github.com/opentracing/opentracing-go"
 	pb "github.com/wealdtech/eth2-signer-api/pb/v1"
+	"github.com/wealdtech/walletd/api"
 	"github.com/wealdtech/walletd/core"
 	"github.com/wealdtech/walletd/services/ruler"
 )
@@ -31,7 +32,7 @@ func (h *Handler) Sign(ctx context.Context, req *pb.SignRequest) (*pb.SignRespon
 		Domain: req.Domain,
 		Data:   req.Data,
 	}
-	result, signature := h.signer.Sign(ctx, h.generateCredentials(ctx), req.GetAccount(), req.GetPublicKey(), data)
+	result, signature, approvalID := h.signer.Sign(ctx, h.generateCredentials(ctx), req.GetAccount(), req.GetPublicKey(), data)
 	res := &pb.SignResponse{}
 	switch result {
 	case core.APPROVED:
@@ -41,9 +42,16 @@ func (h *Handler) Sign(ctx context.Context, req *pb.SignRequest) (*pb.SignRespon
 		res.State = pb.ResponseState_DENIED
 	case core.FAILED:
 		res.State = pb.ResponseState_FAILED
+	case core.PENDING:
+		res.State = api.ResponseStatePending
+	case core.EXPIRED:
+		res.State = api.ResponseStateExpired
 	default:
 		res.State = pb.ResponseState_UNKNOWN
 	}
+	if err := setApprovalID(ctx, approvalID); err != nil {
+		log.Warn().Err(err).Msg("Failed to set approval ID")
+	}
 
 	log.Debug().Str("result", "succeeded").Msg("Success")
 	return res, nil
@@ -25,6 +25,7 @@ import (
 	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
 	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
 	e2wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+	"github.com/wealdtech/walletd/api"
 	"github.com/wealdtech/walletd/core"
 	"github.com/wealdtech/walletd/handlers/grpc/signer"
 	"github.com/wealdtech/walletd/interceptors"
@@ -32,6 +33,8 @@ import (
 	mockchecker "github.com/wealdtech/walletd/services/checker/mock"
 	"github.com/wealdtech/walletd/services/fetcher/memfetcher"
 	"github.com/wealdtech/walletd/services/locker"
+	"github.com/wealdtech/walletd/services/networks"
+	timedrelocker "github.com/wealdtech/walletd/services/relocker/timed"
 	"github.com/wealdtech/walletd/services/ruler/lua"
 	signersvc "github.com/wealdtech/walletd/services/signer"
 	"github.com/wealdtech/walletd/services/storage/mem"
@@ -46,13 +49,14 @@ func TestMain(m *testing.M) {
 
 func TestSign(t *testing.T) {
 	tests := []struct {
-		name    string
-		client  string
-		account string
-		data    []byte
-		domain  []byte
-		state   pb.ResponseState
-		err     string
+		name      string
+		client    string
+		account   string
+		data      []byte
+		domain    []byte
+		cancelled bool
+		state     pb.ResponseState
+		err       string
 	}{
 		{
 			name:   "Empty",
@@ -64,9 +68,26 @@ func TestSign(t *testing.T) {
 			client:  "client1",
 			account: "Wallet 1/Account 1",
 			data:    []byte("Hello, world"),
-			domain:  []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+			domain:  []byte{0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
 			state:   pb.ResponseState_SUCCEEDED,
 		},
+		{
+			name:    "BeaconProposerDomain",
+			client:  "client1",
+			account: "Wallet 1/Account 1",
+			data:    []byte("Hello, world"),
+			domain:  []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+			state:   pb.ResponseState_DENIED,
+		},
+		{
+			name:      "Expired",
+			client:    "client1",
+			account:   "Wallet 1/Account 1",
+			data:      []byte("Hello, world"),
+			domain:    []byte{0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+			cancelled: true,
+			state:     api.ResponseStateExpired,
+		},
 	}
 
 	handler, err := Setup()
@@ -81,7 +102,11 @@ func TestSign(t *testing.T) {
 				Data:   test.data,
 				Domain: test.domain,
 			}
-			ctx := context.WithValue(context.Background(), &interceptors.ClientName{}, test.client)
+			ctx, cancel := context.WithCancel(context.WithValue(context.Background(), &interceptors.ClientName{}, test.client))
+			defer cancel()
+			if test.cancelled {
+				cancel()
+			}
 			resp, err := handler.Sign(ctx, req)
 			if test.err == "" {
 				require.NoError(t, err)
@@ -126,7 +151,7 @@ func Setup() (*signer.Handler, error) {
 	if err != nil {
 		return nil, err
 	}
-	fetcher, err := memfetcher.New([]e2wtypes.Store{store})
+	fetcher, err := memfetcher.New(context.Background(), []e2wtypes.Store{store})
 	if err != nil {
 		return nil, err
 	}
@@ -153,7 +178,17 @@ func Setup() (*signer.Handler, error) {
 		return nil, err
 	}
 
-	signerSvc, err := signersvc.New(unlocker, checker, fetcher, ruler)
+	relocker, err := timedrelocker.New(context.Background(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	networks, err := networks.New(context.Background(), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	signerSvc, err := signersvc.New(unlocker, relocker, checker, fetcher, ruler, networks)
 	if err != nil {
 		return nil, err
 	}
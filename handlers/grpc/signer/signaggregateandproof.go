@@ -0,0 +1,64 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signer
+
+import (
+	context "context"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/wealdtech/walletd/api"
+	"github.com/wealdtech/walletd/services/ruler"
+)
+
+// SignAggregateAndProof signs an aggregate and proof.
+func (h *Handler) SignAggregateAndProof(ctx context.Context, req *api.SignAggregateAndProofRequest) (*api.SignResponse, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "handlers.signer.SignAggregateAndProof")
+	defer span.Finish()
+
+	data := &ruler.SignAggregateAndProofData{
+		Domain:          req.Domain,
+		AggregatorIndex: req.AggregatorIndex,
+		SelectionProof:  req.SelectionProof,
+	}
+	if req.Aggregate != nil {
+		data.Aggregate = &ruler.Attestation{
+			AggregationBits: req.Aggregate.AggregationBits,
+			Signature:       req.Aggregate.Signature,
+		}
+		if req.Aggregate.Data != nil {
+			data.Aggregate.Data = &ruler.AttestationData{
+				Slot:            req.Aggregate.Data.Slot,
+				CommitteeIndex:  req.Aggregate.Data.CommitteeIndex,
+				BeaconBlockRoot: req.Aggregate.Data.BeaconBlockRoot,
+			}
+			if req.Aggregate.Data.Source != nil {
+				data.Aggregate.Data.Source = &ruler.Checkpoint{
+					Epoch: req.Aggregate.Data.Source.Epoch,
+					Root:  req.Aggregate.Data.Source.Root,
+				}
+			}
+			if req.Aggregate.Data.Target != nil {
+				data.Aggregate.Data.Target = &ruler.Checkpoint{
+					Epoch: req.Aggregate.Data.Target.Epoch,
+					Root:  req.Aggregate.Data.Target.Root,
+				}
+			}
+		}
+	}
+
+	result, signature, approvalID := h.signer.SignAggregateAndProof(ctx, h.generateCredentials(ctx), req.Account, req.PublicKey, data)
+	res := generateSignResponse(result, signature)
+	res.ApprovalID = approvalID
+	return res, nil
+}
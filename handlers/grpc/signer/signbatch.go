@@ -0,0 +1,92 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signer
+
+import (
+	context "context"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/wealdtech/walletd/api"
+	"github.com/wealdtech/walletd/services/ruler"
+	signersvc "github.com/wealdtech/walletd/services/signer"
+)
+
+// SignBatch signs a number of beacon attestations and proposals.
+func (h *Handler) SignBatch(ctx context.Context, req *api.SignBatchRequest) (*api.SignBatchResponse, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "handlers.signer.SignBatch")
+	defer span.Finish()
+
+	attestations := make([]*signersvc.BatchAttestation, len(req.Attestations))
+	for i, attestation := range req.Attestations {
+		attestations[i] = &signersvc.BatchAttestation{}
+		if attestation == nil {
+			continue
+		}
+		attestations[i].AccountName = attestation.Account
+		attestations[i].PubKey = attestation.PublicKey
+		if attestation.Data != nil && attestation.Data.Source != nil && attestation.Data.Target != nil {
+			attestations[i].Data = &ruler.SignBeaconAttestationData{
+				Domain:          attestation.Domain,
+				Slot:            attestation.Data.Slot,
+				CommitteeIndex:  attestation.Data.CommitteeIndex,
+				BeaconBlockRoot: attestation.Data.BeaconBlockRoot,
+				Source: &ruler.Checkpoint{
+					Epoch: attestation.Data.Source.Epoch,
+					Root:  attestation.Data.Source.Root,
+				},
+				Target: &ruler.Checkpoint{
+					Epoch: attestation.Data.Target.Epoch,
+					Root:  attestation.Data.Target.Root,
+				},
+			}
+		}
+	}
+
+	proposals := make([]*signersvc.BatchProposal, len(req.Proposals))
+	for i, proposal := range req.Proposals {
+		proposals[i] = &signersvc.BatchProposal{}
+		if proposal == nil {
+			continue
+		}
+		proposals[i].AccountName = proposal.Account
+		proposals[i].PubKey = proposal.PublicKey
+		if proposal.Data != nil {
+			proposals[i].Data = &ruler.SignBeaconProposalData{
+				Domain:        proposal.Domain,
+				Slot:          proposal.Data.Slot,
+				ProposerIndex: proposal.Data.ProposerIndex,
+				ParentRoot:    proposal.Data.ParentRoot,
+				StateRoot:     proposal.Data.StateRoot,
+				BodyRoot:      proposal.Data.BodyRoot,
+			}
+		}
+	}
+
+	attestationResults, proposalResults := h.signer.SignBatch(ctx, h.generateCredentials(ctx), attestations, proposals)
+	res := &api.SignBatchResponse{
+		Attestations: make([]*api.SignResponse, len(attestationResults)),
+		Proposals:    make([]*api.SignResponse, len(proposalResults)),
+	}
+	for i, result := range attestationResults {
+		res.Attestations[i] = generateSignResponse(result.Result, result.Signature)
+		res.Attestations[i].ApprovalID = result.ApprovalID
+	}
+	for i, result := range proposalResults {
+		res.Proposals[i] = generateSignResponse(result.Result, result.Signature)
+		res.Proposals[i].ApprovalID = result.ApprovalID
+	}
+
+	log.Debug().Int("attestations", len(res.Attestations)).Int("proposals", len(res.Proposals)).Str("result", "succeeded").Msg("Success")
+	return res, nil
+}
@@ -0,0 +1,76 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signer_test
+
+import (
+	context "context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	pb "github.com/wealdtech/eth2-signer-api/pb/v1"
+	"github.com/wealdtech/walletd/api"
+	"github.com/wealdtech/walletd/interceptors"
+)
+
+func TestSignBatch(t *testing.T) {
+	handler, err := Setup()
+	require.Nil(t, err)
+
+	attesterDomain := make([]byte, 32)
+	attesterDomain[0] = 0x01
+	proposerDomain := make([]byte, 32)
+	root := make([]byte, 32)
+
+	req := &api.SignBatchRequest{
+		Attestations: []*api.SignBeaconAttestationRequest{
+			{
+				Account: "Wallet 1/Account 1",
+				Domain:  attesterDomain,
+				Data: &api.AttestationData{
+					Slot:            1,
+					BeaconBlockRoot: root,
+					Source:          &api.Checkpoint{Epoch: 0, Root: root},
+					Target:          &api.Checkpoint{Epoch: 1, Root: root},
+				},
+			},
+			nil,
+			{
+				Account: "Wallet 1/Account 1",
+				Domain:  attesterDomain,
+			},
+		},
+		Proposals: []*api.SignBeaconProposalRequest{
+			{
+				Account: "Wallet 1/Account 1",
+				Domain:  proposerDomain,
+				Data: &api.BeaconBlockHeader{
+					Slot:       1,
+					ParentRoot: root,
+					StateRoot:  root,
+					BodyRoot:   root,
+				},
+			},
+		},
+	}
+	ctx := context.WithValue(context.Background(), &interceptors.ClientName{}, "client1")
+	resp, err := handler.SignBatch(ctx, req)
+	require.NoError(t, err)
+	require.Len(t, resp.Attestations, 3)
+	require.Equal(t, pb.ResponseState_SUCCEEDED, resp.Attestations[0].State)
+	require.Len(t, resp.Attestations[0].Signature, 96)
+	require.Equal(t, pb.ResponseState_DENIED, resp.Attestations[1].State)
+	require.Equal(t, pb.ResponseState_DENIED, resp.Attestations[2].State)
+	require.Len(t, resp.Proposals, 1)
+	require.Equal(t, pb.ResponseState_SUCCEEDED, resp.Proposals[0].State)
+}
@@ -18,6 +18,7 @@ import (
 
 	"github.com/opentracing/opentracing-go"
 	pb "github.com/wealdtech/eth2-signer-api/pb/v1"
+	"github.com/wealdtech/walletd/api"
 	"github.com/wealdtech/walletd/core"
 	"github.com/wealdtech/walletd/services/ruler"
 )
@@ -27,22 +28,29 @@ func (h *Handler) SignBeaconAttestation(ctx context.Context, req *pb.SignBeaconA
 	span, ctx := opentracing.StartSpanFromContext(ctx, "handlers.signer.SignBeaconAttestation")
 	defer span.Finish()
 
-	data := &ruler.SignBeaconAttestationData{
-		Domain:          req.Domain,
-		Slot:            req.Data.Slot,
-		CommitteeIndex:  req.Data.CommitteeIndex,
-		BeaconBlockRoot: req.Data.BeaconBlockRoot,
-		Source: &ruler.Checkpoint{
-			Epoch: req.Data.Source.Epoch,
-			Root:  req.Data.Source.Root,
-		},
-		Target: &ruler.Checkpoint{
-			Epoch: req.Data.Target.Epoch,
-			Root:  req.Data.Target.Root,
-		},
+	var data *ruler.SignBeaconAttestationData
+	if req.Data != nil {
+		data = &ruler.SignBeaconAttestationData{
+			Domain:          req.Domain,
+			Slot:            req.Data.Slot,
+			CommitteeIndex:  req.Data.CommitteeIndex,
+			BeaconBlockRoot: req.Data.BeaconBlockRoot,
+		}
+		if req.Data.Source != nil {
+			data.Source = &ruler.Checkpoint{
+				Epoch: req.Data.Source.Epoch,
+				Root:  req.Data.Source.Root,
+			}
+		}
+		if req.Data.Target != nil {
+			data.Target = &ruler.Checkpoint{
+				Epoch: req.Data.Target.Epoch,
+				Root:  req.Data.Target.Root,
+			}
+		}
 	}
 
-	result, signature := h.signer.SignBeaconAttestation(ctx, h.generateCredentials(ctx), req.GetAccount(), req.GetPublicKey(), data)
+	result, signature, approvalID := h.signer.SignBeaconAttestation(ctx, h.generateCredentials(ctx), req.GetAccount(), req.GetPublicKey(), data)
 	res := &pb.SignResponse{}
 	switch result {
 	case core.APPROVED:
@@ -52,9 +60,16 @@ func (h *Handler) SignBeaconAttestation(ctx context.Context, req *pb.SignBeaconA
 		res.State = pb.ResponseState_DENIED
 	case core.FAILED:
 		res.State = pb.ResponseState_FAILED
+	case core.PENDING:
+		res.State = api.ResponseStatePending
+	case core.EXPIRED:
+		res.State = api.ResponseStateExpired
 	default:
 		res.State = pb.ResponseState_UNKNOWN
 	}
+	if err := setApprovalID(ctx, approvalID); err != nil {
+		log.Warn().Err(err).Msg("Failed to set approval ID")
+	}
 
 	log.Debug().Str("result", "succeeded").Msg("Success")
 	return res, nil
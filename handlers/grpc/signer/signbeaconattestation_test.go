@@ -0,0 +1,97 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signer_test
+
+import (
+	"bytes"
+	context "context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	pb "github.com/wealdtech/eth2-signer-api/pb/v1"
+	"github.com/wealdtech/walletd/interceptors"
+)
+
+func TestSignBeaconAttestation(t *testing.T) {
+	domain := append([]byte{0x01}, make([]byte, 31)...)
+	source := &pb.Checkpoint{
+		Epoch: 0,
+		Root:  bytes.Repeat([]byte{0x04}, 32),
+	}
+	target := &pb.Checkpoint{
+		Epoch: 1,
+		Root:  bytes.Repeat([]byte{0x05}, 32),
+	}
+
+	tests := []struct {
+		name  string
+		data  *pb.AttestationData
+		state pb.ResponseState
+	}{
+		{
+			name:  "NoData",
+			state: pb.ResponseState_DENIED,
+		},
+		{
+			name: "MissingSource",
+			data: &pb.AttestationData{
+				Slot:            1,
+				CommitteeIndex:  2,
+				BeaconBlockRoot: bytes.Repeat([]byte{0x03}, 32),
+				Target:          target,
+			},
+			state: pb.ResponseState_DENIED,
+		},
+		{
+			name: "MissingTarget",
+			data: &pb.AttestationData{
+				Slot:            1,
+				CommitteeIndex:  2,
+				BeaconBlockRoot: bytes.Repeat([]byte{0x03}, 32),
+				Source:          source,
+			},
+			state: pb.ResponseState_DENIED,
+		},
+		{
+			name: "Good",
+			data: &pb.AttestationData{
+				Slot:            1,
+				CommitteeIndex:  2,
+				BeaconBlockRoot: bytes.Repeat([]byte{0x03}, 32),
+				Source:          source,
+				Target:          target,
+			},
+			state: pb.ResponseState_SUCCEEDED,
+		},
+	}
+
+	handler, err := Setup()
+	require.Nil(t, err)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := &pb.SignBeaconAttestationRequest{
+				Id: &pb.SignBeaconAttestationRequest_Account{
+					Account: "Wallet 1/Account 1",
+				},
+				Domain: domain,
+				Data:   test.data,
+			}
+			ctx := context.WithValue(context.Background(), &interceptors.ClientName{}, "client1")
+			resp, err := handler.SignBeaconAttestation(ctx, req)
+			require.NoError(t, err)
+			require.Equal(t, test.state, resp.State)
+		})
+	}
+}
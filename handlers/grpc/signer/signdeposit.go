@@ -0,0 +1,40 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signer
+
+import (
+	context "context"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/wealdtech/walletd/api"
+	"github.com/wealdtech/walletd/services/ruler"
+)
+
+// SignDeposit signs deposit data.
+func (h *Handler) SignDeposit(ctx context.Context, req *api.SignDepositRequest) (*api.SignResponse, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "handlers.signer.SignDeposit")
+	defer span.Finish()
+
+	data := &ruler.SignDepositData{
+		Domain:                req.Domain,
+		PubKey:                req.ValidatorPublicKey,
+		WithdrawalCredentials: req.WithdrawalCredentials,
+		Amount:                req.Amount,
+	}
+
+	result, signature, approvalID := h.signer.SignDeposit(ctx, h.generateCredentials(ctx), req.Account, req.PublicKey, data)
+	res := generateSignResponse(result, signature)
+	res.ApprovalID = approvalID
+	return res, nil
+}
@@ -0,0 +1,69 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signer_test
+
+import (
+	context "context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	pb "github.com/wealdtech/eth2-signer-api/pb/v1"
+	"github.com/wealdtech/walletd/api"
+	"github.com/wealdtech/walletd/interceptors"
+)
+
+func TestSignVoluntaryExit(t *testing.T) {
+	tests := []struct {
+		name    string
+		client  string
+		account string
+		domain  []byte
+		state   pb.ResponseState
+	}{
+		{
+			name:   "Empty",
+			client: "client1",
+			state:  pb.ResponseState_DENIED,
+		},
+		{
+			name:    "Pending",
+			client:  "client1",
+			account: "Wallet 1/Account 1",
+			domain:  []byte{0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+			state:   api.ResponseStatePending,
+		},
+	}
+
+	handler, err := Setup()
+	require.Nil(t, err)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := &api.SignVoluntaryExitRequest{
+				Account:        test.account,
+				Domain:         test.domain,
+				Epoch:          1,
+				ValidatorIndex: 2,
+			}
+			ctx := context.WithValue(context.Background(), &interceptors.ClientName{}, test.client)
+			resp, err := handler.SignVoluntaryExit(ctx, req)
+			require.NoError(t, err)
+			require.Equal(t, test.state, resp.State)
+			if test.state == api.ResponseStatePending {
+				require.Nil(t, resp.Signature)
+				require.NotEmpty(t, resp.ApprovalID)
+			}
+		})
+	}
+}
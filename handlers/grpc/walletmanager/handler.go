@@ -14,20 +14,26 @@
 package walletmanager
 
 import (
+	"github.com/wealdtech/walletd/services/checker"
 	"github.com/wealdtech/walletd/services/fetcher"
 	"github.com/wealdtech/walletd/services/ruler"
+	"github.com/wealdtech/walletd/services/unlockguard"
 )
 
 // Handler is the wallet handler.
 type Handler struct {
+	checker checker.Service
 	fetcher fetcher.Service
 	ruler   ruler.Service
+	guard   *unlockguard.Service
 }
 
 // New creates a new wallet handler.
-func New(fetcher fetcher.Service, ruler ruler.Service) *Handler {
+func New(checker checker.Service, fetcher fetcher.Service, ruler ruler.Service, guard *unlockguard.Service) *Handler {
 	return &Handler{
+		checker: checker,
 		fetcher: fetcher,
 		ruler:   ruler,
+		guard:   guard,
 	}
 }
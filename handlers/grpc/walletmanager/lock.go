@@ -17,21 +17,36 @@ import (
 	context "context"
 
 	pb "github.com/wealdtech/eth2-signer-api/pb/v1"
+	"github.com/wealdtech/walletd/services/ruler"
+	"github.com/wealdtech/walletd/stores/protected"
 )
 
 // Lock locks a wallet.
 func (h *Handler) Lock(ctx context.Context, req *pb.LockWalletRequest) (*pb.LockWalletResponse, error) {
+	credentials := h.generateCredentials(ctx)
+	log := log.With().Str("client", credentials.Client).Logger()
 	log.Info().Str("wallet", req.GetWallet()).Msg("Lock wallet received")
 	res := &pb.LockWalletResponse{}
 
-	wallet, err := h.fetcher.FetchWallet(req.Wallet)
+	wallet, err := h.fetcher.FetchWallet(ctx, req.Wallet)
 	if err != nil {
 		log.Info().Err(err).Str("result", "denied").Msg("Failed to fetch wallet")
 		res.State = pb.ResponseState_DENIED
-	} else {
-		wallet.Lock()
-		log.Info().Str("result", "succeeded").Msg("Wallet locked")
-		res.State = pb.ResponseState_SUCCEEDED
+		return res, nil
 	}
+	if !h.checker.Check(ctx, credentials, wallet.Name(), ruler.ActionLockWallet) {
+		log.Info().Str("result", "denied").Msg("Client does not have permission to lock wallet")
+		res.State = pb.ResponseState_DENIED
+		return res, nil
+	}
+	if protected.IsProtected(wallet) {
+		log.Info().Str("result", "denied").Msg("Wallet is protected")
+		res.State = pb.ResponseState_DENIED
+		return res, nil
+	}
+
+	wallet.Lock()
+	log.Info().Str("result", "succeeded").Msg("Wallet locked")
+	res.State = pb.ResponseState_SUCCEEDED
 	return res, nil
 }
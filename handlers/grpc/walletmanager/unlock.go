@@ -17,25 +17,47 @@ import (
 	context "context"
 
 	pb "github.com/wealdtech/eth2-signer-api/pb/v1"
+	"github.com/wealdtech/walletd/services/ruler"
+	"github.com/wealdtech/walletd/stores/protected"
 )
 
 // Unlock unlocks a wallet.
 func (h *Handler) Unlock(ctx context.Context, req *pb.UnlockWalletRequest) (*pb.UnlockWalletResponse, error) {
+	credentials := h.generateCredentials(ctx)
+	log := log.With().Str("client", credentials.Client).Logger()
 	log.Info().Str("wallet", req.GetWallet()).Msg("Unlock wallet received")
 	res := &pb.UnlockWalletResponse{}
 
-	wallet, err := h.fetcher.FetchWallet(req.Wallet)
+	wallet, err := h.fetcher.FetchWallet(ctx, req.Wallet)
 	if err != nil {
 		log.Info().Err(err).Str("result", "denied").Msg("Failed to fetch wallet")
 		res.State = pb.ResponseState_DENIED
-	} else {
-		err = wallet.Unlock(req.Passphrase)
-		if err != nil {
-			log.Info().Err(err).Str("result", "denied").Msg("Failed to unlock")
-			res.State = pb.ResponseState_DENIED
-		} else {
-			res.State = pb.ResponseState_SUCCEEDED
-		}
+		return res, nil
 	}
+	if !h.checker.Check(ctx, credentials, wallet.Name(), ruler.ActionUnlockWallet) {
+		log.Info().Str("result", "denied").Msg("Client does not have permission to unlock wallet")
+		res.State = pb.ResponseState_DENIED
+		return res, nil
+	}
+	if protected.IsProtected(wallet) {
+		log.Info().Str("result", "denied").Msg("Wallet is protected")
+		res.State = pb.ResponseState_DENIED
+		return res, nil
+	}
+
+	if !h.guard.Allowed(credentials.Client, wallet.Name()) {
+		log.Info().Str("result", "denied").Msg("Unlock attempts blocked")
+		res.State = pb.ResponseState_DENIED
+		return res, nil
+	}
+
+	if err := wallet.Unlock(req.Passphrase); err != nil {
+		h.guard.Failed(credentials.Client, wallet.Name())
+		log.Info().Err(err).Str("result", "denied").Msg("Failed to unlock")
+		res.State = pb.ResponseState_DENIED
+		return res, nil
+	}
+	h.guard.Succeeded(credentials.Client, wallet.Name())
+	res.State = pb.ResponseState_SUCCEEDED
 	return res, nil
 }
@@ -0,0 +1,443 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web3signer
+
+import (
+	"errors"
+	"fmt"
+
+	bitfield "github.com/prysmaticlabs/go-bitfield"
+	ssz "github.com/prysmaticlabs/go-ssz"
+)
+
+// Limits on the lists in a phase 0 beacon block body.
+const (
+	maxProposerSlashings      = 16
+	maxAttesterSlashings      = 2
+	maxAttestations           = 128
+	maxDeposits               = 16
+	maxVoluntaryExits         = 16
+	maxValidatorsPerCommittee = 2048
+	depositProofLength        = 33
+)
+
+// phase0Block is a phase 0 beacon block, as supplied in `BLOCK` requests and phase 0 `BLOCK_V2` requests.
+type phase0Block struct {
+	Slot          quantity         `json:"slot"`
+	ProposerIndex quantity         `json:"proposer_index"`
+	ParentRoot    hexBytes         `json:"parent_root"`
+	StateRoot     hexBytes         `json:"state_root"`
+	Body          *phase0BlockBody `json:"body"`
+}
+
+type phase0BlockBody struct {
+	RANDAOReveal      hexBytes               `json:"randao_reveal"`
+	ETH1Data          *eth1Data              `json:"eth1_data"`
+	Graffiti          hexBytes               `json:"graffiti"`
+	ProposerSlashings []*proposerSlashing    `json:"proposer_slashings"`
+	AttesterSlashings []*attesterSlashing    `json:"attester_slashings"`
+	Attestations      []*attestation         `json:"attestations"`
+	Deposits          []*blockDeposit        `json:"deposits"`
+	VoluntaryExits    []*signedVoluntaryExit `json:"voluntary_exits"`
+}
+
+type eth1Data struct {
+	DepositRoot  hexBytes `json:"deposit_root"`
+	DepositCount quantity `json:"deposit_count"`
+	BlockHash    hexBytes `json:"block_hash"`
+}
+
+type proposerSlashing struct {
+	SignedHeader1 *signedBeaconBlockHeader `json:"signed_header_1"`
+	SignedHeader2 *signedBeaconBlockHeader `json:"signed_header_2"`
+}
+
+type signedBeaconBlockHeader struct {
+	Message   *beaconBlockHeader `json:"message"`
+	Signature hexBytes           `json:"signature"`
+}
+
+type attesterSlashing struct {
+	Attestation1 *indexedAttestation `json:"attestation_1"`
+	Attestation2 *indexedAttestation `json:"attestation_2"`
+}
+
+type indexedAttestation struct {
+	AttestingIndices []quantity       `json:"attesting_indices"`
+	Data             *attestationData `json:"data"`
+	Signature        hexBytes         `json:"signature"`
+}
+
+type blockDeposit struct {
+	Proof []hexBytes        `json:"proof"`
+	Data  *blockDepositData `json:"data"`
+}
+
+type blockDepositData struct {
+	PubKey                hexBytes `json:"pubkey"`
+	WithdrawalCredentials hexBytes `json:"withdrawal_credentials"`
+	Amount                quantity `json:"amount"`
+	Signature             hexBytes `json:"signature"`
+}
+
+type signedVoluntaryExit struct {
+	Message   *voluntaryExit `json:"message"`
+	Signature hexBytes       `json:"signature"`
+}
+
+// The following types carry the SSZ layout of the phase 0 beacon block body, from which its hash tree root is
+// calculated.
+
+type sszBlockBody struct {
+	RANDAOReveal      []byte `ssz-size:"96"`
+	ETH1Data          sszETH1Data
+	Graffiti          []byte                   `ssz-size:"32"`
+	ProposerSlashings []sszProposerSlashing    `ssz-max:"16"`
+	AttesterSlashings []sszAttesterSlashing    `ssz-max:"2"`
+	Attestations      []sszAttestation         `ssz-max:"128"`
+	Deposits          []sszDeposit             `ssz-max:"16"`
+	VoluntaryExits    []sszSignedVoluntaryExit `ssz-max:"16"`
+}
+
+type sszETH1Data struct {
+	DepositRoot  []byte `ssz-size:"32"`
+	DepositCount uint64
+	BlockHash    []byte `ssz-size:"32"`
+}
+
+type sszBeaconBlockHeader struct {
+	Slot          uint64
+	ProposerIndex uint64
+	ParentRoot    []byte `ssz-size:"32"`
+	StateRoot     []byte `ssz-size:"32"`
+	BodyRoot      []byte `ssz-size:"32"`
+}
+
+type sszSignedBeaconBlockHeader struct {
+	Message   sszBeaconBlockHeader
+	Signature []byte `ssz-size:"96"`
+}
+
+type sszProposerSlashing struct {
+	SignedHeader1 sszSignedBeaconBlockHeader
+	SignedHeader2 sszSignedBeaconBlockHeader
+}
+
+type sszCheckpoint struct {
+	Epoch uint64
+	Root  []byte `ssz-size:"32"`
+}
+
+type sszAttestationData struct {
+	Slot            uint64
+	Index           uint64
+	BeaconBlockRoot []byte `ssz-size:"32"`
+	Source          sszCheckpoint
+	Target          sszCheckpoint
+}
+
+type sszIndexedAttestation struct {
+	AttestingIndices []uint64 `ssz-max:"2048"`
+	Data             sszAttestationData
+	Signature        []byte `ssz-size:"96"`
+}
+
+type sszAttesterSlashing struct {
+	Attestation1 sszIndexedAttestation
+	Attestation2 sszIndexedAttestation
+}
+
+type sszAttestation struct {
+	AggregationBits bitfield.Bitlist `ssz-max:"2048"`
+	Data            sszAttestationData
+	Signature       []byte `ssz-size:"96"`
+}
+
+type sszDepositData struct {
+	PubKey                []byte `ssz-size:"48"`
+	WithdrawalCredentials []byte `ssz-size:"32"`
+	Amount                uint64
+	Signature             []byte `ssz-size:"96"`
+}
+
+type sszDeposit struct {
+	Proof [][]byte `ssz-size:"33,32"`
+	Data  sszDepositData
+}
+
+type sszVoluntaryExit struct {
+	Epoch          uint64
+	ValidatorIndex uint64
+}
+
+type sszSignedVoluntaryExit struct {
+	Message   sszVoluntaryExit
+	Signature []byte `ssz-size:"96"`
+}
+
+// header returns the header of the block, calculating the root of its body.
+func (b *phase0Block) header() (*beaconBlockHeader, error) {
+	if b.Body == nil {
+		return nil, errors.New("missing block body")
+	}
+	body, err := b.Body.ssz()
+	if err != nil {
+		return nil, err
+	}
+	bodyRoot, err := ssz.HashTreeRoot(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate block body root: %v", err)
+	}
+	return &beaconBlockHeader{
+		Slot:          b.Slot,
+		ProposerIndex: b.ProposerIndex,
+		ParentRoot:    b.ParentRoot,
+		StateRoot:     b.StateRoot,
+		BodyRoot:      bodyRoot[:],
+	}, nil
+}
+
+// ssz converts the block body to its SSZ layout, checking the sizes of its fields.
+func (b *phase0BlockBody) ssz() (*sszBlockBody, error) {
+	if b.ETH1Data == nil {
+		return nil, errors.New("missing eth1 data")
+	}
+	if len(b.ProposerSlashings) > maxProposerSlashings ||
+		len(b.AttesterSlashings) > maxAttesterSlashings ||
+		len(b.Attestations) > maxAttestations ||
+		len(b.Deposits) > maxDeposits ||
+		len(b.VoluntaryExits) > maxVoluntaryExits {
+		return nil, errors.New("too many operations in block body")
+	}
+	res := &sszBlockBody{
+		RANDAOReveal: b.RANDAOReveal,
+		ETH1Data: sszETH1Data{
+			DepositRoot:  b.ETH1Data.DepositRoot,
+			DepositCount: uint64(b.ETH1Data.DepositCount),
+			BlockHash:    b.ETH1Data.BlockHash,
+		},
+		Graffiti:          b.Graffiti,
+		ProposerSlashings: make([]sszProposerSlashing, len(b.ProposerSlashings)),
+		AttesterSlashings: make([]sszAttesterSlashing, len(b.AttesterSlashings)),
+		Attestations:      make([]sszAttestation, len(b.Attestations)),
+		Deposits:          make([]sszDeposit, len(b.Deposits)),
+		VoluntaryExits:    make([]sszSignedVoluntaryExit, len(b.VoluntaryExits)),
+	}
+	if len(res.RANDAOReveal) != 96 {
+		return nil, errors.New("invalid randao reveal")
+	}
+	if len(res.Graffiti) != 32 {
+		return nil, errors.New("invalid graffiti")
+	}
+	if len(res.ETH1Data.DepositRoot) != 32 {
+		return nil, errors.New("invalid deposit root")
+	}
+	if len(res.ETH1Data.BlockHash) != 32 {
+		return nil, errors.New("invalid block hash")
+	}
+
+	var err error
+	for i, slashing := range b.ProposerSlashings {
+		if slashing == nil {
+			return nil, errors.New("missing proposer slashing")
+		}
+		if res.ProposerSlashings[i].SignedHeader1, err = slashing.SignedHeader1.ssz(); err != nil {
+			return nil, err
+		}
+		if res.ProposerSlashings[i].SignedHeader2, err = slashing.SignedHeader2.ssz(); err != nil {
+			return nil, err
+		}
+	}
+	for i, slashing := range b.AttesterSlashings {
+		if slashing == nil {
+			return nil, errors.New("missing attester slashing")
+		}
+		if res.AttesterSlashings[i].Attestation1, err = slashing.Attestation1.ssz(); err != nil {
+			return nil, err
+		}
+		if res.AttesterSlashings[i].Attestation2, err = slashing.Attestation2.ssz(); err != nil {
+			return nil, err
+		}
+	}
+	for i, attestation := range b.Attestations {
+		if res.Attestations[i], err = attestation.ssz(); err != nil {
+			return nil, err
+		}
+	}
+	for i, deposit := range b.Deposits {
+		if res.Deposits[i], err = deposit.ssz(); err != nil {
+			return nil, err
+		}
+	}
+	for i, exit := range b.VoluntaryExits {
+		if res.VoluntaryExits[i], err = exit.ssz(); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func (h *signedBeaconBlockHeader) ssz() (sszSignedBeaconBlockHeader, error) {
+	if h == nil || h.Message == nil {
+		return sszSignedBeaconBlockHeader{}, errors.New("missing signed block header")
+	}
+	res := sszSignedBeaconBlockHeader{
+		Message: sszBeaconBlockHeader{
+			Slot:          uint64(h.Message.Slot),
+			ProposerIndex: uint64(h.Message.ProposerIndex),
+			ParentRoot:    h.Message.ParentRoot,
+			StateRoot:     h.Message.StateRoot,
+			BodyRoot:      h.Message.BodyRoot,
+		},
+		Signature: h.Signature,
+	}
+	if len(res.Message.ParentRoot) != 32 {
+		return sszSignedBeaconBlockHeader{}, errors.New("invalid parent root")
+	}
+	if len(res.Message.StateRoot) != 32 {
+		return sszSignedBeaconBlockHeader{}, errors.New("invalid state root")
+	}
+	if len(res.Message.BodyRoot) != 32 {
+		return sszSignedBeaconBlockHeader{}, errors.New("invalid body root")
+	}
+	if len(res.Signature) != 96 {
+		return sszSignedBeaconBlockHeader{}, errors.New("invalid signature")
+	}
+	return res, nil
+}
+
+func (a *indexedAttestation) ssz() (sszIndexedAttestation, error) {
+	if a == nil {
+		return sszIndexedAttestation{}, errors.New("missing indexed attestation")
+	}
+	if len(a.AttestingIndices) > maxValidatorsPerCommittee {
+		return sszIndexedAttestation{}, errors.New("too many attesting indices")
+	}
+	data, err := a.Data.ssz()
+	if err != nil {
+		return sszIndexedAttestation{}, err
+	}
+	res := sszIndexedAttestation{
+		AttestingIndices: make([]uint64, len(a.AttestingIndices)),
+		Data:             data,
+		Signature:        a.Signature,
+	}
+	for i := range a.AttestingIndices {
+		res.AttestingIndices[i] = uint64(a.AttestingIndices[i])
+	}
+	if len(res.Signature) != 96 {
+		return sszIndexedAttestation{}, errors.New("invalid signature")
+	}
+	return res, nil
+}
+
+func (a *attestation) ssz() (sszAttestation, error) {
+	if a == nil {
+		return sszAttestation{}, errors.New("missing attestation")
+	}
+	// A bitlist is terminated by a set bit in its final byte, which gives its length.
+	if len(a.AggregationBits) == 0 || a.AggregationBits[len(a.AggregationBits)-1] == 0 {
+		return sszAttestation{}, errors.New("invalid aggregation bits")
+	}
+	if bitfield.Bitlist(a.AggregationBits).Len() > maxValidatorsPerCommittee {
+		return sszAttestation{}, errors.New("too many aggregation bits")
+	}
+	data, err := a.Data.ssz()
+	if err != nil {
+		return sszAttestation{}, err
+	}
+	res := sszAttestation{
+		AggregationBits: bitfield.Bitlist(a.AggregationBits),
+		Data:            data,
+		Signature:       a.Signature,
+	}
+	if len(res.Signature) != 96 {
+		return sszAttestation{}, errors.New("invalid signature")
+	}
+	return res, nil
+}
+
+func (d *attestationData) ssz() (sszAttestationData, error) {
+	if d == nil || d.Source == nil || d.Target == nil {
+		return sszAttestationData{}, errors.New("missing attestation data")
+	}
+	res := sszAttestationData{
+		Slot:            uint64(d.Slot),
+		Index:           uint64(d.Index),
+		BeaconBlockRoot: d.BeaconBlockRoot,
+		Source:          sszCheckpoint{Epoch: uint64(d.Source.Epoch), Root: d.Source.Root},
+		Target:          sszCheckpoint{Epoch: uint64(d.Target.Epoch), Root: d.Target.Root},
+	}
+	if len(res.BeaconBlockRoot) != 32 {
+		return sszAttestationData{}, errors.New("invalid beacon block root")
+	}
+	if len(res.Source.Root) != 32 {
+		return sszAttestationData{}, errors.New("invalid source root")
+	}
+	if len(res.Target.Root) != 32 {
+		return sszAttestationData{}, errors.New("invalid target root")
+	}
+	return res, nil
+}
+
+func (d *blockDeposit) ssz() (sszDeposit, error) {
+	if d == nil || d.Data == nil {
+		return sszDeposit{}, errors.New("missing deposit")
+	}
+	if len(d.Proof) != depositProofLength {
+		return sszDeposit{}, errors.New("invalid deposit proof")
+	}
+	res := sszDeposit{
+		Proof: make([][]byte, len(d.Proof)),
+		Data: sszDepositData{
+			PubKey:                d.Data.PubKey,
+			WithdrawalCredentials: d.Data.WithdrawalCredentials,
+			Amount:                uint64(d.Data.Amount),
+			Signature:             d.Data.Signature,
+		},
+	}
+	for i := range d.Proof {
+		if len(d.Proof[i]) != 32 {
+			return sszDeposit{}, errors.New("invalid deposit proof")
+		}
+		res.Proof[i] = d.Proof[i]
+	}
+	if len(res.Data.PubKey) != 48 {
+		return sszDeposit{}, errors.New("invalid deposit public key")
+	}
+	if len(res.Data.WithdrawalCredentials) != 32 {
+		return sszDeposit{}, errors.New("invalid withdrawal credentials")
+	}
+	if len(res.Data.Signature) != 96 {
+		return sszDeposit{}, errors.New("invalid deposit signature")
+	}
+	return res, nil
+}
+
+func (e *signedVoluntaryExit) ssz() (sszSignedVoluntaryExit, error) {
+	if e == nil || e.Message == nil {
+		return sszSignedVoluntaryExit{}, errors.New("missing voluntary exit")
+	}
+	res := sszSignedVoluntaryExit{
+		Message: sszVoluntaryExit{
+			Epoch:          uint64(e.Message.Epoch),
+			ValidatorIndex: uint64(e.Message.ValidatorIndex),
+		},
+		Signature: e.Signature,
+	}
+	if len(res.Signature) != 96 {
+		return sszSignedVoluntaryExit{}, errors.New("invalid signature")
+	}
+	return res, nil
+}
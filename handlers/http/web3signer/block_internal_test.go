@@ -0,0 +1,130 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web3signer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hash returns the SHA-256 hash of the concatenation of its inputs.
+func hash(a []byte, b []byte) []byte {
+	res := sha256.Sum256(append(append([]byte{}, a...), b...))
+	return res[:]
+}
+
+// zeroHash returns the root of a tree of zero chunks of the given depth.
+func zeroHash(depth int) []byte {
+	res := make([]byte, 32)
+	for i := 0; i < depth; i++ {
+		res = hash(res, res)
+	}
+	return res
+}
+
+// uint64Chunk returns a chunk containing a little-endian integer.
+func uint64Chunk(val uint64) []byte {
+	res := make([]byte, 32)
+	binary.LittleEndian.PutUint64(res, val)
+	return res
+}
+
+// signatureRoot returns the root of a signature made up of repeated bytes.
+func signatureRoot(b byte) []byte {
+	chunk := bytes.Repeat([]byte{b}, 32)
+	return hash(hash(chunk, chunk), hash(chunk, make([]byte, 32)))
+}
+
+// listRoot returns the root of a list with a single item, or no items if the item is nil.
+func listRoot(item []byte, depth int) []byte {
+	if item == nil {
+		return hash(zeroHash(depth), uint64Chunk(0))
+	}
+	root := item
+	for i := 0; i < depth; i++ {
+		root = hash(root, zeroHash(i))
+	}
+	return hash(root, uint64Chunk(1))
+}
+
+func TestBlockBodyRoot(t *testing.T) {
+	root := func(b byte) string {
+		return `"0x` + string(bytes.Repeat([]byte{"0123456789abcdef"[b>>4], "0123456789abcdef"[b&0x0f]}, 32)) + `"`
+	}
+	sig := `"0x` + string(bytes.Repeat([]byte("07"), 96)) + `"`
+	body := `{"randao_reveal":` + sig + `,"eth1_data":{"deposit_root":` + root(0x04) + `,"deposit_count":"7","block_hash":` + root(0x05) + `},"graffiti":` + root(0x06) + `,"proposer_slashings":[],"attester_slashings":[],"attestations":[],"deposits":[],"voluntary_exits":[%s]}`
+
+	eth1DataRoot := hash(hash(bytes.Repeat([]byte{0x04}, 32), uint64Chunk(7)), hash(bytes.Repeat([]byte{0x05}, 32), make([]byte, 32)))
+	exitRoot := hash(hash(uint64Chunk(2), uint64Chunk(9)), signatureRoot(0x07))
+	bodyRoot := func(exits []byte) []byte {
+		fields := [][]byte{
+			signatureRoot(0x07),
+			eth1DataRoot,
+			bytes.Repeat([]byte{0x06}, 32),
+			listRoot(nil, 4),
+			listRoot(nil, 1),
+			listRoot(nil, 7),
+			listRoot(nil, 4),
+			listRoot(exits, 4),
+		}
+		return hash(
+			hash(hash(fields[0], fields[1]), hash(fields[2], fields[3])),
+			hash(hash(fields[4], fields[5]), hash(fields[6], fields[7])),
+		)
+	}
+
+	tests := []struct {
+		name string
+		body string
+		root []byte
+		err  string
+	}{
+		{
+			name: "Empty",
+			body: fmt.Sprintf(body, ``),
+			root: bodyRoot(nil),
+		},
+		{
+			name: "VoluntaryExit",
+			body: fmt.Sprintf(body, `{"message":{"epoch":"2","validator_index":"9"},"signature":`+sig+`}`),
+			root: bodyRoot(exitRoot),
+		},
+		{
+			name: "ShortSignature",
+			body: fmt.Sprintf(body, `{"message":{"epoch":"2","validator_index":"9"},"signature":"0x07"}`),
+			err:  "invalid signature",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			block := &phase0Block{}
+			require.NoError(t, json.Unmarshal([]byte(`{"slot":"1","body":`+test.body+`}`), block))
+			header, err := block.header()
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.root, []byte(header.BodyRoot))
+		})
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package web3signer provides a subset of the Web3Signer REST API, allowing validator clients that use it to sign
+// with walletd.
+package web3signer
+
+import (
+	"net/http"
+
+	"github.com/wealdtech/walletd/interceptors"
+	"github.com/wealdtech/walletd/services/checker"
+	"github.com/wealdtech/walletd/services/fetcher"
+	"github.com/wealdtech/walletd/services/networks"
+	"github.com/wealdtech/walletd/services/ruler"
+	signersvc "github.com/wealdtech/walletd/services/signer"
+)
+
+// Handler is the Web3Signer handler, allowing access to signer functions through HTTP.
+type Handler struct {
+	signer         *signersvc.Service
+	checker        checker.Service
+	fetcher        fetcher.Service
+	ruler          ruler.Service
+	networks       *networks.Service
+	identitySource interceptors.IdentitySource
+	mux            *http.ServeMux
+}
+
+// New creates a new Web3Signer handler.
+func New(signer *signersvc.Service, checker checker.Service, fetcher fetcher.Service, ruler ruler.Service, networks *networks.Service, identitySource interceptors.IdentitySource) *Handler {
+	h := &Handler{
+		signer:         signer,
+		checker:        checker,
+		fetcher:        fetcher,
+		ruler:          ruler,
+		networks:       networks,
+		identitySource: identitySource,
+		mux:            http.NewServeMux(),
+	}
+	h.mux.HandleFunc("/upcheck", h.upcheck)
+	h.mux.HandleFunc("/api/v1/eth2/publicKeys", h.publicKeys)
+	h.mux.HandleFunc("/api/v1/eth2/sign/", h.sign)
+	return h
+}
+
+// ServeHTTP serves an HTTP request.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// upcheck confirms that the server is running.
+func (h *Handler) upcheck(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write([]byte("OK"))
+}
@@ -0,0 +1,411 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web3signer_test
+
+import (
+	"bytes"
+	context "context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+	e2wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+	"github.com/wealdtech/walletd/api"
+	"github.com/wealdtech/walletd/core"
+	"github.com/wealdtech/walletd/handlers/http/web3signer"
+	"github.com/wealdtech/walletd/interceptors"
+	keysunlocker "github.com/wealdtech/walletd/services/autounlocker/keys"
+	mockchecker "github.com/wealdtech/walletd/services/checker/mock"
+	"github.com/wealdtech/walletd/services/fetcher/memfetcher"
+	"github.com/wealdtech/walletd/services/locker"
+	"github.com/wealdtech/walletd/services/networks"
+	timedrelocker "github.com/wealdtech/walletd/services/relocker/timed"
+	"github.com/wealdtech/walletd/services/ruler"
+	"github.com/wealdtech/walletd/services/ruler/lua"
+	signersvc "github.com/wealdtech/walletd/services/signer"
+	"github.com/wealdtech/walletd/services/storage/mem"
+)
+
+func TestMain(m *testing.M) {
+	if err := e2types.InitBLS(); err != nil {
+		os.Exit(1)
+	}
+	os.Exit(m.Run())
+}
+
+// phase0Block is a phase 0 beacon block with a voluntary exit and an attestation.
+const phase0Block = `{"slot":"35","proposer_index":"4","parent_root":"0x0202020202020202020202020202020202020202020202020202020202020202","state_root":"0x0303030303030303030303030303030303030303030303030303030303030303","body":{"randao_reveal":"0x` + sig96 + `","eth1_data":{"deposit_root":"0x0404040404040404040404040404040404040404040404040404040404040404","deposit_count":"7","block_hash":"0x0505050505050505050505050505050505050505050505050505050505050505"},"graffiti":"0x0606060606060606060606060606060606060606060606060606060606060606","proposer_slashings":[],"attester_slashings":[],"attestations":[{"aggregation_bits":"0x0b","data":{"slot":"34","index":"1","beacon_block_root":"0x0202020202020202020202020202020202020202020202020202020202020202","source":{"epoch":"0","root":"0x0303030303030303030303030303030303030303030303030303030303030303"},"target":{"epoch":"1","root":"0x0404040404040404040404040404040404040404040404040404040404040404"}},"signature":"0x` + sig96 + `"}],"deposits":[],"voluntary_exits":[{"message":{"epoch":"2","validator_index":"9"},"signature":"0x` + sig96 + `"}]}}`
+
+// sig96 is a hex-encoded 96-byte signature.
+const sig96 = "070707070707070707070707070707070707070707070707070707070707070707070707070707070707070707070707070707070707070707070707070707070707070707070707070707070707070707070707070707070707070707070707"
+
+const forkInfo = `"fork_info":{"fork":{"previous_version":"0x00000000","current_version":"0x00000001","epoch":"10"},"genesis_validators_root":"0x0101010101010101010101010101010101010101010101010101010101010101"}`
+
+func TestUpcheck(t *testing.T) {
+	handler, _, _ := setup(t, nil)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, request(http.MethodGet, "/upcheck", "", "client1"))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "OK", rec.Body.String())
+}
+
+func TestPublicKeys(t *testing.T) {
+	handler, pubKeys, _ := setup(t, nil)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, request(http.MethodGet, "/api/v1/eth2/publicKeys", "", "client1"))
+	require.Equal(t, http.StatusOK, rec.Code)
+	res := make([]string, 0)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	// The account the client cannot access is not listed.
+	assert.Equal(t, []string{fmt.Sprintf("%#x", pubKeys["Account 1"])}, res)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, request(http.MethodGet, "/api/v1/eth2/publicKeys", "", "Deny client"))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "[]\n", rec.Body.String())
+}
+
+func TestSign(t *testing.T) {
+	handler, pubKeys, _ := setup(t, nil)
+	account1 := fmt.Sprintf("%#x", pubKeys["Account 1"])
+
+	tests := []struct {
+		name       string
+		method     string
+		identifier string
+		body       string
+		client     string
+		accept     string
+		cancelled  bool
+		status     int
+		approval   bool
+	}{
+		{
+			name:       "BadMethod",
+			method:     http.MethodGet,
+			identifier: account1,
+			status:     http.StatusMethodNotAllowed,
+		},
+		{
+			name:       "InvalidIdentifier",
+			method:     http.MethodPost,
+			identifier: "0x01",
+			body:       `{"type":"RANDAO_REVEAL",` + forkInfo + `,"randao_reveal":{"epoch":"1"}}`,
+			status:     http.StatusBadRequest,
+		},
+		{
+			name:       "UnknownKey",
+			method:     http.MethodPost,
+			identifier: fmt.Sprintf("%#x", bytes.Repeat([]byte{0x01}, 48)),
+			body:       `{"type":"RANDAO_REVEAL",` + forkInfo + `,"randao_reveal":{"epoch":"1"}}`,
+			status:     http.StatusNotFound,
+		},
+		{
+			name:       "InvalidBody",
+			method:     http.MethodPost,
+			identifier: account1,
+			body:       `{"type":"RANDAO_REVEAL",` + forkInfo + `,"randao_reveal":{"epoch":"one"}}`,
+			status:     http.StatusBadRequest,
+		},
+		{
+			name:       "UnsupportedType",
+			method:     http.MethodPost,
+			identifier: account1,
+			body:       `{"type":"SYNC_COMMITTEE_MESSAGE",` + forkInfo + `}`,
+			status:     http.StatusBadRequest,
+		},
+		{
+			name:       "MissingForkInfo",
+			method:     http.MethodPost,
+			identifier: account1,
+			body:       `{"type":"RANDAO_REVEAL","randao_reveal":{"epoch":"1"}}`,
+			status:     http.StatusBadRequest,
+		},
+		{
+			name:       "NoPermission",
+			method:     http.MethodPost,
+			identifier: account1,
+			body:       `{"type":"RANDAO_REVEAL",` + forkInfo + `,"randao_reveal":{"epoch":"1"}}`,
+			client:     "Deny client",
+			status:     http.StatusNotFound,
+		},
+		{
+			name:       "NoAccessToKey",
+			method:     http.MethodPost,
+			identifier: fmt.Sprintf("%#x", pubKeys["Deny this account"]),
+			body:       `{"type":"RANDAO_REVEAL",` + forkInfo + `,"randao_reveal":{"epoch":"1"}}`,
+			status:     http.StatusNotFound,
+		},
+		{
+			name:       "RANDAOReveal",
+			method:     http.MethodPost,
+			identifier: account1,
+			body:       `{"type":"RANDAO_REVEAL",` + forkInfo + `,"randao_reveal":{"epoch":"1"}}`,
+			status:     http.StatusOK,
+		},
+		{
+			name:       "RANDAORevealJSON",
+			method:     http.MethodPost,
+			identifier: account1,
+			body:       `{"type":"RANDAO_REVEAL",` + forkInfo + `,"randao_reveal":{"epoch":"1"}}`,
+			accept:     "application/json",
+			status:     http.StatusOK,
+		},
+		{
+			name:       "Attestation",
+			method:     http.MethodPost,
+			identifier: account1,
+			body:       `{"type":"ATTESTATION",` + forkInfo + `,"attestation":{"slot":"32","index":"0","beacon_block_root":"0x0202020202020202020202020202020202020202020202020202020202020202","source":{"epoch":"0","root":"0x0303030303030303030303030303030303030303030303030303030303030303"},"target":{"epoch":"1","root":"0x0404040404040404040404040404040404040404040404040404040404040404"}}}`,
+			status:     http.StatusOK,
+		},
+		{
+			name:       "Block",
+			method:     http.MethodPost,
+			identifier: account1,
+			body:       `{"type":"BLOCK_V2",` + forkInfo + `,"beacon_block":{"version":"PHASE0","block_header":{"slot":"33","proposer_index":"4","parent_root":"0x0202020202020202020202020202020202020202020202020202020202020202","state_root":"0x0303030303030303030303030303030303030303030303030303030303030303","body_root":"0x0404040404040404040404040404040404040404040404040404040404040404"}}}`,
+			status:     http.StatusOK,
+		},
+		{
+			name:       "BlockV1",
+			method:     http.MethodPost,
+			identifier: account1,
+			body:       `{"type":"BLOCK",` + forkInfo + `,"block":` + phase0Block + `}`,
+			status:     http.StatusOK,
+		},
+		{
+			name:       "BlockPhase0",
+			method:     http.MethodPost,
+			identifier: account1,
+			body:       `{"type":"BLOCK_V2",` + forkInfo + `,"beacon_block":{"version":"PHASE0","block":` + phase0Block + `}}`,
+			status:     http.StatusOK,
+		},
+		{
+			name:       "BlockAltairWithoutHeader",
+			method:     http.MethodPost,
+			identifier: account1,
+			body:       `{"type":"BLOCK_V2",` + forkInfo + `,"beacon_block":{"version":"ALTAIR","block":` + phase0Block + `}}`,
+			status:     http.StatusBadRequest,
+		},
+		{
+			name:       "BlockMissingBody",
+			method:     http.MethodPost,
+			identifier: account1,
+			body:       `{"type":"BLOCK",` + forkInfo + `,"block":{"slot":"36","proposer_index":"4","parent_root":"0x0202020202020202020202020202020202020202020202020202020202020202","state_root":"0x0303030303030303030303030303030303030303030303030303030303030303"}}`,
+			status:     http.StatusBadRequest,
+		},
+		{
+			name:       "AggregationSlot",
+			method:     http.MethodPost,
+			identifier: account1,
+			body:       `{"type":"AGGREGATION_SLOT",` + forkInfo + `,"aggregation_slot":{"slot":"34"}}`,
+			status:     http.StatusOK,
+		},
+		{
+			name:       "Deposit",
+			method:     http.MethodPost,
+			identifier: account1,
+			body:       `{"type":"DEPOSIT","deposit":{"pubkey":"` + account1 + `","withdrawal_credentials":"0x0005050505050505050505050505050505050505050505050505050505050505","amount":"32000000000","genesis_fork_version":"0x00000000"}}`,
+			status:     http.StatusOK,
+		},
+		{
+			name:       "VoluntaryExit",
+			method:     http.MethodPost,
+			identifier: account1,
+			body:       `{"type":"VOLUNTARY_EXIT",` + forkInfo + `,"voluntary_exit":{"epoch":"20","validator_index":"5"}}`,
+			status:     http.StatusAccepted,
+			approval:   true,
+		},
+		{
+			name:       "Expired",
+			method:     http.MethodPost,
+			identifier: account1,
+			body:       `{"type":"RANDAO_REVEAL",` + forkInfo + `,"randao_reveal":{"epoch":"2"}}`,
+			cancelled:  true,
+			status:     http.StatusRequestTimeout,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := test.client
+			if client == "" {
+				client = "client1"
+			}
+			req := request(test.method, "/api/v1/eth2/sign/"+test.identifier, test.body, client)
+			if test.accept != "" {
+				req.Header.Set("Accept", test.accept)
+			}
+			if test.cancelled {
+				ctx, cancel := context.WithCancel(req.Context())
+				cancel()
+				req = req.WithContext(ctx)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			require.Equal(t, test.status, rec.Code, rec.Body.String())
+			if test.approval {
+				assert.NotEmpty(t, rec.Header().Get(api.ApprovalIDMetadataKey))
+			}
+			if test.status != http.StatusOK {
+				return
+			}
+			encoded := rec.Body.String()
+			if test.accept == "application/json" {
+				res := make(map[string]string)
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+				encoded = res["signature"]
+			}
+			require.True(t, strings.HasPrefix(encoded, "0x"))
+			signature, err := hex.DecodeString(strings.TrimPrefix(encoded, "0x"))
+			require.NoError(t, err)
+			assert.Len(t, signature, 96)
+		})
+	}
+}
+
+func TestClientInfo(t *testing.T) {
+	handler, pubKeys, rulerSvc := setup(t, nil)
+
+	req := request(http.MethodPost, fmt.Sprintf("/api/v1/eth2/sign/%#x", pubKeys["Account 1"]), `{"type":"RANDAO_REVEAL",`+forkInfo+`,"randao_reveal":{"epoch":"3"}}`, "client1")
+	req.RemoteAddr = "192.0.2.1:12345"
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	// Rules see the client and source IP of the request.
+	assert.Equal(t, "client1", rulerSvc.client)
+	assert.Equal(t, "192.0.2.1", rulerSvc.ip)
+}
+
+func TestSlotsPerEpoch(t *testing.T) {
+	// The network has only the current fork version of the fork information, so requests that select the previous
+	// fork version are denied.
+	handler, pubKeys, _ := setup(t, []*core.NetworkConfig{
+		{
+			Name:                  "test",
+			GenesisValidatorsRoot: "0x0101010101010101010101010101010101010101010101010101010101010101",
+			ForkVersions:          []string{"0x00000001"},
+			SlotsPerEpoch:         8,
+		},
+	})
+	account1 := fmt.Sprintf("%#x", pubKeys["Account 1"])
+
+	tests := []struct {
+		name   string
+		slot   int
+		status int
+	}{
+		{
+			name:   "PreviousFork",
+			slot:   79,
+			status: http.StatusPreconditionFailed,
+		},
+		{
+			name:   "CurrentFork",
+			slot:   80,
+			status: http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			body := fmt.Sprintf(`{"type":"AGGREGATION_SLOT",%s,"aggregation_slot":{"slot":"%d"}}`, forkInfo, test.slot)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, request(http.MethodPost, "/api/v1/eth2/sign/"+account1, body, "client1"))
+			assert.Equal(t, test.status, rec.Code, rec.Body.String())
+		})
+	}
+}
+
+// recordingRuler records the client and source IP seen by the rules.
+type recordingRuler struct {
+	ruler.Service
+	client string
+	ip     string
+}
+
+// RunRules records the client and source IP from the context before running the rules.
+func (r *recordingRuler) RunRules(ctx context.Context, action string, walletName string, accountName string, pubKey []byte, data interface{}) core.RulesResult {
+	r.client, _ = ctx.Value(&interceptors.ClientName{}).(string)
+	r.ip, _ = ctx.Value(&interceptors.ExternalIP{}).(string)
+	return r.Service.RunRules(ctx, action, walletName, accountName, pubKey, data)
+}
+
+// request creates a request as if sent over TLS by the named client.
+func request(method string, path string, body string, client string) *http.Request {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.TLS = &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{
+			{Subject: pkix.Name{CommonName: client}},
+		},
+	}
+	return req
+}
+
+// setup creates a Web3Signer handler with a wallet on the given networks, returning the public keys of the wallet's
+// accounts and the ruler.
+func setup(t *testing.T, networkConfigs []*core.NetworkConfig) (*web3signer.Handler, map[string][]byte, *recordingRuler) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("Wallet 1", []byte("Wallet 1 passphrase"), store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock([]byte("Wallet 1 passphrase")))
+	pubKeys := make(map[string][]byte)
+	for _, name := range []string{"Account 1", "Deny this account"} {
+		account, err := wallet.CreateAccount(name, []byte(name+" passphrase"))
+		require.NoError(t, err)
+		pubKeys[name] = account.PublicKey().Marshal()
+	}
+	wallet.Lock()
+
+	lockerSvc, err := locker.New()
+	require.NoError(t, err)
+	fetcherSvc, err := memfetcher.New(context.Background(), []e2wtypes.Store{store})
+	require.NoError(t, err)
+	storageSvc, err := mem.New()
+	require.NoError(t, err)
+	luaSvc, err := lua.New(lockerSvc, storageSvc, []*core.Rule{})
+	require.NoError(t, err)
+	rulerSvc := &recordingRuler{Service: luaSvc}
+	unlockerSvc, err := keysunlocker.New(context.Background(), &core.KeysConfig{
+		Keys: []string{"Account 1 passphrase"},
+	})
+	require.NoError(t, err)
+	checkerSvc, err := mockchecker.New()
+	require.NoError(t, err)
+	relockerSvc, err := timedrelocker.New(context.Background(), nil)
+	require.NoError(t, err)
+	networksSvc, err := networks.New(context.Background(), networkConfigs, nil)
+	require.NoError(t, err)
+	signerSvc, err := signersvc.New(unlockerSvc, relockerSvc, checkerSvc, fetcherSvc, rulerSvc, networksSvc)
+	require.NoError(t, err)
+
+	return web3signer.New(signerSvc, checkerSvc, fetcherSvc, rulerSvc, networksSvc, interceptors.IdentitySourceCN), pubKeys, rulerSvc
+}
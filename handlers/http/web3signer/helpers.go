@@ -0,0 +1,59 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web3signer
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/wealdtech/walletd/interceptors"
+	"github.com/wealdtech/walletd/services/checker"
+)
+
+// generateCredentials generates credentials from the request information.
+func (h *Handler) generateCredentials(r *http.Request) *checker.Credentials {
+	res := &checker.Credentials{}
+
+	// Validity, expiry and CA are checked by the TLS handshake, as is revocation if configured.
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		peerCert := r.TLS.PeerCertificates[0]
+		res.Fingerprint = interceptors.Fingerprint(peerCert)
+		identity, err := interceptors.Identity(peerCert, h.identitySource)
+		if err != nil {
+			log.Warn().Err(err).Str("fingerprint", res.Fingerprint).Msg("Failed to obtain client identity")
+		} else {
+			res.Client = identity
+		}
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		res.SourceIP = host
+	}
+	return res
+}
+
+// withClientInfo adds the client identity and source IP from the credentials to the context, as the gRPC interceptors
+// do for gRPC requests, so that they are available to rules.
+func withClientInfo(ctx context.Context, credentials *checker.Credentials) context.Context {
+	if credentials.Client != "" {
+		ctx = context.WithValue(ctx, &interceptors.ClientName{}, credentials.Client)
+	}
+	if credentials.Fingerprint != "" {
+		ctx = context.WithValue(ctx, &interceptors.ClientFingerprint{}, credentials.Fingerprint)
+	}
+	if credentials.SourceIP != "" {
+		ctx = context.WithValue(ctx, &interceptors.ExternalIP{}, credentials.SourceIP)
+	}
+	return ctx
+}
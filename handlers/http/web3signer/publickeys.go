@@ -0,0 +1,76 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web3signer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/wealdtech/walletd/core"
+	"github.com/wealdtech/walletd/services/ruler"
+	"github.com/wealdtech/walletd/util"
+)
+
+// publicKeys lists the public keys of the accounts that the client can access.
+func (h *Handler) publicKeys(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	credentials := h.generateCredentials(r)
+	ctx := withClientInfo(r.Context(), credentials)
+	log := log.With().Str("client", credentials.Client).Logger()
+	log.Debug().Msg("Public keys request received")
+
+	paths, err := h.fetcher.AccountPaths(ctx)
+	if err != nil {
+		log.Warn().Err(err).Str("result", "failed").Msg("Failed to obtain accounts")
+		http.Error(w, "failed to obtain accounts", http.StatusServiceUnavailable)
+		return
+	}
+	accounts := make([]string, 0, len(paths))
+	pubKeys := make(map[string]string, len(paths))
+	for pubKey, path := range paths {
+		walletName, accountName, err := util.WalletAndAccountNamesFromPath(path)
+		if err != nil {
+			log.Warn().Err(err).Str("path", path).Msg("Failed to obtain wallet and account names from path")
+			continue
+		}
+		if !h.checker.Check(ctx, credentials, path, ruler.ActionAccessAccount) {
+			continue
+		}
+		key := pubKey
+		if h.ruler.RunRules(ctx, ruler.ActionAccessAccount, walletName, accountName, key[:], &ruler.AccessAccountData{}) != core.APPROVED {
+			continue
+		}
+		accounts = append(accounts, path)
+		pubKeys[path] = fmt.Sprintf("%#x", key)
+	}
+
+	// Return keys in a stable order.
+	sort.Strings(accounts)
+	res := make([]string, len(accounts))
+	for i, account := range accounts {
+		res[i] = pubKeys[account]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		log.Warn().Err(err).Msg("Failed to write response")
+		return
+	}
+	log.Debug().Int("keys", len(res)).Str("result", "succeeded").Msg("Success")
+}
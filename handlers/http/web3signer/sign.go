@@ -0,0 +1,261 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web3signer
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+	"github.com/wealdtech/walletd/api"
+	"github.com/wealdtech/walletd/core"
+	"github.com/wealdtech/walletd/interceptors"
+	"github.com/wealdtech/walletd/services/checker"
+	"github.com/wealdtech/walletd/services/ruler"
+)
+
+const (
+	// signPath is the path prefix for signing requests; the public key of the signing account follows it.
+	signPath = "/api/v1/eth2/sign/"
+	// maxRequestSize is the maximum size of the body of a signing request.
+	maxRequestSize = 64 * 1024
+)
+
+var (
+	// domainSelectionProof is the domain type for selection proofs.
+	domainSelectionProof = e2types.DomainType{0x05, 0x00, 0x00, 0x00}
+	// domainAggregateAndProof is the domain type for aggregate and proofs.
+	domainAggregateAndProof = e2types.DomainType{0x06, 0x00, 0x00, 0x00}
+)
+
+// sign signs the data in the request with the account given by the public key in the path.
+func (h *Handler) sign(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ctx := r.Context()
+	credentials := h.generateCredentials(r)
+	identifier := strings.TrimPrefix(r.URL.Path, signPath)
+	log := log.With().Str("client", credentials.Client).Str("identifier", identifier).Logger()
+
+	pubKey, err := hex.DecodeString(strings.TrimPrefix(identifier, "0x"))
+	if err != nil || len(pubKey) != 48 {
+		log.Debug().Str("result", "denied").Msg("Invalid identifier")
+		http.Error(w, "invalid identifier", http.StatusBadRequest)
+		return
+	}
+	req := &signRequest{}
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxRequestSize)).Decode(req); err != nil {
+		log.Debug().Err(err).Str("result", "denied").Msg("Invalid request body")
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	log = log.With().Str("type", req.Type).Logger()
+	log.Debug().Msg("Sign request received")
+
+	// Keys that the client cannot access are reported in the same way as unknown keys, so that a client cannot find
+	// out which keys exist.
+	wallet, account, err := h.fetcher.FetchAccountByKey(ctx, pubKey)
+	if err != nil {
+		log.Debug().Err(err).Str("result", "denied").Msg("Account not found")
+		http.Error(w, "public key not found", http.StatusNotFound)
+		return
+	}
+	if !h.checker.Check(ctx, credentials, fmt.Sprintf("%s/%s", wallet.Name(), account.Name()), ruler.ActionAccessAccount) {
+		log.Debug().Str("result", "denied").Msg("Account not accessible")
+		http.Error(w, "public key not found", http.StatusNotFound)
+		return
+	}
+	ctx = withClientInfo(ctx, credentials)
+	if id := r.Header.Get(api.ApprovalIDMetadataKey); id != "" {
+		ctx = context.WithValue(ctx, &interceptors.ApprovalID{}, id)
+	}
+
+	result, signature, approvalID, err := h.signRequest(ctx, credentials, pubKey, req)
+	if err != nil {
+		log.Debug().Err(err).Str("result", "denied").Msg("Invalid request")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if approvalID != "" {
+		w.Header().Set(api.ApprovalIDMetadataKey, approvalID)
+	}
+
+	switch result {
+	case core.APPROVED:
+		writeSignature(w, r, signature)
+		log.Debug().Str("result", "succeeded").Msg("Success")
+	case core.DENIED:
+		http.Error(w, "signing denied", http.StatusPreconditionFailed)
+	case core.PENDING:
+		w.WriteHeader(http.StatusAccepted)
+	case core.EXPIRED:
+		http.Error(w, "request expired", http.StatusRequestTimeout)
+	default:
+		http.Error(w, "signing failed", http.StatusInternalServerError)
+	}
+}
+
+// signRequest passes the request to the signer according to its type.
+// An error is returned if the request is malformed or of an unsupported type.
+func (h *Handler) signRequest(ctx context.Context, credentials *checker.Credentials, pubKey []byte, req *signRequest) (core.RulesResult, []byte, string, error) {
+	// The number of slots in an epoch, used to select the fork version for slot-based requests, depends on the network.
+	var genesisValidatorsRoot []byte
+	if req.ForkInfo != nil {
+		genesisValidatorsRoot = req.ForkInfo.GenesisValidatorsRoot
+	}
+	slotsPerEpoch := h.networks.SlotsPerEpoch(genesisValidatorsRoot)
+
+	switch req.Type {
+	case "BLOCK", "BLOCK_V2":
+		header, err := req.blockHeader()
+		if err != nil {
+			return core.UNKNOWN, nil, "", err
+		}
+		domain, err := req.domain(e2types.DomainBeaconProposer, uint64(header.Slot)/slotsPerEpoch)
+		if err != nil {
+			return core.UNKNOWN, nil, "", err
+		}
+		result, signature, approvalID := h.signer.SignBeaconProposal(ctx, credentials, "", pubKey, &ruler.SignBeaconProposalData{
+			Domain:        domain,
+			Slot:          uint64(header.Slot),
+			ProposerIndex: uint64(header.ProposerIndex),
+			ParentRoot:    header.ParentRoot,
+			StateRoot:     header.StateRoot,
+			BodyRoot:      header.BodyRoot,
+		})
+		return result, signature, approvalID, nil
+	case "ATTESTATION":
+		data := req.Attestation
+		if data == nil || data.Source == nil || data.Target == nil {
+			return core.UNKNOWN, nil, "", errors.New("missing attestation")
+		}
+		domain, err := req.domain(e2types.DomainBeaconAttester, uint64(data.Target.Epoch))
+		if err != nil {
+			return core.UNKNOWN, nil, "", err
+		}
+		result, signature, approvalID := h.signer.SignBeaconAttestation(ctx, credentials, "", pubKey, &ruler.SignBeaconAttestationData{
+			Domain:          domain,
+			Slot:            uint64(data.Slot),
+			CommitteeIndex:  uint64(data.Index),
+			BeaconBlockRoot: data.BeaconBlockRoot,
+			Source:          data.Source.ruleCheckpoint(),
+			Target:          data.Target.ruleCheckpoint(),
+		})
+		return result, signature, approvalID, nil
+	case "AGGREGATION_SLOT":
+		if req.AggregationSlot == nil {
+			return core.UNKNOWN, nil, "", errors.New("missing aggregation slot")
+		}
+		domain, err := req.domain(domainSelectionProof, uint64(req.AggregationSlot.Slot)/slotsPerEpoch)
+		if err != nil {
+			return core.UNKNOWN, nil, "", err
+		}
+		result, signature, approvalID := h.signer.SignSelectionProof(ctx, credentials, "", pubKey, &ruler.SignSelectionProofData{
+			Domain: domain,
+			Slot:   uint64(req.AggregationSlot.Slot),
+		})
+		return result, signature, approvalID, nil
+	case "AGGREGATE_AND_PROOF":
+		data := req.AggregateAndProof
+		if data == nil || data.Aggregate == nil || data.Aggregate.Data == nil || data.Aggregate.Data.Source == nil || data.Aggregate.Data.Target == nil {
+			return core.UNKNOWN, nil, "", errors.New("missing aggregate and proof")
+		}
+		domain, err := req.domain(domainAggregateAndProof, uint64(data.Aggregate.Data.Slot)/slotsPerEpoch)
+		if err != nil {
+			return core.UNKNOWN, nil, "", err
+		}
+		result, signature, approvalID := h.signer.SignAggregateAndProof(ctx, credentials, "", pubKey, &ruler.SignAggregateAndProofData{
+			Domain:          domain,
+			AggregatorIndex: uint64(data.AggregatorIndex),
+			Aggregate: &ruler.Attestation{
+				AggregationBits: data.Aggregate.AggregationBits,
+				Data: &ruler.AttestationData{
+					Slot:            uint64(data.Aggregate.Data.Slot),
+					CommitteeIndex:  uint64(data.Aggregate.Data.Index),
+					BeaconBlockRoot: data.Aggregate.Data.BeaconBlockRoot,
+					Source:          data.Aggregate.Data.Source.ruleCheckpoint(),
+					Target:          data.Aggregate.Data.Target.ruleCheckpoint(),
+				},
+				Signature: data.Aggregate.Signature,
+			},
+			SelectionProof: data.SelectionProof,
+		})
+		return result, signature, approvalID, nil
+	case "RANDAO_REVEAL":
+		if req.RANDAOReveal == nil {
+			return core.UNKNOWN, nil, "", errors.New("missing RANDAO reveal")
+		}
+		domain, err := req.domain(e2types.DomainRANDAO, uint64(req.RANDAOReveal.Epoch))
+		if err != nil {
+			return core.UNKNOWN, nil, "", err
+		}
+		result, signature, approvalID := h.signer.SignRANDAOReveal(ctx, credentials, "", pubKey, &ruler.SignRANDAORevealData{
+			Domain: domain,
+			Epoch:  uint64(req.RANDAOReveal.Epoch),
+		})
+		return result, signature, approvalID, nil
+	case "VOLUNTARY_EXIT":
+		if req.VoluntaryExit == nil {
+			return core.UNKNOWN, nil, "", errors.New("missing voluntary exit")
+		}
+		domain, err := req.domain(e2types.DomainVoluntaryExit, uint64(req.VoluntaryExit.Epoch))
+		if err != nil {
+			return core.UNKNOWN, nil, "", err
+		}
+		result, signature, approvalID := h.signer.SignVoluntaryExit(ctx, credentials, "", pubKey, &ruler.SignVoluntaryExitData{
+			Domain:         domain,
+			Epoch:          uint64(req.VoluntaryExit.Epoch),
+			ValidatorIndex: uint64(req.VoluntaryExit.ValidatorIndex),
+		})
+		return result, signature, approvalID, nil
+	case "DEPOSIT":
+		if req.Deposit == nil {
+			return core.UNKNOWN, nil, "", errors.New("missing deposit")
+		}
+		// Deposits are valid across forks, so are signed with the genesis fork version and no genesis validators root.
+		if len(req.Deposit.GenesisForkVersion) != 4 {
+			return core.UNKNOWN, nil, "", errors.New("invalid genesis fork version")
+		}
+		domain := e2types.Domain(e2types.DomainDeposit, req.Deposit.GenesisForkVersion, make([]byte, 32))
+		result, signature, approvalID := h.signer.SignDeposit(ctx, credentials, "", pubKey, &ruler.SignDepositData{
+			Domain:                domain,
+			PubKey:                req.Deposit.PubKey,
+			WithdrawalCredentials: req.Deposit.WithdrawalCredentials,
+			Amount:                uint64(req.Deposit.Amount),
+		})
+		return result, signature, approvalID, nil
+	default:
+		return core.UNKNOWN, nil, "", fmt.Errorf("unsupported signing type %q", req.Type)
+	}
+}
+
+// writeSignature writes the signature in the format requested by the client.
+func writeSignature(w http.ResponseWriter, r *http.Request, signature []byte) {
+	encoded := fmt.Sprintf("%#x", signature)
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{"signature": encoded})
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write([]byte(encoded))
+}
@@ -0,0 +1,195 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web3signer
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+	"github.com/wealdtech/walletd/services/ruler"
+)
+
+// quantity is an unsigned integer, encoded in JSON as a decimal string as per the Ethereum 2 APIs.
+// Plain JSON numbers are also accepted.
+type quantity uint64
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (q *quantity) UnmarshalJSON(input []byte) error {
+	val, err := strconv.ParseUint(strings.Trim(string(input), `"`), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid quantity %s", string(input))
+	}
+	*q = quantity(val)
+	return nil
+}
+
+// hexBytes is a byte array, encoded in JSON as a 0x-prefixed hex string.
+type hexBytes []byte
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (b *hexBytes) UnmarshalJSON(input []byte) error {
+	var str string
+	if err := json.Unmarshal(input, &str); err != nil {
+		return fmt.Errorf("invalid hex string %s", string(input))
+	}
+	data, err := hex.DecodeString(strings.TrimPrefix(str, "0x"))
+	if err != nil {
+		return fmt.Errorf("invalid hex string %q", str)
+	}
+	*b = data
+	return nil
+}
+
+// signRequest is a Web3Signer signing request.
+// Only the object that matches the type of the request is used.
+type signRequest struct {
+	Type     string    `json:"type"`
+	ForkInfo *forkInfo `json:"fork_info"`
+	// SigningRoot is supplied by the client, but is not used; walletd always calculates the signing root itself.
+	SigningRoot       hexBytes           `json:"signingRoot"`
+	Block             *phase0Block       `json:"block"`
+	BeaconBlock       *beaconBlock       `json:"beacon_block"`
+	Attestation       *attestationData   `json:"attestation"`
+	AggregationSlot   *aggregationSlot   `json:"aggregation_slot"`
+	AggregateAndProof *aggregateAndProof `json:"aggregate_and_proof"`
+	RANDAOReveal      *randaoReveal      `json:"randao_reveal"`
+	VoluntaryExit     *voluntaryExit     `json:"voluntary_exit"`
+	Deposit           *deposit           `json:"deposit"`
+}
+
+type forkInfo struct {
+	Fork                  *fork    `json:"fork"`
+	GenesisValidatorsRoot hexBytes `json:"genesis_validators_root"`
+}
+
+type fork struct {
+	PreviousVersion hexBytes `json:"previous_version"`
+	CurrentVersion  hexBytes `json:"current_version"`
+	Epoch           quantity `json:"epoch"`
+}
+
+type beaconBlock struct {
+	Version     string             `json:"version"`
+	Block       *phase0Block       `json:"block"`
+	BlockHeader *beaconBlockHeader `json:"block_header"`
+}
+
+type beaconBlockHeader struct {
+	Slot          quantity `json:"slot"`
+	ProposerIndex quantity `json:"proposer_index"`
+	ParentRoot    hexBytes `json:"parent_root"`
+	StateRoot     hexBytes `json:"state_root"`
+	BodyRoot      hexBytes `json:"body_root"`
+}
+
+type attestationData struct {
+	Slot            quantity    `json:"slot"`
+	Index           quantity    `json:"index"`
+	BeaconBlockRoot hexBytes    `json:"beacon_block_root"`
+	Source          *checkpoint `json:"source"`
+	Target          *checkpoint `json:"target"`
+}
+
+type checkpoint struct {
+	Epoch quantity `json:"epoch"`
+	Root  hexBytes `json:"root"`
+}
+
+type aggregationSlot struct {
+	Slot quantity `json:"slot"`
+}
+
+type aggregateAndProof struct {
+	AggregatorIndex quantity     `json:"aggregator_index"`
+	Aggregate       *attestation `json:"aggregate"`
+	SelectionProof  hexBytes     `json:"selection_proof"`
+}
+
+type attestation struct {
+	AggregationBits hexBytes         `json:"aggregation_bits"`
+	Data            *attestationData `json:"data"`
+	Signature       hexBytes         `json:"signature"`
+}
+
+type randaoReveal struct {
+	Epoch quantity `json:"epoch"`
+}
+
+type voluntaryExit struct {
+	Epoch          quantity `json:"epoch"`
+	ValidatorIndex quantity `json:"validator_index"`
+}
+
+type deposit struct {
+	PubKey                hexBytes `json:"pubkey"`
+	WithdrawalCredentials hexBytes `json:"withdrawal_credentials"`
+	Amount                quantity `json:"amount"`
+	GenesisForkVersion    hexBytes `json:"genesis_fork_version"`
+}
+
+// blockHeader returns the header of the block to be signed.
+// `BLOCK` requests supply a phase 0 block.  `BLOCK_V2` requests supply either a block header or, for phase 0, a block;
+// the bodies of blocks for later forks are not understood, so their headers must be supplied.
+func (r *signRequest) blockHeader() (*beaconBlockHeader, error) {
+	if r.Type == "BLOCK" {
+		if r.Block == nil {
+			return nil, errors.New("missing block")
+		}
+		return r.Block.header()
+	}
+	switch {
+	case r.BeaconBlock == nil:
+		return nil, errors.New("missing block")
+	case r.BeaconBlock.BlockHeader != nil:
+		return r.BeaconBlock.BlockHeader, nil
+	case r.BeaconBlock.Block != nil:
+		if !strings.EqualFold(r.BeaconBlock.Version, "PHASE0") {
+			return nil, fmt.Errorf("block header required for version %q", r.BeaconBlock.Version)
+		}
+		return r.BeaconBlock.Block.header()
+	default:
+		return nil, errors.New("missing block header")
+	}
+}
+
+// domain returns the signing domain for the domain type at the given epoch, using the fork information of the request.
+func (r *signRequest) domain(domainType e2types.DomainType, epoch uint64) ([]byte, error) {
+	if r.ForkInfo == nil || r.ForkInfo.Fork == nil {
+		return nil, errors.New("missing fork information")
+	}
+	forkVersion := r.ForkInfo.Fork.CurrentVersion
+	if epoch < uint64(r.ForkInfo.Fork.Epoch) {
+		forkVersion = r.ForkInfo.Fork.PreviousVersion
+	}
+	if len(forkVersion) != 4 {
+		return nil, errors.New("invalid fork version")
+	}
+	if len(r.ForkInfo.GenesisValidatorsRoot) != 32 {
+		return nil, errors.New("invalid genesis validators root")
+	}
+	return e2types.Domain(domainType, forkVersion, r.ForkInfo.GenesisValidatorsRoot), nil
+}
+
+// ruleCheckpoint converts a checkpoint to its ruler equivalent.
+func (c *checkpoint) ruleCheckpoint() *ruler.Checkpoint {
+	return &ruler.Checkpoint{
+		Epoch: uint64(c.Epoch),
+		Root:  c.Root,
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interceptors
+
+import (
+	"context"
+
+	"github.com/wealdtech/walletd/api"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// ApprovalID is a context tag for the ID of an approval supplied with the request.
+type ApprovalID struct{}
+
+// ApprovalIDInterceptor adds the ID of an approval supplied in the request metadata to incoming requests.
+func ApprovalIDInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if ids := md.Get(api.ApprovalIDMetadataKey); len(ids) > 0 && ids[0] != "" {
+				ctx = context.WithValue(ctx, &ApprovalID{}, ids[0])
+			}
+		}
+		return handler(ctx, req)
+	}
+}
@@ -24,11 +24,15 @@ import (
 	"google.golang.org/grpc/status"
 )
 
-// ClientName is a context tag for the CN of the client's certificate.
+// ClientName is a context tag for the identity of the client.
 type ClientName struct{}
 
-// ClientInfoInterceptor adds the client certificate common name to incoming requests.
-func ClientInfoInterceptor() grpc.UnaryServerInterceptor {
+// ClientFingerprint is a context tag for the SHA-256 fingerprint of the client's certificate.
+type ClientFingerprint struct{}
+
+// ClientInfoInterceptor adds the client identity and certificate fingerprint to incoming requests.
+// The identity is obtained from the part of the client certificate given by the identity source.
+func ClientInfoInterceptor(source IdentitySource) grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 		grpcPeer, ok := peer.FromContext(ctx)
 		if !ok {
@@ -38,12 +42,19 @@ func ClientInfoInterceptor() grpc.UnaryServerInterceptor {
 		newCtx := ctx
 		authState := grpcPeer.AuthInfo.(credentials.TLSInfo).State
 		if authState.HandshakeComplete {
-			// TODO any further checks required here?  Validity, expiry, revocation, correct CA etc?
+			// Validity, expiry and CA are checked by the TLS handshake, as is revocation if configured.
 			peerCerts := authState.PeerCertificates
 			if len(peerCerts) > 0 {
 				peerCert := peerCerts[0]
-				newCtx = context.WithValue(ctx, &ClientName{}, peerCert.Subject.CommonName)
-				grpc_ctxtags.Extract(ctx).Set("client", peerCert.Subject.CommonName)
+				fingerprint := Fingerprint(peerCert)
+				newCtx = context.WithValue(newCtx, &ClientFingerprint{}, fingerprint)
+				identity, err := Identity(peerCert, source)
+				if err != nil {
+					log.Warn().Err(err).Str("fingerprint", fingerprint).Msg("Failed to obtain client identity")
+				} else {
+					newCtx = context.WithValue(newCtx, &ClientName{}, identity)
+					grpc_ctxtags.Extract(ctx).Set("client", identity)
+				}
 			}
 		}
 		return handler(newCtx, req)
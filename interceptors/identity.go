@@ -0,0 +1,95 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interceptors
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// IdentitySource is the part of the client certificate used to identify the client.
+type IdentitySource string
+
+const (
+	// IdentitySourceCN identifies the client by the common name of its certificate.
+	IdentitySourceCN IdentitySource = "cn"
+	// IdentitySourceDNSSAN identifies the client by the first DNS subject alternative name of its certificate.
+	IdentitySourceDNSSAN IdentitySource = "dns-san"
+	// IdentitySourceURISAN identifies the client by the first URI subject alternative name of its certificate.
+	IdentitySourceURISAN IdentitySource = "uri-san"
+	// IdentitySourceSPIFFE identifies the client by the SPIFFE ID in the URI subject alternative names of its certificate.
+	IdentitySourceSPIFFE IdentitySource = "spiffe"
+	// IdentitySourceFingerprint identifies the client by the SHA-256 fingerprint of its certificate.
+	IdentitySourceFingerprint IdentitySource = "fingerprint"
+)
+
+// ParseIdentitySource parses an identity source, defaulting to the common name if not supplied.
+func ParseIdentitySource(source string) (IdentitySource, error) {
+	switch IdentitySource(strings.ToLower(source)) {
+	case "", IdentitySourceCN:
+		return IdentitySourceCN, nil
+	case IdentitySourceDNSSAN:
+		return IdentitySourceDNSSAN, nil
+	case IdentitySourceURISAN:
+		return IdentitySourceURISAN, nil
+	case IdentitySourceSPIFFE:
+		return IdentitySourceSPIFFE, nil
+	case IdentitySourceFingerprint:
+		return IdentitySourceFingerprint, nil
+	default:
+		return "", fmt.Errorf("unknown identity source %q", source)
+	}
+}
+
+// Fingerprint returns the hex-encoded SHA-256 fingerprint of the certificate.
+func Fingerprint(cert *x509.Certificate) string {
+	fingerprint := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(fingerprint[:])
+}
+
+// Identity obtains the identity of the client from its certificate.
+func Identity(cert *x509.Certificate, source IdentitySource) (string, error) {
+	switch source {
+	case IdentitySourceCN:
+		if cert.Subject.CommonName == "" {
+			return "", errors.New("certificate has no common name")
+		}
+		return cert.Subject.CommonName, nil
+	case IdentitySourceDNSSAN:
+		if len(cert.DNSNames) == 0 {
+			return "", errors.New("certificate has no DNS subject alternative name")
+		}
+		return cert.DNSNames[0], nil
+	case IdentitySourceURISAN:
+		if len(cert.URIs) == 0 {
+			return "", errors.New("certificate has no URI subject alternative name")
+		}
+		return cert.URIs[0].String(), nil
+	case IdentitySourceSPIFFE:
+		for _, uri := range cert.URIs {
+			if uri.Scheme == "spiffe" {
+				return uri.String(), nil
+			}
+		}
+		return "", errors.New("certificate has no SPIFFE ID")
+	case IdentitySourceFingerprint:
+		return Fingerprint(cert), nil
+	default:
+		return "", fmt.Errorf("unknown identity source %q", source)
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interceptors_test
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wealdtech/walletd/interceptors"
+)
+
+func TestParseIdentitySource(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		res    interceptors.IdentitySource
+		err    string
+	}{
+		{
+			name: "Default",
+			res:  interceptors.IdentitySourceCN,
+		},
+		{
+			name:   "SPIFFE",
+			source: "SPIFFE",
+			res:    interceptors.IdentitySourceSPIFFE,
+		},
+		{
+			name:   "Unknown",
+			source: "serial",
+			err:    `unknown identity source "serial"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := interceptors.ParseIdentitySource(test.source)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.res, res)
+			}
+		})
+	}
+}
+
+func TestIdentity(t *testing.T) {
+	spiffeID, err := url.Parse("spiffe://example.org/validator/1")
+	require.NoError(t, err)
+	otherURI, err := url.Parse("https://example.org/validator/1")
+	require.NoError(t, err)
+
+	cert := &x509.Certificate{
+		Raw:      []byte("certificate"),
+		Subject:  pkix.Name{CommonName: "client1"},
+		DNSNames: []string{"client1.example.org", "client2.example.org"},
+		URIs:     []*url.URL{otherURI, spiffeID},
+	}
+	fingerprint := sha256.Sum256([]byte("certificate"))
+
+	tests := []struct {
+		name   string
+		cert   *x509.Certificate
+		source interceptors.IdentitySource
+		res    string
+		err    string
+	}{
+		{
+			name:   "CN",
+			cert:   cert,
+			source: interceptors.IdentitySourceCN,
+			res:    "client1",
+		},
+		{
+			name:   "CNMissing",
+			cert:   &x509.Certificate{},
+			source: interceptors.IdentitySourceCN,
+			err:    "certificate has no common name",
+		},
+		{
+			name:   "DNSSAN",
+			cert:   cert,
+			source: interceptors.IdentitySourceDNSSAN,
+			res:    "client1.example.org",
+		},
+		{
+			name:   "DNSSANMissing",
+			cert:   &x509.Certificate{},
+			source: interceptors.IdentitySourceDNSSAN,
+			err:    "certificate has no DNS subject alternative name",
+		},
+		{
+			name:   "URISAN",
+			cert:   cert,
+			source: interceptors.IdentitySourceURISAN,
+			res:    "https://example.org/validator/1",
+		},
+		{
+			name:   "SPIFFE",
+			cert:   cert,
+			source: interceptors.IdentitySourceSPIFFE,
+			res:    "spiffe://example.org/validator/1",
+		},
+		{
+			name:   "SPIFFEMissing",
+			cert:   &x509.Certificate{URIs: []*url.URL{otherURI}},
+			source: interceptors.IdentitySourceSPIFFE,
+			err:    "certificate has no SPIFFE ID",
+		},
+		{
+			name:   "Fingerprint",
+			cert:   cert,
+			source: interceptors.IdentitySourceFingerprint,
+			res:    hex.EncodeToString(fingerprint[:]),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := interceptors.Identity(test.cert, test.source)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.res, res)
+			}
+		})
+	}
+}
@@ -15,10 +15,13 @@ package main
 
 import (
 	"context"
+	"expvar"
 	"flag"
 	"os"
+	"os/signal"
 	"runtime"
 	"strings"
+	"syscall"
 
 	"net/http"
 	_ "net/http/pprof"
@@ -28,9 +31,16 @@ import (
 	"github.com/rs/zerolog/log"
 	e2types "github.com/wealdtech/go-eth2-types/v2"
 	"github.com/wealdtech/walletd/core"
+	"github.com/wealdtech/walletd/services/approvals"
 	"github.com/wealdtech/walletd/services/autounlocker"
+	helperautounlocker "github.com/wealdtech/walletd/services/autounlocker/helper"
 	"github.com/wealdtech/walletd/services/autounlocker/keys"
+	keystoresautounlocker "github.com/wealdtech/walletd/services/autounlocker/keystores"
+	multiautounlocker "github.com/wealdtech/walletd/services/autounlocker/multi"
 	staticchecker "github.com/wealdtech/walletd/services/checker/static"
+	"github.com/wealdtech/walletd/services/networks"
+	timedrelocker "github.com/wealdtech/walletd/services/relocker/timed"
+	"github.com/wealdtech/walletd/services/unlockguard"
 	"github.com/wealdtech/walletd/services/wallet"
 )
 
@@ -43,6 +53,8 @@ func main() {
 	flag.StringVar(&pprof, "pprof", "", "address of a pprof interface for profiling")
 	trace := false
 	flag.BoolVar(&trace, "trace", false, "provide opentracing stats")
+	metrics := ""
+	flag.StringVar(&metrics, "metrics", "", "address of an interface for metrics")
 	flag.Parse()
 
 	if pprof != "" {
@@ -55,6 +67,16 @@ func main() {
 		}()
 	}
 
+	if metrics != "" {
+		go func() {
+			mux := http.NewServeMux()
+			mux.Handle("/debug/vars", expvar.Handler())
+			if err := http.ListenAndServe(metrics, mux); err != nil {
+				log.Warn().Err(err).Msg("Failed to start metrics server")
+			}
+		}()
+	}
+
 	runtime.GOMAXPROCS(runtime.NumCPU() * 8)
 
 	ctx := context.Background()
@@ -101,12 +123,25 @@ func main() {
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to obtain permissions")
 	}
+
+	// Set up the checker.
+	checker, err := staticchecker.New(ctx, permissions)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to initialise certificate checker")
+	}
 	if showPerms {
 		// Need to dump our permission information.
-		core.DumpPerms(permissions)
+		checker.DumpPerms()
 		os.Exit(0)
 	}
 
+	// Set up the networks.
+	networks, err := networks.New(ctx, config.Networks, permissions)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to initialise networks")
+	}
+	go reloadPermissionsOnSignal(ctx, checker, networks)
+
 	// Initialise the keymanager stores.
 	stores, err := core.InitStores(ctx, config.Stores)
 	if err != nil {
@@ -119,27 +154,56 @@ func main() {
 		log.Fatal().Err(err).Msg("Failed to initialise rules")
 	}
 
-	// Set up the autounlocker.
-	var autounlocker autounlocker.Service
+	// Set up the autounlockers.
+	autounlockers := make([]autounlocker.Service, 0)
 	keysConfig, err := core.FetchKeysConfig()
-	if err != nil {
+	if err != nil && !os.IsNotExist(err) {
 		log.Fatal().Err(err).Msg("Failed to obtain keys config")
 	}
 	if keysConfig != nil {
-		autounlocker, err = keys.New(ctx, keysConfig)
+		keysAutounlocker, err := keys.New(ctx, keysConfig)
 		if err != nil {
 			log.Fatal().Err(err).Msg("Failed to initialise keys-based autounlocker")
 		}
+		autounlockers = append(autounlockers, keysAutounlocker)
+	}
+	keystoresAutounlocker, err := keystoresautounlocker.New(ctx)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to initialise keystores-based autounlocker")
+	}
+	autounlockers = append(autounlockers, keystoresAutounlocker)
+	if config.Helper != nil {
+		helperAutounlocker, err := helperautounlocker.New(ctx, config.Helper)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to initialise helper-based autounlocker")
+		}
+		autounlockers = append(autounlockers, helperAutounlocker)
+	}
+	autounlocker, err := multiautounlocker.New(ctx, autounlockers...)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to initialise autounlocker")
 	}
 
-	// Set up the checker.
-	checker, err := staticchecker.New(ctx, permissions)
+	// Set up the relocker.
+	relocker, err := timedrelocker.New(ctx, config.Relock)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to initialise certificate checker")
+		log.Fatal().Err(err).Msg("Failed to initialise relocker")
+	}
+
+	// Set up the unlock guard.
+	guard, err := unlockguard.New(ctx, config.UnlockGuard)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to initialise unlock guard")
+	}
+
+	// Set up the approvals.
+	approvals, err := approvals.New(ctx, config.Approvals)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to initialise approvals")
 	}
 
 	// Initialise the wallet GRPC service.
-	service, err := wallet.New(ctx, autounlocker, checker, stores, rules)
+	service, err := wallet.New(ctx, autounlocker, relocker, guard, checker, networks, approvals, stores, rules, config.Sign)
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to create daemon")
 	}
@@ -149,3 +213,30 @@ func main() {
 		log.Fatal().Err(err).Msg("Error running daemon")
 	}
 }
+
+// reloadPermissionsOnSignal reloads the client permissions whenever SIGHUP is received.
+func reloadPermissionsOnSignal(ctx context.Context, checker *staticchecker.StaticChecker, networks *networks.Service) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGHUP)
+	for range sigCh {
+		log.Info().Msg("Received SIGHUP; reloading permissions")
+		permissions, err := core.FetchPermissions()
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to obtain permissions; retaining existing permissions")
+			continue
+		}
+		// Validate the permissions for all services before applying them, so that the services remain consistent.
+		applyChecker, err := checker.PrepareReload(ctx, permissions)
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to reload permissions")
+			continue
+		}
+		applyNetworks, err := networks.PrepareReload(ctx, permissions)
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to reload client networks; retaining existing permissions")
+			continue
+		}
+		applyChecker()
+		applyNetworks()
+	}
+}
@@ -0,0 +1,25 @@
+-- Attempt to approve a request to sign an aggregate and proof.
+--
+-- Contents of request are as follows:
+--   - ip: the IP address of the requesting server (string)
+--   - client: the name of the client presenting the request, as defined by its certificate (string)
+--   - timestamp: the Unix timestamp of the request (number)
+--   - account: the name of the account presenting the request (string)
+--   - pubKey: the public key of the account presenting the request (string)
+--   - domain: the domain of the request (string)
+--   - aggregatorIndex: the validator index of the aggregator (number)
+--   - selectionProof: the selection proof of the aggregator (string)
+--   - aggregationBits: the aggregation bits of the aggregate attestation (string)
+--   - signature: the signature of the aggregate attestation (string)
+--   - slot: the slot of the aggregate attestation (number)
+--   - committeeIndex: the committee index of the aggregate attestation (number)
+--   - beaconBlockRoot: the beacon block root of the aggregate attestation (string)
+--   - sourceEpoch: the source epoch of the aggregate attestation (number)
+--   - sourceRoot: the source root of the aggregate attestation (string)
+--   - targetEpoch: the target epoch of the aggregate attestation (number)
+--   - targetRoot: the target root of the aggregate attestation (string)
+--  storage is storage that persists between calls, specific to this request type and account
+--  messages is a list of messages that will be printed in the logs at the conclusion of the script
+function approve(request, storage, messages)
+  return "Approved"
+end
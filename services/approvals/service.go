@@ -0,0 +1,379 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package approvals
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opentracing/opentracing-go"
+	"github.com/wealdtech/walletd/core"
+	"github.com/wealdtech/walletd/services/checker"
+)
+
+const (
+	defaultWindow   = time.Hour
+	defaultRequired = 1
+)
+
+var (
+	// ErrUnknownApproval is returned when deciding a request that does not exist.
+	ErrUnknownApproval = errors.New("unknown approval")
+	// ErrApprovalExpired is returned when deciding a request whose window has passed.
+	ErrApprovalExpired = errors.New("approval has expired")
+	// ErrAlreadyApproved is returned when approving a request that has already received all required approvals.
+	ErrAlreadyApproved = errors.New("already approved")
+	// ErrAlreadyRejected is returned when deciding a request that has been rejected.
+	ErrAlreadyRejected = errors.New("already rejected")
+	// ErrDuplicateApprover is returned when a client approves a request that it has already approved.
+	ErrDuplicateApprover = errors.New("client has already approved this request")
+	// ErrSelfApproval is returned when a client attempts to approve its own request.
+	ErrSelfApproval = errors.New("client cannot approve its own request")
+)
+
+// metrics are the metrics for approvals, available at /debug/vars.
+var metrics = expvar.NewMap("approvals")
+
+// Approval is a request that requires approval by operators before it completes.
+// Clients are identified by both their name and the fingerprint of their certificate.
+type Approval struct {
+	ID                string
+	Client            string
+	ClientFingerprint string
+	Account           string
+	Action            string
+	Root              []byte
+	Required          int
+	Created           time.Time
+	Expires           time.Time
+	Approvers         []string
+	Rejected          bool
+	Rejecter          string
+	// approverFingerprints are the certificate fingerprints of the approvers, in the same order.
+	approverFingerprints []string
+	// decided is closed when the request has been approved or rejected.
+	decided chan struct{}
+}
+
+// Approved returns true if the request has received all required approvals.
+func (a *Approval) Approved() bool {
+	return !a.Rejected && len(a.Approvers) >= a.Required
+}
+
+// Service tracks requests that require approval before they complete.
+type Service struct {
+	window    time.Duration
+	required  int
+	approvals map[string]*Approval
+	mutex     sync.Mutex
+}
+
+// New creates a new approvals service.
+func New(ctx context.Context, config *core.ApprovalsConfig) (*Service, error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "approvals.New")
+	defer span.Finish()
+
+	s := &Service{
+		window:    defaultWindow,
+		required:  defaultRequired,
+		approvals: make(map[string]*Approval),
+	}
+	if config != nil {
+		if config.Window < 0 {
+			return nil, fmt.Errorf("invalid approval window %v", config.Window)
+		}
+		if config.Window != 0 {
+			s.window = config.Window
+		}
+		if config.Required < 0 {
+			return nil, fmt.Errorf("invalid required approvals %d", config.Required)
+		}
+		if config.Required != 0 {
+			s.required = config.Required
+		}
+	}
+
+	return s, nil
+}
+
+// RequestedBy returns true if the request was made by the client with the given credentials.
+// A client with either the same name or the same certificate as the requesting client is treated as the same client.
+func (a *Approval) RequestedBy(credentials *checker.Credentials) bool {
+	name, fingerprint := identity(credentials)
+	return sameClient(a.Client, a.ClientFingerprint, name, fingerprint)
+}
+
+// ApprovedBy returns true if the client with the given credentials has already approved the request.
+// A client with either the same name or the same certificate as an approver is treated as that approver.
+func (a *Approval) ApprovedBy(credentials *checker.Credentials) bool {
+	name, fingerprint := identity(credentials)
+	for i := range a.Approvers {
+		if sameClient(a.Approvers[i], a.approverFingerprints[i], name, fingerprint) {
+			return true
+		}
+	}
+	return false
+}
+
+// Request requests approval for the client to carry out the action on the account for the given signing root.
+// If id is supplied it must refer to a request with the same details, otherwise a matching request is used if present.
+// If the request has been approved it is consumed and APPROVED is returned; if it has been rejected it is consumed
+// and DENIED is returned.  Otherwise the pending approval is returned, being created if this is the first request,
+// along with PENDING.
+func (s *Service) Request(id string, credentials *checker.Credentials, account string, action string, root []byte) (*Approval, core.RulesResult) {
+	client, fingerprint := identity(credentials)
+	log := log.With().Str("client", client).Str("account", account).Str("action", action).Str("root", fmt.Sprintf("%#x", root)).Logger()
+	now := time.Now()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.prune(now)
+	var approval *Approval
+	if id != "" {
+		var exists bool
+		approval, exists = s.approvals[id]
+		if !exists {
+			log.Info().Str("id", id).Str("result", "denied").Msg("Unknown or expired approval")
+			return nil, core.DENIED
+		}
+		if !approval.matches(client, fingerprint, account, action, root) {
+			log.Warn().Str("id", id).Str("result", "denied").Msg("Approval does not match request")
+			return nil, core.DENIED
+		}
+	} else {
+		for _, candidate := range s.approvals {
+			if candidate.matches(client, fingerprint, account, action, root) {
+				approval = candidate
+				break
+			}
+		}
+	}
+
+	if approval == nil {
+		approval = &Approval{
+			ID:                   uuid.New().String(),
+			Client:               client,
+			ClientFingerprint:    fingerprint,
+			Account:              account,
+			Action:               action,
+			Root:                 root,
+			Required:             s.required,
+			Created:              now,
+			Expires:              now.Add(s.window),
+			Approvers:            make([]string, 0),
+			approverFingerprints: make([]string, 0),
+			decided:              make(chan struct{}),
+		}
+		s.approvals[approval.ID] = approval
+		metrics.Add("requested", 1)
+		log.Info().Str("id", approval.ID).Int("required", approval.Required).Time("expires", approval.Expires).Msg("Request requires approval")
+		return approval.copy(), core.PENDING
+	}
+
+	log = log.With().Str("id", approval.ID).Logger()
+	switch {
+	case approval.Rejected:
+		delete(s.approvals, approval.ID)
+		log.Info().Str("rejecter", approval.Rejecter).Str("result", "denied").Msg("Rejected request denied")
+		return approval.copy(), core.DENIED
+	case approval.Approved():
+		delete(s.approvals, approval.ID)
+		metrics.Add("released", 1)
+		log.Info().Strs("approvers", approval.Approvers).Msg("Approved request released")
+		return approval.copy(), core.APPROVED
+	default:
+		log.Info().Int("approvals", len(approval.Approvers)).Int("required", approval.Required).Time("expires", approval.Expires).Msg("Request awaiting approval")
+		return approval.copy(), core.PENDING
+	}
+}
+
+// Wait waits until the request with the given ID has been approved or rejected, or the context is done.
+func (s *Service) Wait(ctx context.Context, id string) {
+	s.mutex.Lock()
+	approval, exists := s.approvals[id]
+	s.mutex.Unlock()
+	if !exists {
+		return
+	}
+
+	timer := time.NewTimer(time.Until(approval.Expires))
+	defer timer.Stop()
+	select {
+	case <-approval.decided:
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// Approval returns the approval with the given ID, or nil if there is no such approval.
+func (s *Service) Approval(id string) *Approval {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	approval, exists := s.approvals[id]
+	if !exists || time.Now().After(approval.Expires) {
+		return nil
+	}
+	return approval.copy()
+}
+
+// Pending returns all requests that are awaiting approval, oldest first.
+func (s *Service) Pending() []*Approval {
+	now := time.Now()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.prune(now)
+	res := make([]*Approval, 0)
+	for _, approval := range s.approvals {
+		if !approval.Rejected && !approval.Approved() {
+			res = append(res, approval.copy())
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Created.Before(res[j].Created)
+	})
+	return res
+}
+
+// Approve approves the request with the given ID on behalf of the client with the given credentials.
+// It returns the approval as it stands after the approver's approval has been added.
+func (s *Service) Approve(id string, credentials *checker.Credentials) (*Approval, error) {
+	approver, fingerprint := identity(credentials)
+	log := log.With().Str("id", id).Str("approver", approver).Logger()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	approval, err := s.decidable(id)
+	if err != nil {
+		log.Info().Err(err).Str("result", "denied").Msg("Approval refused")
+		return nil, err
+	}
+	log = log.With().Str("client", approval.Client).Str("account", approval.Account).Str("action", approval.Action).Logger()
+	if approval.RequestedBy(credentials) {
+		log.Warn().Str("result", "denied").Msg("Client attempted to approve its own request")
+		return nil, ErrSelfApproval
+	}
+	if approval.ApprovedBy(credentials) {
+		log.Info().Str("result", "denied").Msg("Client has already approved request")
+		return nil, ErrDuplicateApprover
+	}
+
+	approval.Approvers = append(approval.Approvers, approver)
+	approval.approverFingerprints = append(approval.approverFingerprints, fingerprint)
+	metrics.Add("approvals", 1)
+	if approval.Approved() {
+		close(approval.decided)
+		metrics.Add("approved", 1)
+		log.Info().Strs("approvers", approval.Approvers).Str("result", "succeeded").Msg("Request approved")
+	} else {
+		log.Info().Int("approvals", len(approval.Approvers)).Int("required", approval.Required).Str("result", "succeeded").Msg("Approval recorded")
+	}
+	return approval.copy(), nil
+}
+
+// Reject rejects the request with the given ID on behalf of the client with the given credentials.
+func (s *Service) Reject(id string, credentials *checker.Credentials) (*Approval, error) {
+	rejecter, _ := identity(credentials)
+	log := log.With().Str("id", id).Str("rejecter", rejecter).Logger()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	approval, err := s.decidable(id)
+	if err != nil {
+		log.Info().Err(err).Str("result", "denied").Msg("Rejection refused")
+		return nil, err
+	}
+
+	approval.Rejected = true
+	approval.Rejecter = rejecter
+	close(approval.decided)
+	metrics.Add("rejected", 1)
+	log.Info().Str("client", approval.Client).Str("account", approval.Account).Str("action", approval.Action).Str("result", "succeeded").Msg("Request rejected")
+	return approval.copy(), nil
+}
+
+// decidable returns the approval with the given ID if it is still awaiting a decision.
+// This assumes that the mutex is held.
+func (s *Service) decidable(id string) (*Approval, error) {
+	approval, exists := s.approvals[id]
+	if !exists {
+		return nil, ErrUnknownApproval
+	}
+	if time.Now().After(approval.Expires) {
+		return nil, ErrApprovalExpired
+	}
+	if approval.Rejected {
+		return nil, ErrAlreadyRejected
+	}
+	if approval.Approved() {
+		return nil, ErrAlreadyApproved
+	}
+	return approval, nil
+}
+
+// prune removes approvals whose window has passed.
+// This assumes that the mutex is held.
+func (s *Service) prune(now time.Time) {
+	for id, approval := range s.approvals {
+		if now.After(approval.Expires) {
+			delete(s.approvals, id)
+			metrics.Add("expired", 1)
+			log.Info().Str("id", id).Str("client", approval.Client).Str("account", approval.Account).Str("action", approval.Action).Bool("approved", approval.Approved()).Msg("Approval window expired")
+		}
+	}
+}
+
+// matches returns true if the approval is for the given request.
+func (a *Approval) matches(client string, fingerprint string, account string, action string, root []byte) bool {
+	return a.Client == client &&
+		a.ClientFingerprint == fingerprint &&
+		a.Account == account &&
+		a.Action == action &&
+		bytes.Equal(a.Root, root)
+}
+
+// copy returns a copy of the approval, so that it can be used outside of the mutex.
+func (a *Approval) copy() *Approval {
+	res := *a
+	res.Approvers = make([]string, len(a.Approvers))
+	copy(res.Approvers, a.Approvers)
+	res.approverFingerprints = make([]string, len(a.approverFingerprints))
+	copy(res.approverFingerprints, a.approverFingerprints)
+	return &res
+}
+
+// identity returns the name and certificate fingerprint of the client with the given credentials.
+func identity(credentials *checker.Credentials) (string, string) {
+	if credentials == nil {
+		return "", ""
+	}
+	return credentials.Client, credentials.Fingerprint
+}
+
+// sameClient returns true if two clients share either a name or a certificate.
+func sameClient(name1 string, fingerprint1 string, name2 string, fingerprint2 string) bool {
+	return name1 == name2 || (fingerprint1 != "" && fingerprint1 == fingerprint2)
+}
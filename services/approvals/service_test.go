@@ -0,0 +1,224 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package approvals_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wealdtech/walletd/core"
+	"github.com/wealdtech/walletd/services/approvals"
+	"github.com/wealdtech/walletd/services/checker"
+)
+
+func TestNew(t *testing.T) {
+	_, err := approvals.New(context.Background(), nil)
+	require.NoError(t, err)
+
+	_, err = approvals.New(context.Background(), &core.ApprovalsConfig{Window: -time.Second})
+	require.EqualError(t, err, "invalid approval window -1s")
+
+	_, err = approvals.New(context.Background(), &core.ApprovalsConfig{Required: -1})
+	require.EqualError(t, err, "invalid required approvals -1")
+}
+
+func TestApprove(t *testing.T) {
+	service, err := approvals.New(context.Background(), &core.ApprovalsConfig{Window: time.Hour})
+	require.NoError(t, err)
+	root := []byte{0x01, 0x02}
+
+	// First request creates a pending approval.
+	approval, result := service.Request("", &checker.Credentials{Client: "client1"}, "Wallet/Account", "Sign", root)
+	require.Equal(t, core.PENDING, result)
+	require.NotEmpty(t, approval.ID)
+	assert.Equal(t, 1, approval.Required)
+	assert.Len(t, service.Pending(), 1)
+
+	// Repeating the request returns the same pending approval, with or without its ID.
+	repeat, result := service.Request("", &checker.Credentials{Client: "client1"}, "Wallet/Account", "Sign", root)
+	require.Equal(t, core.PENDING, result)
+	assert.Equal(t, approval.ID, repeat.ID)
+	repeat, result = service.Request(approval.ID, &checker.Credentials{Client: "client1"}, "Wallet/Account", "Sign", root)
+	require.Equal(t, core.PENDING, result)
+	assert.Equal(t, approval.ID, repeat.ID)
+
+	// The ID cannot be used for a different request.
+	_, result = service.Request(approval.ID, &checker.Credentials{Client: "client1"}, "Wallet/Account", "Sign", []byte{0x03})
+	require.Equal(t, core.DENIED, result)
+	_, result = service.Request("unknown", &checker.Credentials{Client: "client1"}, "Wallet/Account", "Sign", root)
+	require.Equal(t, core.DENIED, result)
+
+	// A different root requires its own approval.
+	other, result := service.Request("", &checker.Credentials{Client: "client1"}, "Wallet/Account", "Sign", []byte{0x03})
+	require.Equal(t, core.PENDING, result)
+	assert.NotEqual(t, approval.ID, other.ID)
+	assert.Len(t, service.Pending(), 2)
+
+	_, err = service.Approve("unknown", &checker.Credentials{Client: "operator"})
+	assert.Equal(t, approvals.ErrUnknownApproval, err)
+	_, err = service.Approve(approval.ID, &checker.Credentials{Client: "client1"})
+	assert.Equal(t, approvals.ErrSelfApproval, err)
+	approved, err := service.Approve(approval.ID, &checker.Credentials{Client: "operator"})
+	require.NoError(t, err)
+	assert.True(t, approved.Approved())
+	_, err = service.Approve(approval.ID, &checker.Credentials{Client: "operator2"})
+	assert.Equal(t, approvals.ErrAlreadyApproved, err)
+	assert.Len(t, service.Pending(), 1)
+
+	// Request is released once, and only to the requesting client.
+	_, result = service.Request("", &checker.Credentials{Client: "client2"}, "Wallet/Account", "Sign", root)
+	require.Equal(t, core.PENDING, result)
+	released, result := service.Request(approval.ID, &checker.Credentials{Client: "client1"}, "Wallet/Account", "Sign", root)
+	require.Equal(t, core.APPROVED, result)
+	assert.Equal(t, []string{"operator"}, released.Approvers)
+	_, result = service.Request(approval.ID, &checker.Credentials{Client: "client1"}, "Wallet/Account", "Sign", root)
+	require.Equal(t, core.DENIED, result)
+}
+
+func TestMultipleApprovers(t *testing.T) {
+	service, err := approvals.New(context.Background(), &core.ApprovalsConfig{Required: 2})
+	require.NoError(t, err)
+	root := []byte{0x01, 0x02}
+
+	approval, _ := service.Request("", &checker.Credentials{Client: "client1"}, "Wallet/Account", "Sign", root)
+	assert.Equal(t, 2, approval.Required)
+
+	partial, err := service.Approve(approval.ID, &checker.Credentials{Client: "operator1"})
+	require.NoError(t, err)
+	assert.False(t, partial.Approved())
+	_, err = service.Approve(approval.ID, &checker.Credentials{Client: "operator1"})
+	assert.Equal(t, approvals.ErrDuplicateApprover, err)
+	_, result := service.Request("", &checker.Credentials{Client: "client1"}, "Wallet/Account", "Sign", root)
+	require.Equal(t, core.PENDING, result)
+
+	_, err = service.Approve(approval.ID, &checker.Credentials{Client: "operator2"})
+	require.NoError(t, err)
+	released, result := service.Request("", &checker.Credentials{Client: "client1"}, "Wallet/Account", "Sign", root)
+	require.Equal(t, core.APPROVED, result)
+	assert.Equal(t, []string{"operator1", "operator2"}, released.Approvers)
+}
+
+func TestApproverFingerprints(t *testing.T) {
+	service, err := approvals.New(context.Background(), &core.ApprovalsConfig{Required: 3})
+	require.NoError(t, err)
+	root := []byte{0x01, 0x02}
+
+	client := &checker.Credentials{Client: "client1", Fingerprint: "aa"}
+	approval, _ := service.Request("", client, "Wallet/Account", "Sign", root)
+
+	// The request belongs to the client's certificate as well as its name.
+	_, result := service.Request(approval.ID, &checker.Credentials{Client: "client1", Fingerprint: "bb"}, "Wallet/Account", "Sign", root)
+	require.Equal(t, core.DENIED, result)
+
+	// The requesting client cannot approve under another name.
+	_, err = service.Approve(approval.ID, &checker.Credentials{Client: "operator1", Fingerprint: "aa"})
+	assert.Equal(t, approvals.ErrSelfApproval, err)
+
+	// An approver cannot approve again under another name, or with another certificate.
+	_, err = service.Approve(approval.ID, &checker.Credentials{Client: "operator1", Fingerprint: "01"})
+	require.NoError(t, err)
+	_, err = service.Approve(approval.ID, &checker.Credentials{Client: "operator2", Fingerprint: "01"})
+	assert.Equal(t, approvals.ErrDuplicateApprover, err)
+	_, err = service.Approve(approval.ID, &checker.Credentials{Client: "operator1", Fingerprint: "02"})
+	assert.Equal(t, approvals.ErrDuplicateApprover, err)
+
+	pending := service.Approval(approval.ID)
+	require.NotNil(t, pending)
+	assert.True(t, pending.RequestedBy(client))
+	assert.True(t, pending.ApprovedBy(&checker.Credentials{Client: "operator3", Fingerprint: "01"}))
+	assert.False(t, pending.ApprovedBy(&checker.Credentials{Client: "operator2", Fingerprint: "02"}))
+
+	// Distinct approvers approve the request.
+	_, err = service.Approve(approval.ID, &checker.Credentials{Client: "operator2", Fingerprint: "02"})
+	require.NoError(t, err)
+	_, err = service.Approve(approval.ID, &checker.Credentials{Client: "operator3", Fingerprint: "03"})
+	require.NoError(t, err)
+	released, result := service.Request(approval.ID, client, "Wallet/Account", "Sign", root)
+	require.Equal(t, core.APPROVED, result)
+	assert.Equal(t, []string{"operator1", "operator2", "operator3"}, released.Approvers)
+}
+
+func TestReject(t *testing.T) {
+	service, err := approvals.New(context.Background(), &core.ApprovalsConfig{Required: 2})
+	require.NoError(t, err)
+	root := []byte{0x01, 0x02}
+
+	approval, _ := service.Request("", &checker.Credentials{Client: "client1"}, "Wallet/Account", "Sign", root)
+	_, err = service.Approve(approval.ID, &checker.Credentials{Client: "operator1"})
+	require.NoError(t, err)
+	_, err = service.Reject(approval.ID, &checker.Credentials{Client: "operator2"})
+	require.NoError(t, err)
+	_, err = service.Approve(approval.ID, &checker.Credentials{Client: "operator3"})
+	assert.Equal(t, approvals.ErrAlreadyRejected, err)
+	_, err = service.Reject(approval.ID, &checker.Credentials{Client: "operator3"})
+	assert.Equal(t, approvals.ErrAlreadyRejected, err)
+	assert.Len(t, service.Pending(), 0)
+
+	// Rejection is reported once, after which a new request is pending again.
+	_, result := service.Request("", &checker.Credentials{Client: "client1"}, "Wallet/Account", "Sign", root)
+	require.Equal(t, core.DENIED, result)
+	fresh, result := service.Request("", &checker.Credentials{Client: "client1"}, "Wallet/Account", "Sign", root)
+	require.Equal(t, core.PENDING, result)
+	assert.NotEqual(t, approval.ID, fresh.ID)
+}
+
+func TestWait(t *testing.T) {
+	service, err := approvals.New(context.Background(), nil)
+	require.NoError(t, err)
+	root := []byte{0x01, 0x02}
+
+	// Wait ends at the context deadline if there is no decision.
+	approval, _ := service.Request("", &checker.Credentials{Client: "client1"}, "Wallet/Account", "Sign", root)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	service.Wait(ctx, approval.ID)
+	assert.True(t, time.Since(started) >= 20*time.Millisecond)
+
+	// Wait ends on approval.
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_, err := service.Approve(approval.ID, &checker.Credentials{Client: "operator"})
+		assert.NoError(t, err)
+	}()
+	service.Wait(context.Background(), approval.ID)
+	_, result := service.Request(approval.ID, &checker.Credentials{Client: "client1"}, "Wallet/Account", "Sign", root)
+	assert.Equal(t, core.APPROVED, result)
+}
+
+func TestExpiry(t *testing.T) {
+	service, err := approvals.New(context.Background(), &core.ApprovalsConfig{Window: 50 * time.Millisecond})
+	require.NoError(t, err)
+	root := []byte{0x01, 0x02}
+
+	pending, _ := service.Request("", &checker.Credentials{Client: "client1"}, "Wallet/Account", "Sign", root)
+	approved, _ := service.Request("", &checker.Credentials{Client: "client1"}, "Wallet/Account", "Sign", []byte{0x03})
+	_, err = service.Approve(approved.ID, &checker.Credentials{Client: "operator"})
+	require.NoError(t, err)
+	require.NotNil(t, service.Approval(pending.ID))
+
+	time.Sleep(60 * time.Millisecond)
+
+	// Approval after the window is refused.
+	assert.Nil(t, service.Approval(pending.ID))
+	_, err = service.Approve(pending.ID, &checker.Credentials{Client: "operator"})
+	assert.Equal(t, approvals.ErrApprovalExpired, err)
+
+	// Approved requests are not released after the window.
+	_, result := service.Request(approved.ID, &checker.Credentials{Client: "client1"}, "Wallet/Account", "Sign", []byte{0x03})
+	assert.Equal(t, core.DENIED, result)
+}
@@ -0,0 +1,48 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os/exec"
+
+	"github.com/pkg/errors"
+	"github.com/wealdtech/walletd/core"
+)
+
+// fetchFromExecutable runs the helper executable, passing the request on stdin and reading the response from stdout.
+// The executable's stderr is discarded, as it could contain secrets; on failure only its exit status is reported.
+func (s *Service) fetchFromExecutable(ctx context.Context, req *request) (*response, error) {
+	reqData, err := json.Marshal(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode request")
+	}
+
+	cmd := exec.CommandContext(ctx, s.path, s.args...)
+	cmd.Stdin = bytes.NewReader(reqData)
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return nil, errors.Wrap(err, "helper failed")
+	}
+	defer core.ZeroBytes(stdout.Bytes())
+
+	resp := &response{}
+	if err := json.Unmarshal(stdout.Bytes(), resp); err != nil {
+		return nil, errors.Wrap(err, "invalid response from helper")
+	}
+	return resp, nil
+}
@@ -0,0 +1,215 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	e2wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+	"github.com/wealdtech/walletd/core"
+)
+
+const (
+	defaultTimeout  = 10 * time.Second
+	defaultCacheTTL = 5 * time.Minute
+)
+
+// Service is an autounlocker service that obtains passphrases from an external helper.
+// The helper is either an executable, which is run for each request, or a Unix socket.  In both cases the helper is
+// sent a JSON request and returns a JSON response; see request and response for details.
+type Service struct {
+	path     string
+	args     []string
+	socket   string
+	timeout  time.Duration
+	cacheTTL time.Duration
+	cache    map[string]*cacheEntry
+	cacheMx  sync.Mutex
+}
+
+// cacheEntry is a passphrase obtained from the helper.
+// An empty passphrase records that the helper has no passphrase for the account.
+type cacheEntry struct {
+	passphrase []byte
+	expiry     time.Time
+}
+
+// request is the request sent to the helper.
+type request struct {
+	Wallet  string `json:"wallet"`
+	Account string `json:"account"`
+	PubKey  string `json:"pubkey"`
+}
+
+// response is the response returned by the helper.
+// A helper that does not have a passphrase for the account should return an empty passphrase.
+type response struct {
+	Passphrase string `json:"passphrase"`
+	Error      string `json:"error,omitempty"`
+}
+
+// New creates a new autounlocker service that obtains passphrases from an external helper.
+func New(ctx context.Context, config *core.HelperConfig) (*Service, error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "autounlocker.helper.New")
+	defer span.Finish()
+
+	if config == nil {
+		return nil, errors.New("no helper config supplied")
+	}
+	if config.Path == "" && config.Socket == "" {
+		return nil, errors.New("helper requires either a path or a socket")
+	}
+	if config.Path != "" && config.Socket != "" {
+		return nil, errors.New("helper cannot have both a path and a socket")
+	}
+
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	cacheTTL := config.CacheTTL
+	if cacheTTL == 0 {
+		cacheTTL = defaultCacheTTL
+	}
+
+	return &Service{
+		path:     config.Path,
+		args:     config.Args,
+		socket:   config.Socket,
+		timeout:  timeout,
+		cacheTTL: cacheTTL,
+		cache:    make(map[string]*cacheEntry),
+	}, nil
+}
+
+// Unlock attempts to unlock an account.
+func (s *Service) Unlock(ctx context.Context, wallet e2wtypes.Wallet, account e2wtypes.Account) (bool, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "autounlocker.helper.Unlock")
+	defer span.Finish()
+
+	if wallet == nil {
+		return false, errors.New("no wallet supplied")
+	}
+	if account == nil {
+		return false, errors.New("no account supplied")
+	}
+	key := fmt.Sprintf("%s/%s", wallet.Name(), account.Name())
+	log := log.With().Str("account", key).Logger()
+
+	if passphrase, exists := s.cached(key); exists {
+		if len(passphrase) == 0 {
+			log.Debug().Msg("Helper recently had no passphrase for account")
+			return false, nil
+		}
+		if err := account.Unlock(passphrase); err == nil {
+			return true, nil
+		}
+		// Cached passphrase no longer works; ask the helper again.
+		s.uncache(key)
+	}
+
+	req := &request{
+		Wallet:  wallet.Name(),
+		Account: account.Name(),
+		PubKey:  fmt.Sprintf("%#x", account.PublicKey().Marshal()),
+	}
+	passphrase, err := s.fetchPassphrase(ctx, req)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to obtain passphrase from helper")
+		return false, err
+	}
+	if len(passphrase) == 0 {
+		log.Debug().Msg("Helper has no passphrase for account")
+		// Remember this, so that the helper is not asked again for every request for the account.
+		s.cachePassphrase(key, passphrase)
+		return false, nil
+	}
+
+	if err := account.Unlock(passphrase); err != nil {
+		log.Warn().Msg("Passphrase from helper failed to unlock account")
+		core.ZeroBytes(passphrase)
+		return false, nil
+	}
+	s.cachePassphrase(key, passphrase)
+	return true, nil
+}
+
+// fetchPassphrase fetches a passphrase from the helper.
+func (s *Service) fetchPassphrase(ctx context.Context, req *request) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	var resp *response
+	var err error
+	if s.path != "" {
+		resp, err = s.fetchFromExecutable(ctx, req)
+	} else {
+		resp, err = s.fetchFromSocket(ctx, req)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("helper returned error: %s", resp.Error)
+	}
+	return []byte(resp.Passphrase), nil
+}
+
+// cached returns the cached passphrase for an account, if present and not expired.
+func (s *Service) cached(key string) ([]byte, bool) {
+	s.cacheMx.Lock()
+	defer s.cacheMx.Unlock()
+	entry, exists := s.cache[key]
+	if !exists {
+		return nil, false
+	}
+	if time.Now().After(entry.expiry) {
+		core.ZeroBytes(entry.passphrase)
+		delete(s.cache, key)
+		return nil, false
+	}
+	return entry.passphrase, true
+}
+
+// cachePassphrase caches the passphrase for an account, removing any expired entries.
+func (s *Service) cachePassphrase(key string, passphrase []byte) {
+	s.cacheMx.Lock()
+	defer s.cacheMx.Unlock()
+	now := time.Now()
+	for k, entry := range s.cache {
+		if now.After(entry.expiry) {
+			core.ZeroBytes(entry.passphrase)
+			delete(s.cache, k)
+		}
+	}
+	s.cache[key] = &cacheEntry{
+		passphrase: passphrase,
+		expiry:     now.Add(s.cacheTTL),
+	}
+}
+
+// uncache removes the cached passphrase for an account.
+func (s *Service) uncache(key string) {
+	s.cacheMx.Lock()
+	defer s.cacheMx.Unlock()
+	if entry, exists := s.cache[key]; exists {
+		core.ZeroBytes(entry.passphrase)
+		delete(s.cache, key)
+	}
+}
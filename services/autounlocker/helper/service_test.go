@@ -0,0 +1,229 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helper_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+	e2wallet "github.com/wealdtech/go-eth2-wallet"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+	e2wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+	"github.com/wealdtech/walletd/core"
+	"github.com/wealdtech/walletd/services/autounlocker/helper"
+)
+
+func TestMain(m *testing.M) {
+	if err := e2types.InitBLS(); err != nil {
+		os.Exit(1)
+	}
+	os.Exit(m.Run())
+}
+
+// createTestWallet creates a wallet with two accounts.
+func createTestWallet(t *testing.T) (e2wtypes.Wallet, e2wtypes.Account, e2wtypes.Account) {
+	wallet, err := e2wallet.CreateWallet("Test wallet", e2wallet.WithStore(scratch.New()))
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	account1, err := wallet.CreateAccount("Account 1", []byte("secret1"))
+	require.NoError(t, err)
+	account2, err := wallet.CreateAccount("Account 2", []byte("secret2"))
+	require.NoError(t, err)
+	wallet.Lock()
+	return wallet, account1, account2
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *core.HelperConfig
+		err    string
+	}{
+		{
+			name: "Nil",
+			err:  "no helper config supplied",
+		},
+		{
+			name:   "Empty",
+			config: &core.HelperConfig{},
+			err:    "helper requires either a path or a socket",
+		},
+		{
+			name: "Both",
+			config: &core.HelperConfig{
+				Path:   "/bin/true",
+				Socket: "/tmp/helper.sock",
+			},
+			err: "helper cannot have both a path and a socket",
+		},
+		{
+			name: "Good",
+			config: &core.HelperConfig{
+				Path: "/bin/true",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := helper.New(context.Background(), test.config)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestExecutable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "helper")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// Helper returns a passphrase for account 1, and records each call.
+	calls := filepath.Join(dir, "calls")
+	script := filepath.Join(dir, "helper.sh")
+	require.NoError(t, ioutil.WriteFile(script, []byte(fmt.Sprintf(`#!/bin/sh
+echo call >> %s
+if grep -q '"account":"Account 1"'; then
+  echo '{"passphrase":"secret1"}'
+else
+  echo '{"passphrase":""}'
+fi
+`, calls)), 0700))
+
+	ctx := context.Background()
+	service, err := helper.New(ctx, &core.HelperConfig{
+		Path:     script,
+		CacheTTL: time.Minute,
+	})
+	require.NoError(t, err)
+	wallet, account1, account2 := createTestWallet(t)
+
+	unlocked, err := service.Unlock(ctx, wallet, account1)
+	require.NoError(t, err)
+	assert.True(t, unlocked)
+
+	// Second unlock should use the cached passphrase.
+	account1.Lock()
+	unlocked, err = service.Unlock(ctx, wallet, account1)
+	require.NoError(t, err)
+	assert.True(t, unlocked)
+	data, err := ioutil.ReadFile(calls)
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(data), "call"))
+
+	// Helper has no passphrase for account 2, which is also cached.
+	unlocked, err = service.Unlock(ctx, wallet, account2)
+	require.NoError(t, err)
+	assert.False(t, unlocked)
+	unlocked, err = service.Unlock(ctx, wallet, account2)
+	require.NoError(t, err)
+	assert.False(t, unlocked)
+	data, err = ioutil.ReadFile(calls)
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(data), "call"))
+}
+
+func TestExecutableFailure(t *testing.T) {
+	ctx := context.Background()
+	service, err := helper.New(ctx, &core.HelperConfig{
+		Path: "/bin/false",
+	})
+	require.NoError(t, err)
+	wallet, account1, _ := createTestWallet(t)
+
+	unlocked, err := service.Unlock(ctx, wallet, account1)
+	assert.EqualError(t, err, "helper failed: exit status 1")
+	assert.False(t, unlocked)
+}
+
+func TestExecutableFailureOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "helper")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// Helper writes a secret to stderr before failing.
+	script := filepath.Join(dir, "helper.sh")
+	require.NoError(t, ioutil.WriteFile(script, []byte(`#!/bin/sh
+echo 'secret1' >&2
+exit 2
+`), 0700))
+
+	ctx := context.Background()
+	service, err := helper.New(ctx, &core.HelperConfig{
+		Path: script,
+	})
+	require.NoError(t, err)
+	wallet, account1, _ := createTestWallet(t)
+
+	unlocked, err := service.Unlock(ctx, wallet, account1)
+	assert.EqualError(t, err, "helper failed: exit status 2")
+	assert.False(t, unlocked)
+}
+
+func TestSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "helper")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "helper.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			req := make(map[string]string)
+			data, _ := bufio.NewReader(conn).ReadBytes('\n')
+			if err := json.Unmarshal(data, &req); err == nil && req["wallet"] == "Test wallet" && req["account"] == "Account 2" {
+				_, _ = conn.Write([]byte(`{"passphrase":"secret2"}` + "\n"))
+			} else {
+				_, _ = conn.Write([]byte(`{"error":"unknown account"}` + "\n"))
+			}
+			conn.Close()
+		}
+	}()
+
+	ctx := context.Background()
+	service, err := helper.New(ctx, &core.HelperConfig{
+		Socket: socket,
+	})
+	require.NoError(t, err)
+	wallet, account1, account2 := createTestWallet(t)
+
+	unlocked, err := service.Unlock(ctx, wallet, account2)
+	require.NoError(t, err)
+	assert.True(t, unlocked)
+
+	unlocked, err = service.Unlock(ctx, wallet, account1)
+	assert.EqualError(t, err, "helper returned error: unknown account")
+	assert.False(t, unlocked)
+}
@@ -0,0 +1,60 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helper
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+
+	"github.com/pkg/errors"
+	"github.com/wealdtech/walletd/core"
+)
+
+// fetchFromSocket connects to the helper's Unix socket, writes the request as a single line and reads the response
+// as a single line.
+func (s *Service) fetchFromSocket(ctx context.Context, req *request) (*response, error) {
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "unix", s.socket)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect to helper")
+	}
+	defer conn.Close()
+	if deadline, exists := ctx.Deadline(); exists {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return nil, errors.Wrap(err, "failed to set deadline")
+		}
+	}
+
+	reqData, err := json.Marshal(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode request")
+	}
+	if _, err := conn.Write(append(reqData, '\n')); err != nil {
+		return nil, errors.Wrap(err, "failed to send request to helper")
+	}
+
+	respData, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil && len(respData) == 0 {
+		return nil, errors.Wrap(err, "failed to read response from helper")
+	}
+	defer core.ZeroBytes(respData)
+
+	resp := &response{}
+	if err := json.Unmarshal(respData, resp); err != nil {
+		return nil, errors.Wrap(err, "invalid response from helper")
+	}
+	return resp, nil
+}
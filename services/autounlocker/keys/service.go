@@ -14,35 +14,109 @@
 package keys
 
 import (
+	"bytes"
 	"context"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
 
 	"github.com/opentracing/opentracing-go"
 	e2wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
 	"github.com/wealdtech/walletd/core"
+	"github.com/wealdtech/walletd/util"
 )
 
 // Service is an autounlocker service that holds unlock passphrases.
 type Service struct {
 	passphrases [][]byte
+	accounts    []*accountPassphrase
+	// successes holds the passphrase that last unlocked each account, keyed by public key.
+	successes   map[[48]byte][]byte
+	successesMx sync.RWMutex
+}
+
+// accountPassphrase is a passphrase for specific accounts.
+type accountPassphrase struct {
+	wallet     *regexp.Regexp
+	account    *regexp.Regexp
+	pubKey     []byte
+	passphrase []byte
 }
 
 // New creates a new autounlocker service that holds unlock passphrases.
 func New(ctx context.Context, config *core.KeysConfig) (*Service, error) {
-	span, _ := opentracing.StartSpanFromContext(ctx, "autounlocker.keys.New")
+	span, ctx := opentracing.StartSpanFromContext(ctx, "autounlocker.keys.New")
 	defer span.Finish()
 
+	if config == nil {
+		return nil, errors.New("no keys config supplied")
+	}
+
 	passphrases := make([][]byte, len(config.Keys))
 	for i, key := range config.Keys {
-		passphrases[i] = []byte(key)
+		passphrase, err := core.ResolveSecret(ctx, fmt.Sprintf("key %d", i), key)
+		if err != nil {
+			return nil, err
+		}
+		passphrases[i] = passphrase
+		// Remove the passphrase from the configuration so that it is not resolved or exposed again.
+		config.Keys[i] = ""
+	}
+
+	accounts := make([]*accountPassphrase, len(config.Accounts))
+	for i, accountKey := range config.Accounts {
+		if accountKey == nil {
+			return nil, fmt.Errorf("account key %d is empty", i)
+		}
+		account := &accountPassphrase{}
+		switch {
+		case accountKey.Path != "" && accountKey.PubKey != "":
+			return nil, fmt.Errorf("account key %d has both path and public key", i)
+		case accountKey.Path != "":
+			walletName, accountName, err := util.WalletAndAccountNamesFromPath(accountKey.Path)
+			if err != nil || walletName == "" {
+				return nil, fmt.Errorf("account key %d has invalid path %s", i, accountKey.Path)
+			}
+			account.wallet, err = regexify(walletName)
+			if err != nil {
+				return nil, fmt.Errorf("account key %d has invalid wallet regex %s", i, walletName)
+			}
+			account.account, err = regexify(accountName)
+			if err != nil {
+				return nil, fmt.Errorf("account key %d has invalid account regex %s", i, accountName)
+			}
+		case accountKey.PubKey != "":
+			pubKey, err := hex.DecodeString(strings.TrimPrefix(accountKey.PubKey, "0x"))
+			if err != nil || len(pubKey) != 48 {
+				return nil, fmt.Errorf("account key %d has invalid public key %s", i, accountKey.PubKey)
+			}
+			account.pubKey = pubKey
+		default:
+			return nil, fmt.Errorf("account key %d has neither path nor public key", i)
+		}
+		passphrase, err := core.ResolveSecret(ctx, fmt.Sprintf("account key %d", i), accountKey.Passphrase)
+		if err != nil {
+			return nil, err
+		}
+		account.passphrase = passphrase
+		// Remove the passphrase from the configuration so that it is not resolved or exposed again.
+		accountKey.Passphrase = ""
+		accounts[i] = account
 	}
 
 	return &Service{
 		passphrases: passphrases,
+		accounts:    accounts,
+		successes:   make(map[[48]byte][]byte),
 	}, nil
 }
 
 // Unlock attempts to unlock an account.
+// Passphrases are tried in the following order: the passphrase that last unlocked the account, passphrases specific
+// to the account, and general passphrases.  Each passphrase is tried at most once.
 func (s *Service) Unlock(ctx context.Context, wallet e2wtypes.Wallet, account e2wtypes.Account) (bool, error) {
 	span, _ := opentracing.StartSpanFromContext(ctx, "autounlocker.keys.Unlock")
 	defer span.Finish()
@@ -51,10 +125,77 @@ func (s *Service) Unlock(ctx context.Context, wallet e2wtypes.Wallet, account e2
 		return false, errors.New("no account supplied")
 	}
 
+	var key [48]byte
+	pubKey := account.PublicKey().Marshal()
+	copy(key[:], pubKey)
+
+	tried := make([][]byte, 0)
+	try := func(passphrase []byte) bool {
+		for i := range tried {
+			if bytes.Equal(tried[i], passphrase) {
+				return false
+			}
+		}
+		tried = append(tried, passphrase)
+		if err := account.Unlock(passphrase); err != nil {
+			return false
+		}
+		s.successesMx.Lock()
+		s.successes[key] = passphrase
+		s.successesMx.Unlock()
+		return true
+	}
+
+	s.successesMx.RLock()
+	passphrase, exists := s.successes[key]
+	s.successesMx.RUnlock()
+	if exists {
+		if try(passphrase) {
+			return true, nil
+		}
+		// Passphrase no longer works.
+		s.successesMx.Lock()
+		delete(s.successes, key)
+		s.successesMx.Unlock()
+	}
+
+	for _, accountPassphrase := range s.accounts {
+		if accountPassphrase.matches(wallet, account, pubKey) && try(accountPassphrase.passphrase) {
+			return true, nil
+		}
+	}
+
 	for _, passphrase := range s.passphrases {
-		if err := account.Unlock(passphrase); err == nil {
+		if try(passphrase) {
 			return true, nil
 		}
 	}
 	return false, nil
 }
+
+// matches returns true if the account passphrase applies to the given account.
+func (a *accountPassphrase) matches(wallet e2wtypes.Wallet, account e2wtypes.Account, pubKey []byte) bool {
+	if a.pubKey != nil {
+		return bytes.Equal(a.pubKey, pubKey)
+	}
+	if wallet == nil {
+		return false
+	}
+	return a.wallet.MatchString(wallet.Name()) && a.account.MatchString(account.Name())
+}
+
+func regexify(name string) (*regexp.Regexp, error) {
+	// Empty equates to all.
+	if name == "" {
+		name = ".*"
+	}
+	// Anchor if required.
+	if !strings.HasPrefix(name, "^") {
+		name = fmt.Sprintf("^%s", name)
+	}
+	if !strings.HasSuffix(name, "$") {
+		name = fmt.Sprintf("%s$", name)
+	}
+
+	return regexp.Compile(name)
+}
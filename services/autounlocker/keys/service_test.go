@@ -15,11 +15,14 @@ package keys_test
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	e2types "github.com/wealdtech/go-eth2-types/v2"
+	e2wallet "github.com/wealdtech/go-eth2-wallet"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
 	e2wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
 	"github.com/wealdtech/walletd/core"
 	"github.com/wealdtech/walletd/services/autounlocker/keys"
@@ -77,3 +80,167 @@ func TestUnlock(t *testing.T) {
 		})
 	}
 }
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *core.KeysConfig
+		err    string
+	}{
+		{
+			name: "Nil",
+			err:  "no keys config supplied",
+		},
+		{
+			name:   "Empty",
+			config: &core.KeysConfig{},
+		},
+		{
+			name: "AccountNil",
+			config: &core.KeysConfig{
+				Accounts: []*core.AccountKey{nil},
+			},
+			err: "account key 0 is empty",
+		},
+		{
+			name: "AccountNoSelector",
+			config: &core.KeysConfig{
+				Accounts: []*core.AccountKey{{Passphrase: "secret"}},
+			},
+			err: "account key 0 has neither path nor public key",
+		},
+		{
+			name: "AccountBothSelectors",
+			config: &core.KeysConfig{
+				Accounts: []*core.AccountKey{{Path: "Wallet/Account", PubKey: "0x01", Passphrase: "secret"}},
+			},
+			err: "account key 0 has both path and public key",
+		},
+		{
+			name: "AccountInvalidPath",
+			config: &core.KeysConfig{
+				Accounts: []*core.AccountKey{{Path: "/Account", Passphrase: "secret"}},
+			},
+			err: "account key 0 has invalid path /Account",
+		},
+		{
+			name: "AccountInvalidRegex",
+			config: &core.KeysConfig{
+				Accounts: []*core.AccountKey{{Path: "Wallet/Account(", Passphrase: "secret"}},
+			},
+			err: "account key 0 has invalid account regex Account(",
+		},
+		{
+			name: "AccountInvalidPubKey",
+			config: &core.KeysConfig{
+				Accounts: []*core.AccountKey{{PubKey: "0x0102", Passphrase: "secret"}},
+			},
+			err: "account key 0 has invalid public key 0x0102",
+		},
+		{
+			name: "Good",
+			config: &core.KeysConfig{
+				Keys: []string{"secret"},
+				Accounts: []*core.AccountKey{
+					{Path: "Wallet/Account.*", Passphrase: "secret2"},
+					{PubKey: "0xa99a76ed7796f7be22d5b7e85deeb7c5677e88e511e0b337618f8c4eb61349b4bf2d153f649f7b53359fe8b94a38e44c", Passphrase: "secret3"},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := keys.New(context.Background(), test.config)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+				if test.config != nil {
+					// Passphrases should have been removed from the configuration.
+					for _, key := range test.config.Keys {
+						assert.Equal(t, "", key)
+					}
+					for _, account := range test.config.Accounts {
+						assert.Equal(t, "", account.Passphrase)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestUnlockAccounts(t *testing.T) {
+	require.NoError(t, e2types.InitBLS())
+	ctx := context.Background()
+
+	account1 := mock.NewAccount("Account 1", []byte("account secret"))
+	account2 := mock.NewAccount("Account 2", []byte("general secret 2"))
+	config := &core.KeysConfig{
+		Keys: []string{"general secret 1", "general secret 2", "general secret 3"},
+		Accounts: []*core.AccountKey{
+			{PubKey: fmt.Sprintf("%#x", account1.PublicKey().Marshal()), Passphrase: "account secret"},
+		},
+	}
+	service, err := keys.New(ctx, config)
+	require.NoError(t, err)
+
+	// Account-specific passphrase should be tried first.
+	unlocked, err := service.Unlock(ctx, nil, account1)
+	require.NoError(t, err)
+	assert.True(t, unlocked)
+	assert.Equal(t, 1, account1.UnlockAttempts())
+
+	// General passphrases should be tried in turn.
+	unlocked, err = service.Unlock(ctx, nil, account2)
+	require.NoError(t, err)
+	assert.True(t, unlocked)
+	assert.Equal(t, 2, account2.UnlockAttempts())
+
+	// Successful passphrase should be remembered.
+	account2.Lock()
+	unlocked, err = service.Unlock(ctx, nil, account2)
+	require.NoError(t, err)
+	assert.True(t, unlocked)
+	assert.Equal(t, 3, account2.UnlockAttempts())
+
+	// Unknown account should try each passphrase once.
+	account3 := mock.NewAccount("Account 3", []byte("unknown secret"))
+	unlocked, err = service.Unlock(ctx, nil, account3)
+	require.NoError(t, err)
+	assert.False(t, unlocked)
+	assert.Equal(t, 3, account3.UnlockAttempts())
+}
+
+func TestUnlockPaths(t *testing.T) {
+	require.NoError(t, e2types.InitBLS())
+	ctx := context.Background()
+
+	store := scratch.New()
+	wallet, err := e2wallet.CreateWallet("Test wallet", e2wallet.WithStore(store))
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	validator, err := wallet.CreateAccount("Validator 1", []byte("validator secret"))
+	require.NoError(t, err)
+	other, err := wallet.CreateAccount("Other 1", []byte("other secret"))
+	require.NoError(t, err)
+	wallet.Lock()
+
+	config := &core.KeysConfig{
+		Accounts: []*core.AccountKey{
+			{Path: "Test wallet/Validator.*", Passphrase: "validator secret"},
+			{Path: "Other wallet/Other.*", Passphrase: "other secret"},
+		},
+	}
+	service, err := keys.New(ctx, config)
+	require.NoError(t, err)
+
+	unlocked, err := service.Unlock(ctx, wallet, validator)
+	require.NoError(t, err)
+	assert.True(t, unlocked)
+
+	// Path does not match, so should not unlock.
+	unlocked, err = service.Unlock(ctx, wallet, other)
+	require.NoError(t, err)
+	assert.False(t, unlocked)
+}
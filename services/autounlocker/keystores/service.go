@@ -0,0 +1,78 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keystores
+
+import (
+	"context"
+	"errors"
+
+	"github.com/opentracing/opentracing-go"
+	e2wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+	"github.com/wealdtech/walletd/core"
+	keystoresstore "github.com/wealdtech/walletd/stores/keystores"
+)
+
+// Service is an autounlocker service that uses passphrases held alongside keystores.
+type Service struct{}
+
+// New creates a new autounlocker service that uses passphrases held alongside keystores.
+func New(ctx context.Context) (*Service, error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "autounlocker.keystores.New")
+	defer span.Finish()
+
+	return &Service{}, nil
+}
+
+// Unlock attempts to unlock an account.
+func (s *Service) Unlock(ctx context.Context, wallet e2wtypes.Wallet, account e2wtypes.Account) (bool, error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "autounlocker.keystores.Unlock")
+	defer span.Finish()
+
+	if wallet == nil {
+		return false, errors.New("no wallet supplied")
+	}
+	if account == nil {
+		return false, errors.New("no account supplied")
+	}
+
+	storeProvider, isProvider := wallet.(e2wtypes.StoreProvider)
+	if !isProvider {
+		return false, nil
+	}
+	// The store may be wrapped, for example if it is protected.
+	walletStore := storeProvider.Store()
+	for {
+		wrapper, isWrapper := walletStore.(interface{ Unwrap() e2wtypes.Store })
+		if !isWrapper {
+			break
+		}
+		walletStore = wrapper.Unwrap()
+	}
+	store, isKeystores := walletStore.(*keystoresstore.Store)
+	if !isKeystores {
+		return false, nil
+	}
+
+	passphrase, err := store.Passphrase(wallet.Name(), account.Name())
+	if err != nil {
+		// No passphrase available for this account.
+		return false, nil
+	}
+	defer core.ZeroBytes(passphrase)
+	if err := account.Unlock(passphrase); err != nil {
+		log.Warn().Str("wallet", wallet.Name()).Str("account", account.Name()).Msg("Keystore passphrase failed to unlock account")
+		return false, nil
+	}
+	return true, nil
+}
@@ -0,0 +1,64 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package multi
+
+import (
+	"context"
+
+	"github.com/opentracing/opentracing-go"
+	e2wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+	"github.com/wealdtech/walletd/services/autounlocker"
+)
+
+// Service is an autounlocker service that tries a number of autounlockers in turn.
+type Service struct {
+	autounlockers []autounlocker.Service
+}
+
+// New creates a new autounlocker service that tries a number of autounlockers in turn.
+func New(ctx context.Context, autounlockers ...autounlocker.Service) (*Service, error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "autounlocker.multi.New")
+	defer span.Finish()
+
+	res := &Service{
+		autounlockers: make([]autounlocker.Service, 0, len(autounlockers)),
+	}
+	for _, autounlocker := range autounlockers {
+		if autounlocker != nil {
+			res.autounlockers = append(res.autounlockers, autounlocker)
+		}
+	}
+	return res, nil
+}
+
+// Unlock attempts to unlock an account.
+func (s *Service) Unlock(ctx context.Context, wallet e2wtypes.Wallet, account e2wtypes.Account) (bool, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "autounlocker.multi.Unlock")
+	defer span.Finish()
+
+	var firstErr error
+	for _, autounlocker := range s.autounlockers {
+		unlocked, err := autounlocker.Unlock(ctx, wallet, account)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if unlocked {
+			return true, nil
+		}
+	}
+	return false, firstErr
+}
@@ -19,6 +19,10 @@ import "context"
 type Credentials struct {
 	// Client is the authenticated client.
 	Client string
+	// Fingerprint is the hex-encoded SHA-256 fingerprint of the client's certificate.
+	Fingerprint string
+	// SourceIP is the IP address from which the request was received.
+	SourceIP string
 }
 
 // Service is the interface for checking client access to accounts.
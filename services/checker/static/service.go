@@ -15,26 +15,67 @@ package static
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"net"
+	"os"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
 
 	"github.com/opentracing/opentracing-go"
 	"github.com/wealdtech/walletd/core"
 	"github.com/wealdtech/walletd/services/checker"
+	"github.com/wealdtech/walletd/services/ruler"
 	"github.com/wealdtech/walletd/util"
 )
 
 // StaticChecker checks against a static list.
 type StaticChecker struct {
-	access map[string][]*path
+	access       map[string][]*path
+	fingerprints map[string]string
+	accessMx     sync.RWMutex
 }
 
 type path struct {
-	wallet     *regexp.Regexp
-	account    *regexp.Regexp
+	name    string
+	wallet  *regexp.Regexp
+	account *regexp.Regexp
+	// walletOnly is true if the path does not specify an account, in which case it also applies to the wallet itself.
+	walletOnly bool
 	operations []string
+	// deny is true if the operations are denied rather than allowed.
+	deny bool
+	// notBefore and notAfter limit the times at which the path applies, if set.
+	notBefore time.Time
+	notAfter  time.Time
+	// networks limits the source addresses to which the path applies, if set.
+	networks []*net.IPNet
+}
+
+// allOperations are the operations allowed by the "All" operation.  Administrative operations, such as locking and
+// unlocking, clearing lockouts and approving requests, are not included and must be allowed by name.
+var allOperations = map[string]bool{
+	ruler.ActionSign:                  true,
+	ruler.ActionSignBeaconAttestation: true,
+	ruler.ActionSignBeaconProposal:    true,
+	ruler.ActionSignRANDAOReveal:      true,
+	ruler.ActionSignSelectionProof:    true,
+	ruler.ActionSignAggregateAndProof: true,
+	ruler.ActionSignVoluntaryExit:     true,
+	ruler.ActionSignDeposit:           true,
+	ruler.ActionAccessAccount:         true,
+}
+
+// request contains the conditions of a request against which paths are checked.
+type request struct {
+	time time.Time
+	ip   net.IP
 }
 
 // New creates a new static checker.
@@ -42,55 +83,218 @@ func New(ctx context.Context, config *core.Permissions) (*StaticChecker, error)
 	span, _ := opentracing.StartSpanFromContext(ctx, "checker.static.New")
 	defer span.Finish()
 
+	access, fingerprints, err := parsePermissions(config)
+	if err != nil {
+		return nil, err
+	}
+	return &StaticChecker{
+		access:       access,
+		fingerprints: fingerprints,
+	}, nil
+}
+
+// Reload replaces the permissions of the checker.
+// If the permissions are invalid then an error is returned and the existing permissions remain in place.
+func (c *StaticChecker) Reload(ctx context.Context, config *core.Permissions) error {
+	apply, err := c.PrepareReload(ctx, config)
+	if err != nil {
+		return err
+	}
+	apply()
+	return nil
+}
+
+// PrepareReload validates the permissions, returning a function that replaces the permissions of the checker with
+// them.  This allows the permissions to be validated by other services before any of them are replaced.
+// If the permissions are invalid then an error is returned.
+func (c *StaticChecker) PrepareReload(ctx context.Context, config *core.Permissions) (func(), error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "checker.static.PrepareReload")
+	defer span.Finish()
+
+	access, fingerprints, err := parsePermissions(config)
+	if err != nil {
+		log.Warn().Err(err).Msg("Invalid permissions; retaining existing permissions")
+		return nil, err
+	}
+
+	return func() {
+		c.apply(access, fingerprints)
+	}, nil
+}
+
+// apply replaces the permissions of the checker, logging the changes.
+func (c *StaticChecker) apply(access map[string][]*path, fingerprints map[string]string) {
+	c.accessMx.Lock()
+	oldAccess := c.access
+	oldFingerprints := c.fingerprints
+	c.access = access
+	c.fingerprints = fingerprints
+	c.accessMx.Unlock()
+
+	for client, paths := range access {
+		oldPaths, exists := oldAccess[client]
+		switch {
+		case !exists:
+			log.Info().Str("client", client).Msg("Client gained access")
+		case !pathsEqual(oldPaths, paths) || oldFingerprints[client] != fingerprints[client]:
+			log.Info().Str("client", client).Msg("Client access changed")
+		}
+	}
+	for client := range oldAccess {
+		if _, exists := access[client]; !exists {
+			log.Info().Str("client", client).Msg("Client lost access")
+		}
+	}
+	log.Info().Int("clients", len(access)).Msg("Permissions reloaded")
+}
+
+// parsePermissions parses the permissions configuration in to an access map and a map of required fingerprints.
+func parsePermissions(config *core.Permissions) (map[string][]*path, map[string]string, error) {
 	if config == nil {
-		return nil, errors.New("certificate info is required")
+		return nil, nil, errors.New("certificate info is required")
 	}
 	if config.Certs == nil {
-		return nil, errors.New("certificates are required")
+		return nil, nil, errors.New("certificates are required")
 	}
 	if len(config.Certs) == 0 {
-		return nil, errors.New("certificate info empty")
+		return nil, nil, errors.New("certificate info empty")
+	}
+
+	knownOperations := map[string]bool{
+		"All": true,
+	}
+	for _, action := range ruler.Actions() {
+		knownOperations[action] = true
 	}
 
 	access := make(map[string][]*path, len(config.Certs))
+	fingerprints := make(map[string]string)
 	for _, certificateInfo := range config.Certs {
 		if certificateInfo.Name == "" {
-			return nil, errors.New("certificate info requires a name")
+			return nil, nil, errors.New("certificate info requires a name")
 		}
 		if len(certificateInfo.Perms) == 0 {
-			return nil, errors.New("certificate info requires at least one permission")
+			return nil, nil, errors.New("certificate info requires at least one permission")
+		}
+		if certificateInfo.Fingerprint != "" {
+			fingerprint, err := normaliseFingerprint(certificateInfo.Fingerprint)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid fingerprint for %s", certificateInfo.Name)
+			}
+			fingerprints[certificateInfo.Name] = fingerprint
 		}
 		paths := make([]*path, len(certificateInfo.Perms))
 		for i, permissions := range certificateInfo.Perms {
 			walletName, accountName, err := util.WalletAndAccountNamesFromPath(permissions.Path)
 			if err != nil {
-				return nil, fmt.Errorf("invalid account path %s", permissions.Path)
+				return nil, nil, fmt.Errorf("invalid account path %s", permissions.Path)
 			}
 			if walletName == "" {
-				return nil, errors.New("wallet cannot be blank")
+				return nil, nil, errors.New("wallet cannot be blank")
 			}
 			walletRegex, err := regexify(walletName)
 			if err != nil {
-				return nil, fmt.Errorf("invalid wallet regex %s", walletName)
+				return nil, nil, fmt.Errorf("invalid wallet regex %s", walletName)
 			}
 			accountRegex, err := regexify(accountName)
 			if err != nil {
-				return nil, fmt.Errorf("invalid account regex %s", accountName)
+				return nil, nil, fmt.Errorf("invalid account regex %s", accountName)
+			}
+			for _, operation := range permissions.Operations {
+				if !knownOperations[operation] {
+					return nil, nil, fmt.Errorf("unknown operation %q for %s", operation, certificateInfo.Name)
+				}
 			}
 			paths[i] = &path{
+				name:       permissions.Path,
 				wallet:     walletRegex,
 				account:    accountRegex,
+				walletOnly: accountName == "",
 				operations: permissions.Operations,
+				deny:       permissions.Deny,
+			}
+			if permissions.NotBefore != nil {
+				paths[i].notBefore = *permissions.NotBefore
+			}
+			if permissions.NotAfter != nil {
+				paths[i].notAfter = *permissions.NotAfter
+			}
+			if !paths[i].notBefore.IsZero() && !paths[i].notAfter.IsZero() && !paths[i].notAfter.After(paths[i].notBefore) {
+				return nil, nil, fmt.Errorf("not after must be later than not before for %s", certificateInfo.Name)
+			}
+			for _, sourceIP := range permissions.SourceIPs {
+				network, err := parseNetwork(sourceIP)
+				if err != nil {
+					return nil, nil, fmt.Errorf("invalid source IP %s for %s", sourceIP, certificateInfo.Name)
+				}
+				paths[i].networks = append(paths[i].networks, network)
 			}
 		}
 		access[certificateInfo.Name] = paths
 	}
-	return &StaticChecker{
-		access: access,
-	}, nil
+	return access, fingerprints, nil
+}
+
+// parseNetwork parses a CIDR range, or a single IP address.
+func parseNetwork(input string) (*net.IPNet, error) {
+	if strings.Contains(input, "/") {
+		_, network, err := net.ParseCIDR(input)
+		return network, err
+	}
+	ip := net.ParseIP(input)
+	if ip == nil {
+		return nil, errors.New("invalid IP address")
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+// normaliseFingerprint normalises a hex-encoded SHA-256 fingerprint, which can contain colon separators.
+func normaliseFingerprint(input string) (string, error) {
+	fingerprint := strings.ToLower(strings.ReplaceAll(input, ":", ""))
+	data, err := hex.DecodeString(strings.TrimPrefix(fingerprint, "0x"))
+	if err != nil {
+		return "", err
+	}
+	if len(data) != sha256.Size {
+		return "", errors.New("incorrect length")
+	}
+	return hex.EncodeToString(data), nil
+}
+
+// pathsEqual returns true if the two sets of paths are the same.
+func pathsEqual(a []*path, b []*path) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].name != b[i].name ||
+			a[i].deny != b[i].deny ||
+			!a[i].notBefore.Equal(b[i].notBefore) ||
+			!a[i].notAfter.Equal(b[i].notAfter) ||
+			len(a[i].networks) != len(b[i].networks) ||
+			len(a[i].operations) != len(b[i].operations) {
+			return false
+		}
+		for j := range a[i].networks {
+			if a[i].networks[j].String() != b[i].networks[j].String() {
+				return false
+			}
+		}
+		for j := range a[i].operations {
+			if a[i].operations[j] != b[i].operations[j] {
+				return false
+			}
+		}
+	}
+	return true
 }
 
 // Check checks the client to see if the account is allowed.
+// If the account is a wallet with no account name then only permissions that do not specify an account are considered.
+// Permissions that deny an operation take precedence over those that allow it.
 func (c *StaticChecker) Check(ctx context.Context, credentials *checker.Credentials, account string, operation string) bool {
 	span, _ := opentracing.StartSpanFromContext(ctx, "checker.static.Check")
 	defer span.Finish()
@@ -114,29 +318,180 @@ func (c *StaticChecker) Check(ctx context.Context, credentials *checker.Credenti
 		log.Debug().Err(err).Msg("Missing wallet name")
 		return false
 	}
-	if accountName == "" {
-		log.Debug().Err(err).Msg("Missing account name")
-		return false
-	}
 
+	c.accessMx.RLock()
 	paths, exists := c.access[credentials.Client]
+	fingerprint := c.fingerprints[credentials.Client]
+	c.accessMx.RUnlock()
 	if !exists {
 		log.Debug().Msg("Unknown client")
 		return false
 	}
+	if fingerprint != "" && fingerprint != strings.ToLower(credentials.Fingerprint) {
+		log.Warn().Str("fingerprint", credentials.Fingerprint).Msg("Client certificate does not have the required fingerprint")
+		return false
+	}
 
+	allowed, denied := decide(paths, walletName, accountName, operation, &request{
+		time: time.Now(),
+		ip:   net.ParseIP(credentials.SourceIP),
+	})
+	if denied {
+		log.Debug().Str("operation", operation).Msg("Operation explicitly denied")
+		return false
+	}
+	return allowed
+}
+
+// decide returns if the operation is allowed, and if it is explicitly denied, by the paths.
+// If the request is nil then the time and source IP restrictions of the paths are ignored.
+func decide(paths []*path, walletName string, accountName string, operation string, req *request) (bool, bool) {
+	allowed := false
 	for _, path := range paths {
-		if path.wallet.Match([]byte(walletName)) && path.account.Match([]byte(accountName)) {
-			for i := range path.operations {
-				if path.operations[i] == "All" || path.operations[i] == operation {
-					return true
-				}
+		if accountName == "" && !path.walletOnly {
+			continue
+		}
+		if req != nil && !path.appliesTo(req) {
+			continue
+		}
+		if !path.wallet.Match([]byte(walletName)) || !path.account.Match([]byte(accountName)) {
+			continue
+		}
+		if path.coversOperation(operation) {
+			if path.deny {
+				return false, true
+			}
+			allowed = true
+		}
+	}
+	return allowed, false
+}
+
+// coversOperation returns true if the operations of the path include the operation.
+// "All" allows only the operations in allOperations, but denies every operation.
+func (p *path) coversOperation(operation string) bool {
+	for i := range p.operations {
+		if p.operations[i] == operation {
+			return true
+		}
+		if p.operations[i] == "All" && (p.deny || allOperations[operation]) {
+			return true
+		}
+	}
+	return false
+}
+
+// appliesTo returns true if the time and source IP of the request are within the restrictions of the path.
+// Paths that deny operations fail closed on source IP: they apply to requests whose source IP is unknown.
+func (p *path) appliesTo(req *request) bool {
+	if len(p.networks) > 0 {
+		if req.ip == nil {
+			if !p.deny {
+				return false
 			}
+		} else if !p.containsIP(req.ip) {
+			return false
+		}
+	}
+	if !p.notBefore.IsZero() && req.time.Before(p.notBefore) {
+		return false
+	}
+	if !p.notAfter.IsZero() && req.time.After(p.notAfter) {
+		return false
+	}
+	return true
+}
+
+// containsIP returns true if the IP is within the source networks of the path.
+func (p *path) containsIP(ip net.IP) bool {
+	for _, network := range p.networks {
+		if network.Contains(ip) {
+			return true
 		}
 	}
 	return false
 }
 
+// DumpPerms dumps the effective permissions for each client to stdout.
+// For each path named in a client's permissions the decision for each operation is shown, taking in to account
+// all of the client's permissions.  Time and source IP restrictions are assumed to be met.
+func (c *StaticChecker) DumpPerms() {
+	c.accessMx.RLock()
+	defer c.accessMx.RUnlock()
+
+	clients := make([]string, 0, len(c.access))
+	for client := range c.access {
+		clients = append(clients, client)
+	}
+	sort.Strings(clients)
+
+	actions := ruler.Actions()
+	for _, client := range clients {
+		paths := c.access[client]
+		fmt.Printf("Permissions for %q:\n", client)
+		if fingerprint, exists := c.fingerprints[client]; exists {
+			fmt.Printf("\t- certificate must have fingerprint %s\n", fingerprint)
+		}
+		for _, path := range paths {
+			verb := "can"
+			if path.deny {
+				verb = "cannot"
+			}
+			if len(path.operations) == 1 && path.operations[0] == "All" {
+				if path.deny {
+					fmt.Printf("\t- accounts matching the path %q cannot carry out any operations\n", path.name)
+				} else {
+					fmt.Printf("\t- accounts matching the path %q can carry out all signing and access operations\n", path.name)
+				}
+			} else {
+				fmt.Printf("\t- accounts matching the path %q %s carry out operations: %s\n", path.name, verb, strings.Join(path.operations, ", "))
+			}
+			if !path.notBefore.IsZero() {
+				fmt.Printf("\t\t- not before %s\n", path.notBefore.Format(time.RFC3339))
+			}
+			if !path.notAfter.IsZero() {
+				fmt.Printf("\t\t- not after %s\n", path.notAfter.Format(time.RFC3339))
+			}
+			if len(path.networks) > 0 {
+				networks := make([]string, len(path.networks))
+				for i := range path.networks {
+					networks[i] = path.networks[i].String()
+				}
+				fmt.Printf("\t\t- only from %s\n", strings.Join(networks, ", "))
+			}
+		}
+
+		fmt.Printf("Effective permissions for %q:\n", client)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "\tPath\t%s\t\n", strings.Join(actions, "\t"))
+		seen := make(map[string]bool)
+		for _, path := range paths {
+			if seen[path.name] {
+				continue
+			}
+			seen[path.name] = true
+			walletName, accountName, err := util.WalletAndAccountNamesFromPath(path.name)
+			if err != nil {
+				continue
+			}
+			decisions := make([]string, len(actions))
+			for i, action := range actions {
+				allowed, denied := decide(paths, walletName, accountName, action, nil)
+				switch {
+				case denied:
+					decisions[i] = "deny"
+				case allowed:
+					decisions[i] = "allow"
+				default:
+					decisions[i] = "-"
+				}
+			}
+			fmt.Fprintf(w, "\t%s\t%s\t\n", path.name, strings.Join(decisions, "\t"))
+		}
+		w.Flush()
+	}
+}
+
 func regexify(name string) (*regexp.Regexp, error) {
 	// Empty equates to all.
 	if name == "" {
@@ -16,6 +16,7 @@ package static_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -74,6 +75,30 @@ func TestNew(t *testing.T) {
 			perms: &core.Permissions{Certs: []*core.CertificateInfo{{Name: "test", Perms: []*core.CertificatePerms{{Path: "foo/**"}}}}},
 			err:   "invalid account regex **",
 		},
+		{
+			name:  "CertInfoUnknownOperation",
+			perms: &core.Permissions{Certs: []*core.CertificateInfo{{Name: "test", Perms: []*core.CertificatePerms{{Path: "foo/bar", Operations: []string{"Sign beacon attestations"}}}}}},
+			err:   `unknown operation "Sign beacon attestations" for test`,
+		},
+		{
+			name:  "CertInfoInvalidFingerprint",
+			perms: &core.Permissions{Certs: []*core.CertificateInfo{{Name: "test", Fingerprint: "0102", Perms: []*core.CertificatePerms{{Path: "foo/bar"}}}}},
+			err:   "invalid fingerprint for test",
+		},
+		{
+			name:  "CertInfoInvalidSourceIP",
+			perms: &core.Permissions{Certs: []*core.CertificateInfo{{Name: "test", Perms: []*core.CertificatePerms{{Path: "foo/bar", SourceIPs: []string{"10.0.0.0/33"}}}}}},
+			err:   "invalid source IP 10.0.0.0/33 for test",
+		},
+		{
+			name:  "CertInfoInvalidTimes",
+			perms: &core.Permissions{Certs: []*core.CertificateInfo{{Name: "test", Perms: []*core.CertificatePerms{{Path: "foo/bar", NotBefore: timePtr(time.Unix(2000, 0)), NotAfter: timePtr(time.Unix(1000, 0))}}}}},
+			err:   "not after must be later than not before for test",
+		},
+		{
+			name:  "Good",
+			perms: &core.Permissions{Certs: []*core.CertificateInfo{{Name: "test", Perms: []*core.CertificatePerms{{Path: "foo/bar", Operations: []string{"All"}}, {Path: "foo/baz", Operations: []string{"Sign"}, Deny: true}}}}},
+		},
 	}
 
 	for _, test := range tests {
@@ -101,6 +126,43 @@ func TestCheck(t *testing.T) {
 					},
 				},
 			},
+			{
+				Name: "client2",
+				Perms: []*core.CertificatePerms{
+					{
+						Path:       "Wallet1",
+						Operations: []string{"LockWallet", "UnlockWallet"},
+					},
+					{
+						Path:       "Wallet2/.*",
+						Operations: []string{"LockAccount", "UnlockAccount"},
+					},
+				},
+			},
+			{
+				Name:        "client4",
+				Fingerprint: "AB:CD:EF:01:23:45:67:89:AB:CD:EF:01:23:45:67:89:AB:CD:EF:01:23:45:67:89:AB:CD:EF:01:23:45:67:89",
+				Perms: []*core.CertificatePerms{
+					{
+						Path:       "Wallet4",
+						Operations: []string{"All"},
+					},
+				},
+			},
+			{
+				Name: "client3",
+				Perms: []*core.CertificatePerms{
+					{
+						Path:       "Wallet3",
+						Operations: []string{"All"},
+					},
+					{
+						Path:       "Wallet3/Secret.*",
+						Operations: []string{"Sign"},
+						Deny:       true,
+					},
+				},
+			},
 		},
 	})
 	require.Nil(t, err)
@@ -186,6 +248,104 @@ func TestCheck(t *testing.T) {
 			operation:   "Sign",
 			result:      true,
 		},
+		{
+			name:        "WalletValid",
+			credentials: &checker.Credentials{Client: "client2"},
+			account:     "Wallet1",
+			operation:   "UnlockWallet",
+			result:      true,
+		},
+		{
+			name:        "WalletBadOperation",
+			credentials: &checker.Credentials{Client: "client2"},
+			account:     "Wallet1",
+			operation:   "LockAccount",
+			result:      false,
+		},
+		{
+			name:        "WalletAccountPermsOnly",
+			credentials: &checker.Credentials{Client: "client2"},
+			account:     "Wallet2",
+			operation:   "LockWallet",
+			result:      false,
+		},
+		{
+			name:        "AccountValid",
+			credentials: &checker.Credentials{Client: "client2"},
+			account:     "Wallet2/valid",
+			operation:   "UnlockAccount",
+			result:      true,
+		},
+		{
+			name:        "AccountFromWalletPerms",
+			credentials: &checker.Credentials{Client: "client2"},
+			account:     "Wallet1/valid",
+			operation:   "LockWallet",
+			result:      true,
+		},
+		{
+			name:        "AllowedNotDenied",
+			credentials: &checker.Credentials{Client: "client3"},
+			account:     "Wallet3/Public",
+			operation:   "Sign",
+			result:      true,
+		},
+		{
+			name:        "Denied",
+			credentials: &checker.Credentials{Client: "client3"},
+			account:     "Wallet3/Secret1",
+			operation:   "Sign",
+			result:      false,
+		},
+		{
+			name:        "DeniedOtherOperation",
+			credentials: &checker.Credentials{Client: "client3"},
+			account:     "Wallet3/Secret1",
+			operation:   "Sign beacon attestation",
+			result:      true,
+		},
+		{
+			name:        "AllExcludesAdministration",
+			credentials: &checker.Credentials{Client: "client3"},
+			account:     "Wallet3/Public",
+			operation:   "UnlockAccount",
+			result:      false,
+		},
+		{
+			name:        "AllExcludesApproval",
+			credentials: &checker.Credentials{Client: "client3"},
+			account:     "Wallet3/Public",
+			operation:   "Approve",
+			result:      false,
+		},
+		{
+			name:        "AllExcludesWalletAdministration",
+			credentials: &checker.Credentials{Client: "client3"},
+			account:     "Wallet3",
+			operation:   "LockWallet",
+			result:      false,
+		},
+		{
+			name:        "FingerprintMissing",
+			credentials: &checker.Credentials{Client: "client4"},
+			account:     "Wallet4/valid",
+			operation:   "Sign",
+			result:      false,
+		},
+		{
+			name:        "FingerprintIncorrect",
+			credentials: &checker.Credentials{Client: "client4", Fingerprint: "0000000000000000000000000000000000000000000000000000000000000000"},
+			account:     "Wallet4/valid",
+			operation:   "Sign",
+			result:      false,
+		},
+		{
+			name:        "FingerprintCorrect",
+			credentials: &checker.Credentials{Client: "client4", Fingerprint: "abcdef0123456789abcdef0123456789abcdef0123456789abcdef0123456789"},
+			account:     "Wallet4/valid",
+			operation:   "Sign",
+			result:      true,
+		},
 	}
 
 	for _, test := range tests {
@@ -195,3 +355,220 @@ func TestCheck(t *testing.T) {
 		})
 	}
 }
+
+func TestReload(t *testing.T) {
+	ctx := context.Background()
+	service, err := static.New(ctx, &core.Permissions{
+		Certs: []*core.CertificateInfo{
+			{
+				Name:  "client1",
+				Perms: []*core.CertificatePerms{{Path: "Wallet1", Operations: []string{"Sign"}}},
+			},
+		},
+	})
+	require.Nil(t, err)
+	client1 := &checker.Credentials{Client: "client1"}
+	client2 := &checker.Credentials{Client: "client2"}
+	require.True(t, service.Check(ctx, client1, "Wallet1/valid", "Sign"))
+	require.False(t, service.Check(ctx, client2, "Wallet2/valid", "Sign"))
+
+	// Invalid permissions should leave the existing permissions in place.
+	require.EqualError(t, service.Reload(ctx, &core.Permissions{}), "certificates are required")
+	require.EqualError(t, service.Reload(ctx, &core.Permissions{
+		Certs: []*core.CertificateInfo{
+			{
+				Name:  "client2",
+				Perms: []*core.CertificatePerms{{Path: "**/foo"}},
+			},
+		},
+	}), "invalid wallet regex **")
+	assert.True(t, service.Check(ctx, client1, "Wallet1/valid", "Sign"))
+	assert.False(t, service.Check(ctx, client2, "Wallet2/valid", "Sign"))
+
+	// Prepared permissions should not replace the existing permissions until applied.
+	apply, err := service.PrepareReload(ctx, &core.Permissions{
+		Certs: []*core.CertificateInfo{
+			{
+				Name:  "client2",
+				Perms: []*core.CertificatePerms{{Path: "Wallet2", Operations: []string{"Sign"}}},
+			},
+		},
+	})
+	require.NoError(t, err)
+	assert.True(t, service.Check(ctx, client1, "Wallet1/valid", "Sign"))
+	assert.False(t, service.Check(ctx, client2, "Wallet2/valid", "Sign"))
+	apply()
+	assert.False(t, service.Check(ctx, client1, "Wallet1/valid", "Sign"))
+	assert.True(t, service.Check(ctx, client2, "Wallet2/valid", "Sign"))
+
+	// Valid permissions should replace the existing permissions.
+	require.NoError(t, service.Reload(ctx, &core.Permissions{
+		Certs: []*core.CertificateInfo{
+			{
+				Name:  "client2",
+				Perms: []*core.CertificatePerms{{Path: "Wallet2", Operations: []string{"Sign"}}},
+			},
+		},
+	}))
+	assert.False(t, service.Check(ctx, client1, "Wallet1/valid", "Sign"))
+	assert.True(t, service.Check(ctx, client2, "Wallet2/valid", "Sign"))
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func TestCheckRestrictions(t *testing.T) {
+	ctx := context.Background()
+	service, err := static.New(ctx, &core.Permissions{
+		Certs: []*core.CertificateInfo{
+			{
+				Name: "client1",
+				Perms: []*core.CertificatePerms{
+					{
+						Path:       "Wallet1/Current",
+						Operations: []string{"Sign"},
+						NotBefore:  timePtr(time.Now().Add(-time.Hour)),
+						NotAfter:   timePtr(time.Now().Add(time.Hour)),
+					},
+					{
+						Path:       "Wallet1/Future",
+						Operations: []string{"Sign"},
+						NotBefore:  timePtr(time.Now().Add(time.Hour)),
+					},
+					{
+						Path:       "Wallet1/Past",
+						Operations: []string{"Sign"},
+						NotAfter:   timePtr(time.Now().Add(-time.Hour)),
+					},
+					{
+						Path:       "Wallet1/Restricted",
+						Operations: []string{"Sign"},
+						SourceIPs:  []string{"10.0.0.0/8", "192.168.1.1", "2001:db8::/32"},
+					},
+					{
+						Path:       "Wallet1/Guarded",
+						Operations: []string{"Sign"},
+					},
+					{
+						Path:       "Wallet1/Guarded",
+						Operations: []string{"Sign"},
+						SourceIPs:  []string{"172.16.0.1"},
+						Deny:       true,
+					},
+					{
+						Path:       "Wallet1/Maintenance",
+						Operations: []string{"Sign"},
+					},
+					{
+						Path:       "Wallet1/Maintenance",
+						Operations: []string{"Sign"},
+						NotBefore:  timePtr(time.Now().Add(-time.Hour)),
+						NotAfter:   timePtr(time.Now().Add(time.Hour)),
+						Deny:       true,
+					},
+					{
+						Path:       "Wallet1/Maintained",
+						Operations: []string{"Sign"},
+					},
+					{
+						Path:       "Wallet1/Maintained",
+						Operations: []string{"Sign"},
+						NotBefore:  timePtr(time.Now().Add(-2 * time.Hour)),
+						NotAfter:   timePtr(time.Now().Add(-time.Hour)),
+						Deny:       true,
+					},
+				},
+			},
+		},
+	})
+	require.Nil(t, err)
+
+	tests := []struct {
+		name     string
+		sourceIP string
+		account  string
+		result   bool
+	}{
+		{
+			name:    "Current",
+			account: "Wallet1/Current",
+			result:  true,
+		},
+		{
+			name:    "Future",
+			account: "Wallet1/Future",
+			result:  false,
+		},
+		{
+			name:    "Past",
+			account: "Wallet1/Past",
+			result:  false,
+		},
+		{
+			name:    "RestrictedNoIP",
+			account: "Wallet1/Restricted",
+			result:  false,
+		},
+		{
+			name:     "RestrictedWrongIP",
+			sourceIP: "11.0.0.1",
+			account:  "Wallet1/Restricted",
+			result:   false,
+		},
+		{
+			name:     "RestrictedCIDR",
+			sourceIP: "10.1.2.3",
+			account:  "Wallet1/Restricted",
+			result:   true,
+		},
+		{
+			name:     "RestrictedSingleIP",
+			sourceIP: "192.168.1.1",
+			account:  "Wallet1/Restricted",
+			result:   true,
+		},
+		{
+			name:     "RestrictedIPv6",
+			sourceIP: "2001:db8::1",
+			account:  "Wallet1/Restricted",
+			result:   true,
+		},
+		{
+			name:     "DeniedFromIP",
+			sourceIP: "172.16.0.1",
+			account:  "Wallet1/Guarded",
+			result:   false,
+		},
+		{
+			name:     "NotDeniedFromOtherIP",
+			sourceIP: "172.16.0.2",
+			account:  "Wallet1/Guarded",
+			result:   true,
+		},
+		{
+			name:    "DeniedNoIP",
+			account: "Wallet1/Guarded",
+			result:  false,
+		},
+		{
+			name:     "DeniedInsideWindow",
+			sourceIP: "10.1.2.3",
+			account:  "Wallet1/Maintenance",
+			result:   false,
+		},
+		{
+			name:     "NotDeniedAfterWindow",
+			sourceIP: "10.1.2.3",
+			account:  "Wallet1/Maintained",
+			result:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			credentials := &checker.Credentials{Client: "client1", SourceIP: test.sourceIP}
+			assert.Equal(t, test.result, service.Check(ctx, credentials, test.account, "Sign"))
+		})
+	}
+}
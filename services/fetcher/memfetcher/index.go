@@ -0,0 +1,301 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memfetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wealdtech/go-bytesutil"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	e2wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+// maxMisses is the maximum number of missed keys that are remembered.
+const maxMisses = 1024
+
+// maintainIndex builds the public key index, and keeps it up to date.
+func (s *Service) maintainIndex(ctx context.Context) {
+	s.refreshIndex(ctx)
+	close(s.indexReady)
+
+	if s.indexRefreshInterval == 0 {
+		return
+	}
+	ticker := time.NewTicker(s.indexRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.refreshIndex(ctx)
+		}
+	}
+}
+
+// refreshIndexForMiss rebuilds the public key index after a public key was not found in it.
+// Only one such rebuild runs at a time, and rebuilds are no more frequent than the minimum interval; callers that
+// arrive while a rebuild is in progress wait for it rather than starting their own.  The rebuild itself is bound to
+// the lifetime of the service rather than that of the request, so a caller that gives up does not abandon the
+// rebuild for any others waiting on it.
+func (s *Service) refreshIndexForMiss(ctx context.Context) error {
+	s.indexRefreshMx.Lock()
+	done := s.indexRefreshing
+	if done == nil {
+		s.pubKeyPathsMx.RLock()
+		built := s.indexBuilt
+		s.pubKeyPathsMx.RUnlock()
+		if time.Since(built) < s.minIndexRefreshInterval || time.Since(s.indexRefreshStarted) < s.minIndexRefreshInterval {
+			// Recent enough.
+			s.indexRefreshMx.Unlock()
+			return nil
+		}
+		done = make(chan struct{})
+		s.indexRefreshing = done
+		s.indexRefreshStarted = time.Now()
+		go func() {
+			s.refreshIndex(s.ctx)
+			s.indexRefreshMx.Lock()
+			s.indexRefreshing = nil
+			s.indexRefreshMx.Unlock()
+			close(done)
+		}()
+	}
+	s.indexRefreshMx.Unlock()
+
+	// Prefer a completed rebuild, as select chooses randomly between ready cases.
+	select {
+	case <-done:
+		return nil
+	default:
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return errors.New("public key index refresh not complete")
+	}
+}
+
+// refreshIndex rebuilds the public key index.
+// If the context is done before the rebuild completes then the existing index is left in place.
+func (s *Service) refreshIndex(ctx context.Context) {
+	s.indexMx.Lock()
+	defer s.indexMx.Unlock()
+
+	started := time.Now()
+	pubKeyPaths := make(map[[48]byte]string)
+	keyLocations := make(map[[48]byte][]string)
+	walletLocations := make(map[string][]string)
+	encryptor := keystorev4.New()
+	for i, store := range s.stores {
+		storeName := storeDescription(i, store)
+		walletsCh := store.RetrieveWallets()
+		for walletBytes := range walletsCh {
+			if ctx.Err() != nil {
+				// Drain the channel so that the store is not left blocked.
+				for range walletsCh {
+				}
+				log.Debug().Msg("Context done; abandoning build of public key index")
+				return
+			}
+			wallet, err := walletFromBytes(walletBytes, store, encryptor)
+			if err != nil {
+				log.Warn().Err(err).Msg("Failed to decode wallet")
+				continue
+			}
+			walletLocations[wallet.Name()] = append(walletLocations[wallet.Name()], storeName)
+			for account := range wallet.Accounts() {
+				key := bytesutil.ToBytes48(account.PublicKey().Marshal())
+				path := fmt.Sprintf("%s/%s", wallet.Name(), account.Name())
+				pubKeyPaths[key] = path
+				keyLocations[key] = append(keyLocations[key], fmt.Sprintf("%s in %s", path, storeName))
+			}
+		}
+	}
+
+	// Find conflicts.
+	conflictedKeys := make(map[[48]byte]bool)
+	for key, locations := range keyLocations {
+		if len(locations) > 1 {
+			conflictedKeys[key] = true
+			log.Error().Str("pubkey", fmt.Sprintf("%#x", key)).Strs("locations", locations).Msg("Public key present in multiple locations; refusing to use it until resolved")
+		}
+	}
+	conflictedWallets := make(map[string]bool)
+	for name, locations := range walletLocations {
+		if len(locations) > 1 {
+			conflictedWallets[name] = true
+			log.Error().Str("wallet", name).Strs("locations", locations).Msg("Wallet name present in multiple locations; refusing to use it until resolved")
+		}
+	}
+
+	s.pubKeyPathsMx.Lock()
+	s.pubKeyPaths = pubKeyPaths
+	s.indexBuilt = started
+	s.pubKeyPathsMx.Unlock()
+
+	s.conflictsMx.Lock()
+	for name := range s.conflictedWallets {
+		if !conflictedWallets[name] {
+			log.Info().Str("wallet", name).Msg("Wallet name conflict resolved")
+		}
+	}
+	for key := range s.conflictedKeys {
+		if !conflictedKeys[key] {
+			log.Info().Str("pubkey", fmt.Sprintf("%#x", key)).Msg("Public key conflict resolved")
+		}
+	}
+	s.conflictedKeys = conflictedKeys
+	s.conflictedWallets = conflictedWallets
+	s.conflictsMx.Unlock()
+
+	// Conflicted wallets could have been cached from any of their stores, so drop them.
+	for name := range conflictedWallets {
+		s.uncacheWallet(name)
+	}
+
+	// Any previous misses could now be present, so forget them.
+	s.missesMx.Lock()
+	s.misses = make(map[[48]byte]time.Time)
+	s.missesMx.Unlock()
+
+	log.Debug().Int("accounts", len(pubKeyPaths)).Dur("duration", time.Since(started)).Msg("Built public key index")
+}
+
+// uncacheWallet removes a wallet and its accounts from the cache.
+func (s *Service) uncacheWallet(name string) {
+	s.walletsMx.Lock()
+	delete(s.wallets, name)
+	s.walletsMx.Unlock()
+
+	prefix := fmt.Sprintf("%s/", name)
+	s.accountsMx.Lock()
+	for path := range s.accounts {
+		if strings.HasPrefix(path, prefix) {
+			delete(s.accounts, path)
+		}
+	}
+	s.accountsMx.Unlock()
+}
+
+// keyConflicted returns true if the public key is present in multiple locations.
+func (s *Service) keyConflicted(key [48]byte) bool {
+	s.conflictsMx.RLock()
+	defer s.conflictsMx.RUnlock()
+	return s.conflictedKeys[key]
+}
+
+// walletConflicted returns true if the wallet name is present in multiple locations.
+func (s *Service) walletConflicted(name string) bool {
+	s.conflictsMx.RLock()
+	defer s.conflictsMx.RUnlock()
+	return s.conflictedWallets[name]
+}
+
+// storeDescription provides a human-readable description of a store.
+func storeDescription(index int, store e2wtypes.Store) string {
+	if locationProvider, ok := store.(e2wtypes.StoreLocationProvider); ok {
+		return fmt.Sprintf("%s store %q", store.Name(), locationProvider.Location())
+	}
+	return fmt.Sprintf("%s store %d", store.Name(), index)
+}
+
+// pathForKey returns the path of the account with the given public key, if known.
+func (s *Service) pathForKey(key [48]byte) (string, bool) {
+	s.pubKeyPathsMx.RLock()
+	path, exists := s.pubKeyPaths[key]
+	s.pubKeyPathsMx.RUnlock()
+	return path, exists
+}
+
+// AccountPaths returns the paths of the accounts in the public key index, keyed by public key.
+// Accounts with conflicting public keys are not included.
+func (s *Service) AccountPaths(ctx context.Context) (map[[48]byte]string, error) {
+	// Wait for the initial build of the index to complete.
+	if err := s.waitForIndex(ctx); err != nil {
+		return nil, err
+	}
+
+	s.pubKeyPathsMx.RLock()
+	defer s.pubKeyPathsMx.RUnlock()
+	res := make(map[[48]byte]string, len(s.pubKeyPaths))
+	for key, path := range s.pubKeyPaths {
+		if !s.keyConflicted(key) {
+			res[key] = path
+		}
+	}
+	return res, nil
+}
+
+// waitForIndex waits for the initial build of the index to complete, or for the context to be done.
+func (s *Service) waitForIndex(ctx context.Context) error {
+	// Prefer a ready index, as select chooses randomly between ready cases.
+	select {
+	case <-s.indexReady:
+		return nil
+	default:
+	}
+	select {
+	case <-s.indexReady:
+		return nil
+	case <-ctx.Done():
+		return errors.New("public key index not ready")
+	}
+}
+
+// recentlyMissed returns true if the given public key was recently not found.
+func (s *Service) recentlyMissed(key [48]byte) bool {
+	s.missesMx.Lock()
+	defer s.missesMx.Unlock()
+	missed, exists := s.misses[key]
+	if !exists {
+		return false
+	}
+	if time.Since(missed) > s.missTTL {
+		delete(s.misses, key)
+		return false
+	}
+	return true
+}
+
+// recordMiss records that the given public key was not found.
+func (s *Service) recordMiss(key [48]byte) {
+	s.missesMx.Lock()
+	defer s.missesMx.Unlock()
+	if _, exists := s.misses[key]; !exists && len(s.misses) >= maxMisses {
+		for k, missed := range s.misses {
+			if time.Since(missed) > s.missTTL {
+				delete(s.misses, k)
+			}
+		}
+		// If there are still too many then evict the oldest, so that unique unknown keys cannot grow the map.
+		for len(s.misses) >= maxMisses {
+			var oldestKey [48]byte
+			var oldest time.Time
+			for k, missed := range s.misses {
+				if oldest.IsZero() || missed.Before(oldest) {
+					oldestKey = k
+					oldest = missed
+				}
+			}
+			delete(s.misses, oldestKey)
+		}
+	}
+	s.misses[key] = time.Now()
+}
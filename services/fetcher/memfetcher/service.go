@@ -14,22 +14,31 @@
 package memfetcher
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/wealdtech/go-bytesutil"
 	e2w "github.com/wealdtech/go-eth2-wallet"
-	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
 	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
 	nd "github.com/wealdtech/go-eth2-wallet-nd/v2"
 	e2wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
 	"github.com/wealdtech/walletd/util"
 )
 
+const (
+	// defaultIndexRefreshInterval is the default interval between background rebuilds of the public key index.
+	defaultIndexRefreshInterval = 5 * time.Minute
+	// defaultMissTTL is the default time for which a public key that is not in the index is remembered as missing.
+	defaultMissTTL = time.Minute
+	// defaultMinIndexRefreshInterval is the default minimum interval between rebuilds of the public key index due to misses.
+	defaultMinIndexRefreshInterval = 10 * time.Second
+)
+
 // Service contains an in-memory cache of wallets and accounts.
 type Service struct {
 	stores        []e2wtypes.Store
@@ -39,29 +48,99 @@ type Service struct {
 	walletsMx     sync.RWMutex
 	accounts      map[string]e2wtypes.Account
 	accountsMx    sync.RWMutex
+
+	// Public key index information.
+	ctx                     context.Context
+	indexRefreshInterval    time.Duration
+	minIndexRefreshInterval time.Duration
+	indexReady              chan struct{}
+	indexBuilt              time.Time
+	indexMx                 sync.Mutex
+	indexRefreshing         chan struct{}
+	indexRefreshStarted     time.Time
+	indexRefreshMx          sync.Mutex
+	missTTL                 time.Duration
+	misses                  map[[48]byte]time.Time
+	missesMx                sync.Mutex
+
+	// Conflict information.
+	conflictedKeys    map[[48]byte]bool
+	conflictedWallets map[string]bool
+	conflictsMx       sync.RWMutex
+}
+
+// Option is an option for the in-memory fetcher.
+type Option func(*Service)
+
+// WithIndexRefreshInterval sets the interval between background rebuilds of the public key index.
+// An interval of 0 disables background rebuilds.
+func WithIndexRefreshInterval(interval time.Duration) Option {
+	return func(s *Service) {
+		s.indexRefreshInterval = interval
+	}
+}
+
+// WithMinIndexRefreshInterval sets the minimum interval between rebuilds of the public key index when a public key
+// is not found.
+func WithMinIndexRefreshInterval(interval time.Duration) Option {
+	return func(s *Service) {
+		s.minIndexRefreshInterval = interval
+	}
+}
+
+// WithMissTTL sets the time for which a public key that is not found is remembered as missing.
+func WithMissTTL(ttl time.Duration) Option {
+	return func(s *Service) {
+		s.missTTL = ttl
+	}
 }
 
 // New creates a new in-memory fetcher.
-func New(stores []e2wtypes.Store) (*Service, error) {
+// The public key index is built in the background, and refreshed periodically until the context is cancelled.
+func New(ctx context.Context, stores []e2wtypes.Store, opts ...Option) (*Service, error) {
 	if len(stores) == 0 {
 		return nil, errors.New("no stores provided")
 	}
 
-	return &Service{
-		stores:      stores,
-		pubKeyPaths: make(map[[48]byte]string),
-		wallets:     make(map[string]e2wtypes.Wallet),
-		accounts:    make(map[string]e2wtypes.Account),
-	}, nil
+	s := &Service{
+		stores:                  stores,
+		pubKeyPaths:             make(map[[48]byte]string),
+		wallets:                 make(map[string]e2wtypes.Wallet),
+		accounts:                make(map[string]e2wtypes.Account),
+		ctx:                     ctx,
+		indexRefreshInterval:    defaultIndexRefreshInterval,
+		minIndexRefreshInterval: defaultMinIndexRefreshInterval,
+		indexReady:              make(chan struct{}),
+		missTTL:                 defaultMissTTL,
+		misses:                  make(map[[48]byte]time.Time),
+		conflictedKeys:          make(map[[48]byte]bool),
+		conflictedWallets:       make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	go s.maintainIndex(ctx)
+
+	return s, nil
 }
 
 // FetchWallet fetches the wallet.
-func (s *Service) FetchWallet(path string) (e2wtypes.Wallet, error) {
+func (s *Service) FetchWallet(ctx context.Context, path string) (e2wtypes.Wallet, error) {
 	walletName, _, err := util.WalletAndAccountNamesFromPath(path)
 	if err != nil {
 		return nil, err
 	}
 
+	// Wait for the initial build of the index to complete, to ensure that conflicts are known.
+	if err := s.waitForIndex(ctx); err != nil {
+		return nil, err
+	}
+	if s.walletConflicted(walletName) {
+		log.Warn().Str("wallet", walletName).Msg("Refusing to fetch wallet with conflicting definitions")
+		return nil, errors.New("wallet has conflicting definitions")
+	}
+
 	// Return wallet from cache if present.
 	s.walletsMx.RLock()
 	wallet, exists := s.wallets[walletName]
@@ -87,9 +166,9 @@ func (s *Service) FetchWallet(path string) (e2wtypes.Wallet, error) {
 }
 
 // FetchAccount fetches the account given its name.
-func (s *Service) FetchAccount(path string) (e2wtypes.Wallet, e2wtypes.Account, error) {
+func (s *Service) FetchAccount(ctx context.Context, path string) (e2wtypes.Wallet, e2wtypes.Account, error) {
 	// Fetch account and store in cache if present.
-	wallet, err := s.FetchWallet(path)
+	wallet, err := s.FetchWallet(ctx, path)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -99,6 +178,10 @@ func (s *Service) FetchAccount(path string) (e2wtypes.Wallet, e2wtypes.Account,
 	account, exists := s.accounts[path]
 	s.accountsMx.RUnlock()
 	if exists {
+		if s.keyConflicted(bytesutil.ToBytes48(account.PublicKey().Marshal())) {
+			log.Warn().Str("path", path).Msg("Refusing to fetch account with conflicting public key")
+			return nil, nil, errors.New("account has conflicting public key")
+		}
 		log.Debug().Str("path", path).Msg("Account found in cache; returning")
 		return wallet, account, nil
 	}
@@ -112,6 +195,10 @@ func (s *Service) FetchAccount(path string) (e2wtypes.Wallet, e2wtypes.Account,
 	if err != nil {
 		return nil, nil, err
 	}
+	if s.keyConflicted(bytesutil.ToBytes48(account.PublicKey().Marshal())) {
+		log.Warn().Str("path", path).Msg("Refusing to fetch account with conflicting public key")
+		return nil, nil, errors.New("account has conflicting public key")
+	}
 	s.accountsMx.Lock()
 	s.accounts[path] = account
 	s.accountsMx.Unlock()
@@ -123,39 +210,35 @@ func (s *Service) FetchAccount(path string) (e2wtypes.Wallet, e2wtypes.Account,
 }
 
 // FetchAccountByKey fetches the account given its public key.
-func (s *Service) FetchAccountByKey(pubKey []byte) (e2wtypes.Wallet, e2wtypes.Account, error) {
-	// See if we already know this key.
-	s.pubKeyPathsMx.RLock()
-	account, exists := s.pubKeyPaths[bytesutil.ToBytes48(pubKey)]
-	s.pubKeyPathsMx.RUnlock()
-	if exists {
-		return s.FetchAccount(account)
+func (s *Service) FetchAccountByKey(ctx context.Context, pubKey []byte) (e2wtypes.Wallet, e2wtypes.Account, error) {
+	// Wait for the initial build of the index to complete.
+	if err := s.waitForIndex(ctx); err != nil {
+		return nil, nil, err
 	}
 
-	// We don't.  Trawl wallets to find the result.
-	encryptor := keystorev4.New()
-	for _, store := range s.stores {
-		for walletBytes := range store.RetrieveWallets() {
-			wallet, err := walletFromBytes(walletBytes, store, encryptor)
-			if err != nil {
-				log.Warn().Err(err).Msg("Failed to decode wallet")
-				continue
-			}
-			for account := range wallet.Accounts() {
-				if bytes.Equal(account.PublicKey().Marshal(), pubKey) {
-					// Found it.
-					path := fmt.Sprintf("%s/%s", wallet.Name(), account.Name())
-					s.accountsMx.Lock()
-					s.accounts[path] = account
-					s.accountsMx.Unlock()
-					s.pubKeyPathsMx.Lock()
-					s.pubKeyPaths[bytesutil.ToBytes48(pubKey)] = path
-					s.pubKeyPathsMx.Unlock()
-					return wallet, account, nil
-				}
-			}
-		}
+	key := bytesutil.ToBytes48(pubKey)
+	if s.keyConflicted(key) {
+		log.Warn().Str("pubkey", fmt.Sprintf("%#x", pubKey)).Msg("Refusing to fetch account with conflicting public key")
+		return nil, nil, errors.New("account has conflicting public key")
+	}
+	if path, exists := s.pathForKey(key); exists {
+		return s.FetchAccount(ctx, path)
 	}
+
+	// Avoid repeated rebuilds of the index for keys that we have recently failed to find.
+	if s.recentlyMissed(key) {
+		return nil, nil, errors.New("account not found")
+	}
+
+	// The key could belong to an account created since the index was last built, so rebuild it.
+	if err := s.refreshIndexForMiss(ctx); err != nil {
+		return nil, nil, err
+	}
+	if path, exists := s.pathForKey(key); exists {
+		return s.FetchAccount(ctx, path)
+	}
+
+	s.recordMiss(key)
 	return nil, nil, errors.New("account not found")
 }
 
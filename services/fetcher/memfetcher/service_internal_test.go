@@ -14,10 +14,15 @@
 package memfetcher
 
 import (
+	"context"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
 	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
@@ -92,3 +97,120 @@ func TestWalletFromBytes(t *testing.T) {
 		})
 	}
 }
+
+func TestRecordMissBounded(t *testing.T) {
+	s := &Service{
+		missTTL: time.Hour,
+		misses:  make(map[[48]byte]time.Time),
+	}
+
+	first := [48]byte{0x01}
+	s.recordMiss(first)
+	for i := 0; i < 2*maxMisses; i++ {
+		var key [48]byte
+		key[1] = byte(i)
+		key[2] = byte(i >> 8)
+		s.recordMiss(key)
+	}
+	require.Len(t, s.misses, maxMisses)
+	// The oldest miss has been evicted.
+	require.False(t, s.recentlyMissed(first))
+}
+
+func TestIndexNotReady(t *testing.T) {
+	// The index is never built.
+	s := &Service{
+		indexReady: make(chan struct{}),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, _, err := s.FetchAccountByKey(ctx, []byte{0x01})
+	require.EqualError(t, err, "public key index not ready")
+	_, err = s.FetchWallet(ctx, "Test wallet")
+	require.EqualError(t, err, "public key index not ready")
+	_, err = s.AccountPaths(ctx)
+	require.EqualError(t, err, "public key index not ready")
+}
+
+// countingStore is a store that counts, and optionally blocks, retrievals of its wallets.
+type countingStore struct {
+	e2wtypes.Store
+	retrievals int32
+	block      chan struct{}
+}
+
+func (s *countingStore) RetrieveWallets() <-chan []byte {
+	atomic.AddInt32(&s.retrievals, 1)
+	if s.block != nil {
+		<-s.block
+	}
+	return s.Store.RetrieveWallets()
+}
+
+func TestRefreshIndexForMiss(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := &countingStore{Store: scratch.New()}
+	s, err := New(ctx, []e2wtypes.Store{store}, WithIndexRefreshInterval(0), WithMinIndexRefreshInterval(time.Hour), WithMissTTL(0))
+	require.NoError(t, err)
+	require.NoError(t, s.waitForIndex(ctx))
+	require.Equal(t, int32(1), atomic.LoadInt32(&store.retrievals))
+
+	// The index has just been built, so misses do not rebuild it.
+	for i := 0; i < 10; i++ {
+		_, _, err := s.FetchAccountByKey(ctx, []byte{byte(i)})
+		require.EqualError(t, err, "account not found")
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&store.retrievals))
+}
+
+func TestRefreshIndexForMissConcurrent(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := &countingStore{Store: scratch.New()}
+	s, err := New(ctx, []e2wtypes.Store{store}, WithIndexRefreshInterval(0), WithMinIndexRefreshInterval(time.Hour), WithMissTTL(0))
+	require.NoError(t, err)
+	require.NoError(t, s.waitForIndex(ctx))
+
+	// Age the index, and block the next rebuild so that all misses arrive while it is in progress.
+	s.pubKeyPathsMx.Lock()
+	s.indexBuilt = time.Now().Add(-2 * time.Hour)
+	s.pubKeyPathsMx.Unlock()
+	store.block = make(chan struct{})
+
+	misses := 20
+	var wg sync.WaitGroup
+	for i := 0; i < misses; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, _, err := s.FetchAccountByKey(ctx, []byte{byte(i)})
+			assert.EqualError(t, err, "account not found")
+		}(i)
+	}
+	// Give the misses time to arrive before allowing the rebuild to complete.
+	time.Sleep(50 * time.Millisecond)
+	close(store.block)
+	wg.Wait()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&store.retrievals))
+}
+
+func TestRefreshIndexForMissContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := &countingStore{Store: scratch.New()}
+	s, err := New(ctx, []e2wtypes.Store{store}, WithIndexRefreshInterval(0), WithMinIndexRefreshInterval(0), WithMissTTL(0))
+	require.NoError(t, err)
+	require.NoError(t, s.waitForIndex(ctx))
+
+	// The rebuild does not complete, but the caller returns when its context is done.
+	store.block = make(chan struct{})
+	defer close(store.block)
+	reqCtx, reqCancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer reqCancel()
+	started := time.Now()
+	_, _, err = s.FetchAccountByKey(reqCtx, []byte{0x01})
+	require.EqualError(t, err, "public key index refresh not complete")
+	assert.Less(t, int64(time.Since(started)), int64(time.Second))
+}
@@ -14,9 +14,11 @@
 package memfetcher_test
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -58,7 +60,7 @@ func TestNew(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			_, err := memfetcher.New(test.stores)
+			_, err := memfetcher.New(context.Background(), test.stores)
 			if test.err == "" {
 				require.Nil(t, err)
 			} else {
@@ -72,7 +74,7 @@ func TestNew(t *testing.T) {
 func TestFetchWallet(t *testing.T) {
 	stores, err := createTestStores()
 	require.Nil(t, err)
-	fetcher, err := memfetcher.New(stores)
+	fetcher, err := memfetcher.New(context.Background(), stores)
 	require.Nil(t, err)
 
 	tests := []struct {
@@ -101,7 +103,7 @@ func TestFetchWallet(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			_, err := fetcher.FetchWallet(test.path)
+			_, err := fetcher.FetchWallet(context.Background(), test.path)
 			if test.err == "" {
 				require.Nil(t, err)
 			} else {
@@ -109,7 +111,7 @@ func TestFetchWallet(t *testing.T) {
 				require.EqualError(t, err, test.err)
 			}
 			// Fetch again to check cache path.
-			_, err = fetcher.FetchWallet(test.path)
+			_, err = fetcher.FetchWallet(context.Background(), test.path)
 			if test.err == "" {
 				require.Nil(t, err)
 			} else {
@@ -123,7 +125,7 @@ func TestFetchWallet(t *testing.T) {
 func TestFetchAccount(t *testing.T) {
 	stores, err := createTestStores()
 	require.Nil(t, err)
-	fetcher, err := memfetcher.New(stores)
+	fetcher, err := memfetcher.New(context.Background(), stores)
 	require.Nil(t, err)
 
 	tests := []struct {
@@ -157,7 +159,7 @@ func TestFetchAccount(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			_, _, err := fetcher.FetchAccount(test.path)
+			_, _, err := fetcher.FetchAccount(context.Background(), test.path)
 			if test.err == "" {
 				require.Nil(t, err)
 			} else {
@@ -165,7 +167,7 @@ func TestFetchAccount(t *testing.T) {
 				require.EqualError(t, err, test.err)
 			}
 			// Fetch again to test cache path.
-			_, _, err = fetcher.FetchAccount(test.path)
+			_, _, err = fetcher.FetchAccount(context.Background(), test.path)
 			if test.err == "" {
 				require.Nil(t, err)
 			} else {
@@ -179,7 +181,7 @@ func TestFetchAccount(t *testing.T) {
 func TestFetchAccountByKey(t *testing.T) {
 	stores, err := createTestStores()
 	require.Nil(t, err)
-	fetcher, err := memfetcher.New(stores)
+	fetcher, err := memfetcher.New(context.Background(), stores)
 	require.Nil(t, err)
 
 	wallet, err := e2wallet.OpenWallet("Test wallet", e2wallet.WithStore(stores[0]))
@@ -223,7 +225,7 @@ func TestFetchAccountByKey(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			_, _, err := fetcher.FetchAccountByKey(test.key)
+			_, _, err := fetcher.FetchAccountByKey(context.Background(), test.key)
 			if test.err == "" {
 				require.Nil(t, err)
 			} else {
@@ -231,7 +233,7 @@ func TestFetchAccountByKey(t *testing.T) {
 				require.EqualError(t, err, test.err)
 			}
 			// Fetch again to test cache path.
-			_, _, err = fetcher.FetchAccountByKey(test.key)
+			_, _, err = fetcher.FetchAccountByKey(context.Background(), test.key)
 			if test.err == "" {
 				require.Nil(t, err)
 			} else {
@@ -242,17 +244,103 @@ func TestFetchAccountByKey(t *testing.T) {
 	}
 }
 
+func TestFetchAccountByKeyIndex(t *testing.T) {
+	stores, err := createTestStores()
+	require.Nil(t, err)
+
+	wallet, err := e2wallet.OpenWallet("Test wallet", e2wallet.WithStore(stores[0]))
+	require.Nil(t, err)
+	require.Nil(t, wallet.Unlock(nil))
+
+	// Long TTL; new accounts are not picked up until the index is refreshed.
+	fetcher, err := memfetcher.New(context.Background(), stores, memfetcher.WithIndexRefreshInterval(0), memfetcher.WithMissTTL(time.Hour))
+	require.Nil(t, err)
+	_, _, err = fetcher.FetchAccountByKey(context.Background(), []byte{0x01})
+	require.EqualError(t, err, "account not found")
+	account, err := wallet.CreateAccount("Late account", []byte{})
+	require.Nil(t, err)
+	_, _, err = fetcher.FetchAccountByKey(context.Background(), account.PublicKey().Marshal())
+	require.EqualError(t, err, "account not found")
+
+	// No TTL or minimum refresh interval; new accounts are picked up on demand.
+	fetcher, err = memfetcher.New(context.Background(), stores, memfetcher.WithIndexRefreshInterval(0), memfetcher.WithMinIndexRefreshInterval(0), memfetcher.WithMissTTL(0))
+	require.Nil(t, err)
+	account, err = wallet.CreateAccount("Later account", []byte{})
+	require.Nil(t, err)
+	_, fetchedAccount, err := fetcher.FetchAccountByKey(context.Background(), account.PublicKey().Marshal())
+	require.Nil(t, err)
+	assert.Equal(t, "Later account", fetchedAccount.Name())
+}
+
+func TestConflicts(t *testing.T) {
+	stores, err := createTestStores()
+	require.Nil(t, err)
+	duplicateStores, err := createTestStores()
+	require.Nil(t, err)
+
+	// Same wallet names in multiple stores.
+	fetcher, err := memfetcher.New(context.Background(), append(stores, duplicateStores...), memfetcher.WithIndexRefreshInterval(0))
+	require.Nil(t, err)
+	_, err = fetcher.FetchWallet(context.Background(), "Test wallet")
+	require.EqualError(t, err, "wallet has conflicting definitions")
+
+	// Same public key in multiple wallets.
+	store := scratch.New()
+	key, err := e2types.GenerateBLSPrivateKey()
+	require.Nil(t, err)
+	for _, walletName := range []string{"Wallet 1", "Wallet 2"} {
+		walletID := uuid.New()
+		err := store.StoreWallet(walletID, walletName, []byte(fmt.Sprintf(`{"uuid":"%s","version":1,"name":"%s","type":"non-deterministic"}`, walletID.String(), walletName)))
+		require.Nil(t, err)
+		wallet, err := e2wallet.OpenWallet(walletName, e2wallet.WithStore(store))
+		require.Nil(t, err)
+		require.Nil(t, wallet.Unlock(nil))
+		_, err = wallet.(e2wtypes.WalletAccountImporter).ImportAccount("Account", key.Marshal(), []byte{})
+		require.Nil(t, err)
+	}
+	fetcher, err = memfetcher.New(context.Background(), []e2wtypes.Store{store}, memfetcher.WithIndexRefreshInterval(0))
+	require.Nil(t, err)
+	_, err = fetcher.FetchWallet(context.Background(), "Wallet 1")
+	require.Nil(t, err)
+	_, _, err = fetcher.FetchAccount(context.Background(), "Wallet 1/Account")
+	require.EqualError(t, err, "account has conflicting public key")
+	_, _, err = fetcher.FetchAccountByKey(context.Background(), key.PublicKey().Marshal())
+	require.EqualError(t, err, "account has conflicting public key")
+	paths, err := fetcher.AccountPaths(context.Background())
+	require.Nil(t, err)
+	require.Len(t, paths, 0)
+}
+
+func TestAccountPaths(t *testing.T) {
+	stores, err := createTestStores()
+	require.Nil(t, err)
+	fetcher, err := memfetcher.New(context.Background(), stores, memfetcher.WithIndexRefreshInterval(0))
+	require.Nil(t, err)
+
+	wallet, err := e2wallet.OpenWallet("Test wallet", e2wallet.WithStore(stores[0]))
+	require.Nil(t, err)
+	account, err := wallet.AccountByName("Test account")
+	require.Nil(t, err)
+
+	paths, err := fetcher.AccountPaths(context.Background())
+	require.Nil(t, err)
+	require.Len(t, paths, 2)
+	var key [48]byte
+	copy(key[:], account.PublicKey().Marshal())
+	require.Equal(t, "Test wallet/Test account", paths[key])
+}
+
 func TestWalletLocking(t *testing.T) {
 	stores, err := createTestStores()
 	require.Nil(t, err)
-	fetcher, err := memfetcher.New(stores)
+	fetcher, err := memfetcher.New(context.Background(), stores)
 	require.Nil(t, err)
 
 	// Kick off 16 goroutines each retrieving the wallet 1024 times.
 	for i := 0; i < 16; i++ {
 		go func() {
 			for i := 0; i < 1024; i++ {
-				wallet, err := fetcher.FetchWallet("Test wallet")
+				wallet, err := fetcher.FetchWallet(context.Background(), "Test wallet")
 				assert.Nil(t, err)
 				assert.NotNil(t, wallet)
 			}
@@ -263,14 +351,14 @@ func TestWalletLocking(t *testing.T) {
 func TestAccountLocking(t *testing.T) {
 	stores, err := createTestStores()
 	require.Nil(t, err)
-	fetcher, err := memfetcher.New(stores)
+	fetcher, err := memfetcher.New(context.Background(), stores)
 	require.Nil(t, err)
 
 	// Kick off 16 goroutines each retrieving the account 1024 times.
 	for i := 0; i < 16; i++ {
 		go func() {
 			for i := 0; i < 1024; i++ {
-				wallet, account, err := fetcher.FetchAccount("Test wallet/Test account")
+				wallet, account, err := fetcher.FetchAccount(context.Background(), "Test wallet/Test account")
 				assert.Nil(t, err)
 				assert.NotNil(t, wallet)
 				assert.NotNil(t, account)
@@ -13,11 +13,16 @@
 
 package fetcher
 
-import types "github.com/wealdtech/go-eth2-wallet-types/v2"
+import (
+	"context"
+
+	types "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
 
 // Service is the interface for a wallet and account fetching service.
 type Service interface {
-	FetchWallet(path string) (types.Wallet, error)
-	FetchAccount(path string) (types.Wallet, types.Account, error)
-	FetchAccountByKey(pubKey []byte) (types.Wallet, types.Account, error)
+	FetchWallet(ctx context.Context, path string) (types.Wallet, error)
+	FetchAccount(ctx context.Context, path string) (types.Wallet, types.Account, error)
+	FetchAccountByKey(ctx context.Context, pubKey []byte) (types.Wallet, types.Account, error)
+	AccountPaths(ctx context.Context) (map[[48]byte]string, error)
 }
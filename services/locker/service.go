@@ -14,12 +14,12 @@
 package locker
 
 import (
+	"context"
 	"sync"
 )
 
 // Service provides the features and functions for the wallet daemon.
 type Service struct {
-	//locks        map[[48]byte]*sync.Mutex
 	locks        *sync.Map
 	newLockMutex *sync.Mutex
 }
@@ -27,7 +27,6 @@ type Service struct {
 // New creates a new locker service.
 func New() (*Service, error) {
 	return &Service{
-		//locks:        make(map[[48]byte]*sync.Mutex),
 		locks:        &sync.Map{},
 		newLockMutex: &sync.Mutex{},
 	}, nil
@@ -35,17 +34,23 @@ func New() (*Service, error) {
 
 // Lock acquires a lock for a given public key.
 func (s *Service) Lock(key [48]byte) {
-	lock, exists := s.locks.Load(key)
-	if !exists {
-		s.newLockMutex.Lock()
-		lock, exists = s.locks.Load(key)
-		if !exists {
-			lock = &sync.Mutex{}
-			s.locks.Store(key, lock)
-		}
-		s.newLockMutex.Unlock()
+	s.lock(key) <- struct{}{}
+}
+
+// LockContext acquires a lock for a given public key, returning an error if the context is done before the lock is
+// acquired.
+func (s *Service) LockContext(ctx context.Context, key [48]byte) error {
+	lock := s.lock(key)
+	// Check the context first, so that a request that is already done never acquires a free lock.
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	select {
+	case lock <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
-	lock.(*sync.Mutex).Lock()
 }
 
 // Unlock frees a lock for a given public key.
@@ -54,5 +59,25 @@ func (s *Service) Unlock(key [48]byte) {
 	if !exists {
 		panic("Attempt to unlock an unknown lock")
 	}
-	lock.(*sync.Mutex).Unlock()
+	select {
+	case <-lock.(chan struct{}):
+	default:
+		panic("Attempt to unlock an unlocked lock")
+	}
+}
+
+// lock returns the lock for a given public key, creating it if necessary.
+// A lock is a channel with a buffer of one, which is held while the buffer is full.
+func (s *Service) lock(key [48]byte) chan struct{} {
+	lock, exists := s.locks.Load(key)
+	if !exists {
+		s.newLockMutex.Lock()
+		lock, exists = s.locks.Load(key)
+		if !exists {
+			lock = make(chan struct{}, 1)
+			s.locks.Store(key, lock)
+		}
+		s.newLockMutex.Unlock()
+	}
+	return lock.(chan struct{})
 }
@@ -14,8 +14,10 @@
 package locker_test
 
 import (
+	"context"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -55,3 +57,59 @@ func TestBadUnlock(t *testing.T) {
 
 	assert.Panics(t, func() { locker.Unlock(testKey) })
 }
+
+func TestUnlockUnlocked(t *testing.T) {
+	locker, err := locker.New()
+	require.Nil(t, err)
+
+	testKey := [48]byte{}
+	locker.Lock(testKey)
+	locker.Unlock(testKey)
+
+	assert.Panics(t, func() { locker.Unlock(testKey) })
+}
+
+func TestLockContext(t *testing.T) {
+	locker, err := locker.New()
+	require.Nil(t, err)
+
+	testKey := [48]byte{}
+	otherKey := [48]byte{0x01}
+
+	// Free lock.
+	require.NoError(t, locker.LockContext(context.Background(), testKey))
+
+	// Held lock; times out.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, locker.LockContext(ctx, testKey))
+
+	// Other keys are unaffected.
+	require.NoError(t, locker.LockContext(context.Background(), otherKey))
+	locker.Unlock(otherKey)
+
+	// Held lock; cancelled.
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	assert.Equal(t, context.Canceled, locker.LockContext(ctx, testKey))
+
+	// Held lock; released while waiting.
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		locker.Unlock(testKey)
+	}()
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, locker.LockContext(ctx, testKey))
+	locker.Unlock(testKey)
+
+	// Free lock, but the context is already done.
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, locker.LockContext(ctx, testKey))
+	// The lock was not obtained.
+	assert.Panics(t, func() { locker.Unlock(testKey) })
+}
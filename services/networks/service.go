@@ -0,0 +1,237 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networks
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/opentracing/opentracing-go"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+	"github.com/wealdtech/walletd/core"
+	"github.com/wealdtech/walletd/services/checker"
+)
+
+// defaultSlotsPerEpoch is the number of slots in an epoch for networks that do not configure it.
+const defaultSlotsPerEpoch = 32
+
+// Service checks signing domains against the configured networks.
+// If no networks are configured then domains are not checked.
+type Service struct {
+	networks       []*network
+	clientNetworks map[string][]*network
+	mutex          sync.RWMutex
+}
+
+// network contains the fork data roots for a network.
+type network struct {
+	name                  string
+	genesisValidatorsRoot []byte
+	slotsPerEpoch         uint64
+	// forkDataRoots are the fork data roots for each fork of the network, truncated to the length used in domains.
+	forkDataRoots [][]byte
+	// depositForkDataRoot is the fork data root used for deposits, which have no genesis validators root.
+	depositForkDataRoot []byte
+}
+
+// New creates a new network service.
+func New(ctx context.Context, config []*core.NetworkConfig, perms *core.Permissions) (*Service, error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "networks.New")
+	defer span.Finish()
+
+	networks := make([]*network, len(config))
+	names := make(map[string]bool, len(config))
+	for i, networkConfig := range config {
+		if networkConfig.Name == "" {
+			return nil, fmt.Errorf("network %d has no name", i)
+		}
+		if names[networkConfig.Name] {
+			return nil, fmt.Errorf("duplicate network %s", networkConfig.Name)
+		}
+		names[networkConfig.Name] = true
+
+		genesisValidatorsRoot, err := decodeHex(networkConfig.GenesisValidatorsRoot)
+		if err != nil || len(genesisValidatorsRoot) != 32 {
+			return nil, fmt.Errorf("invalid genesis validators root for network %s", networkConfig.Name)
+		}
+		if len(networkConfig.ForkVersions) == 0 {
+			return nil, fmt.Errorf("no fork versions for network %s", networkConfig.Name)
+		}
+		network := &network{
+			name:                  networkConfig.Name,
+			genesisValidatorsRoot: genesisValidatorsRoot,
+			slotsPerEpoch:         networkConfig.SlotsPerEpoch,
+			forkDataRoots:         make([][]byte, len(networkConfig.ForkVersions)),
+		}
+		if network.slotsPerEpoch == 0 {
+			network.slotsPerEpoch = defaultSlotsPerEpoch
+		}
+		for j, forkVersionStr := range networkConfig.ForkVersions {
+			forkVersion, err := decodeHex(forkVersionStr)
+			if err != nil || len(forkVersion) != 4 {
+				return nil, fmt.Errorf("invalid fork version %q for network %s", forkVersionStr, networkConfig.Name)
+			}
+			network.forkDataRoots[j] = e2types.Domain(e2types.DomainType{}, forkVersion, genesisValidatorsRoot)[4:]
+			if j == 0 {
+				network.depositForkDataRoot = e2types.Domain(e2types.DomainType{}, forkVersion, e2types.ZeroGenesisValidatorsRoot)[4:]
+			}
+		}
+		networks[i] = network
+	}
+
+	s := &Service{
+		networks: networks,
+	}
+	clientNetworks, err := s.parsePermissions(perms)
+	if err != nil {
+		return nil, err
+	}
+	s.clientNetworks = clientNetworks
+
+	return s, nil
+}
+
+// Reload replaces the per-client networks from the permissions.
+// If the permissions are invalid then an error is returned and the existing networks remain in place.
+func (s *Service) Reload(ctx context.Context, perms *core.Permissions) error {
+	apply, err := s.PrepareReload(ctx, perms)
+	if err != nil {
+		return err
+	}
+	apply()
+	return nil
+}
+
+// PrepareReload validates the per-client networks in the permissions, returning a function that replaces the
+// per-client networks with them.
+// If the permissions are invalid then an error is returned.
+func (s *Service) PrepareReload(ctx context.Context, perms *core.Permissions) (func(), error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "networks.PrepareReload")
+	defer span.Finish()
+
+	clientNetworks, err := s.parsePermissions(perms)
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		s.mutex.Lock()
+		s.clientNetworks = clientNetworks
+		s.mutex.Unlock()
+	}, nil
+}
+
+// parsePermissions obtains the networks for each client from the permissions.
+func (s *Service) parsePermissions(perms *core.Permissions) (map[string][]*network, error) {
+	clientNetworks := make(map[string][]*network)
+	if perms == nil {
+		return clientNetworks, nil
+	}
+	for _, certificateInfo := range perms.Certs {
+		if len(certificateInfo.Networks) == 0 {
+			continue
+		}
+		networks := make([]*network, 0, len(certificateInfo.Networks))
+		for _, name := range certificateInfo.Networks {
+			network := s.network(name)
+			if network == nil {
+				return nil, fmt.Errorf("unknown network %s for %s", name, certificateInfo.Name)
+			}
+			networks = append(networks, network)
+		}
+		clientNetworks[certificateInfo.Name] = networks
+	}
+	return clientNetworks, nil
+}
+
+// network returns the network with the given name, or nil if there is no such network.
+func (s *Service) network(name string) *network {
+	for _, network := range s.networks {
+		if network.name == name {
+			return network
+		}
+	}
+	return nil
+}
+
+// SlotsPerEpoch returns the number of slots in an epoch for the network with the given genesis validators root.
+// If there is no such network then the default is returned.
+func (s *Service) SlotsPerEpoch(genesisValidatorsRoot []byte) uint64 {
+	for _, network := range s.networks {
+		if bytes.Equal(network.genesisValidatorsRoot, genesisValidatorsRoot) {
+			return network.slotsPerEpoch
+		}
+	}
+	return defaultSlotsPerEpoch
+}
+
+// CheckDomain checks that the domain is of the expected type, and is for a network permitted to the client.
+func (s *Service) CheckDomain(ctx context.Context, credentials *checker.Credentials, domainType e2types.DomainType, domain []byte) error {
+	span, _ := opentracing.StartSpanFromContext(ctx, "networks.CheckDomain")
+	defer span.Finish()
+
+	if len(domain) != 32 {
+		return errors.New("domain must be 32 bytes")
+	}
+	if !bytes.Equal(domain[:4], domainType[:]) {
+		return fmt.Errorf("domain type %#x does not match expected %#x", domain[:4], domainType[:])
+	}
+
+	if len(s.networks) == 0 {
+		// No networks configured, so no fork data root to check.
+		return nil
+	}
+
+	networks := s.networks
+	if credentials != nil {
+		s.mutex.RLock()
+		if clientNetworks, exists := s.clientNetworks[credentials.Client]; exists {
+			networks = clientNetworks
+		}
+		s.mutex.RUnlock()
+	}
+
+	forkDataRoot := domain[4:]
+	for _, network := range networks {
+		if domainType == e2types.DomainDeposit {
+			if bytes.Equal(forkDataRoot, network.depositForkDataRoot) {
+				return nil
+			}
+			continue
+		}
+		for _, root := range network.forkDataRoots {
+			if bytes.Equal(forkDataRoot, root) {
+				return nil
+			}
+		}
+	}
+	return errors.New("domain is not for a permitted network")
+}
+
+// DomainType returns the domain type of the domain, or an empty domain type if the domain is too short.
+func DomainType(domain []byte) e2types.DomainType {
+	var domainType e2types.DomainType
+	if len(domain) >= len(domainType) {
+		copy(domainType[:], domain)
+	}
+	return domainType
+}
+
+func decodeHex(input string) ([]byte, error) {
+	return hex.DecodeString(strings.TrimPrefix(input, "0x"))
+}
@@ -0,0 +1,288 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networks_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+	"github.com/wealdtech/walletd/core"
+	"github.com/wealdtech/walletd/services/checker"
+	"github.com/wealdtech/walletd/services/networks"
+)
+
+func TestMain(m *testing.M) {
+	if err := e2types.InitBLS(); err != nil {
+		os.Exit(1)
+	}
+	os.Exit(m.Run())
+}
+
+var (
+	mainnetRoot = "0x0101010101010101010101010101010101010101010101010101010101010101"
+	testnetRoot = "0x0202020202020202020202020202020202020202020202020202020202020202"
+)
+
+func testNetworks() []*core.NetworkConfig {
+	return []*core.NetworkConfig{
+		{
+			Name:                  "mainnet",
+			GenesisValidatorsRoot: mainnetRoot,
+			ForkVersions:          []string{"0x00000000", "0x01000000"},
+		},
+		{
+			Name:                  "testnet",
+			GenesisValidatorsRoot: testnetRoot,
+			ForkVersions:          []string{"0x00000001"},
+			SlotsPerEpoch:         8,
+		},
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name     string
+		networks []*core.NetworkConfig
+		perms    *core.Permissions
+		err      string
+	}{
+		{
+			name: "Empty",
+		},
+		{
+			name:     "NoName",
+			networks: []*core.NetworkConfig{{GenesisValidatorsRoot: mainnetRoot, ForkVersions: []string{"0x00000000"}}},
+			err:      "network 0 has no name",
+		},
+		{
+			name: "Duplicate",
+			networks: []*core.NetworkConfig{
+				{Name: "mainnet", GenesisValidatorsRoot: mainnetRoot, ForkVersions: []string{"0x00000000"}},
+				{Name: "mainnet", GenesisValidatorsRoot: mainnetRoot, ForkVersions: []string{"0x00000000"}},
+			},
+			err: "duplicate network mainnet",
+		},
+		{
+			name:     "BadRoot",
+			networks: []*core.NetworkConfig{{Name: "mainnet", GenesisValidatorsRoot: "0x0102", ForkVersions: []string{"0x00000000"}}},
+			err:      "invalid genesis validators root for network mainnet",
+		},
+		{
+			name:     "NoForkVersions",
+			networks: []*core.NetworkConfig{{Name: "mainnet", GenesisValidatorsRoot: mainnetRoot}},
+			err:      "no fork versions for network mainnet",
+		},
+		{
+			name:     "BadForkVersion",
+			networks: []*core.NetworkConfig{{Name: "mainnet", GenesisValidatorsRoot: mainnetRoot, ForkVersions: []string{"0x0000"}}},
+			err:      `invalid fork version "0x0000" for network mainnet`,
+		},
+		{
+			name:     "UnknownClientNetwork",
+			networks: testNetworks(),
+			perms:    &core.Permissions{Certs: []*core.CertificateInfo{{Name: "client1", Networks: []string{"other"}}}},
+			err:      "unknown network other for client1",
+		},
+		{
+			name:     "Good",
+			networks: testNetworks(),
+			perms:    &core.Permissions{Certs: []*core.CertificateInfo{{Name: "client1", Networks: []string{"testnet"}}}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := networks.New(context.Background(), test.networks, test.perms)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCheckDomain(t *testing.T) {
+	ctx := context.Background()
+	service, err := networks.New(ctx, testNetworks(), &core.Permissions{
+		Certs: []*core.CertificateInfo{
+			{Name: "client1"},
+			{Name: "client2", Networks: []string{"testnet"}},
+		},
+	})
+	require.NoError(t, err)
+
+	mainnetRootBytes := bytes.Repeat([]byte{0x01}, 32)
+	testnetRootBytes := bytes.Repeat([]byte{0x02}, 32)
+
+	tests := []struct {
+		name        string
+		credentials *checker.Credentials
+		domainType  e2types.DomainType
+		domain      []byte
+		err         string
+	}{
+		{
+			name:        "Short",
+			credentials: &checker.Credentials{Client: "client1"},
+			domainType:  e2types.DomainBeaconAttester,
+			domain:      []byte{0x01, 0x00, 0x00, 0x00},
+			err:         "domain must be 32 bytes",
+		},
+		{
+			name:        "WrongType",
+			credentials: &checker.Credentials{Client: "client1"},
+			domainType:  e2types.DomainBeaconAttester,
+			domain:      e2types.Domain(e2types.DomainBeaconProposer, []byte{0x00, 0x00, 0x00, 0x00}, mainnetRootBytes),
+			err:         "domain type 0x00000000 does not match expected 0x01000000",
+		},
+		{
+			name:        "GenesisFork",
+			credentials: &checker.Credentials{Client: "client1"},
+			domainType:  e2types.DomainBeaconAttester,
+			domain:      e2types.Domain(e2types.DomainBeaconAttester, []byte{0x00, 0x00, 0x00, 0x00}, mainnetRootBytes),
+		},
+		{
+			name:        "LaterFork",
+			credentials: &checker.Credentials{Client: "client1"},
+			domainType:  e2types.DomainBeaconProposer,
+			domain:      e2types.Domain(e2types.DomainBeaconProposer, []byte{0x01, 0x00, 0x00, 0x00}, mainnetRootBytes),
+		},
+		{
+			name:        "UnknownFork",
+			credentials: &checker.Credentials{Client: "client1"},
+			domainType:  e2types.DomainBeaconAttester,
+			domain:      e2types.Domain(e2types.DomainBeaconAttester, []byte{0x02, 0x00, 0x00, 0x00}, mainnetRootBytes),
+			err:         "domain is not for a permitted network",
+		},
+		{
+			name:        "Deposit",
+			credentials: &checker.Credentials{Client: "client1"},
+			domainType:  e2types.DomainDeposit,
+			domain:      e2types.Domain(e2types.DomainDeposit, []byte{0x00, 0x00, 0x00, 0x00}, e2types.ZeroGenesisValidatorsRoot),
+		},
+		{
+			name:        "DepositWithRoot",
+			credentials: &checker.Credentials{Client: "client1"},
+			domainType:  e2types.DomainDeposit,
+			domain:      e2types.Domain(e2types.DomainDeposit, []byte{0x00, 0x00, 0x00, 0x00}, mainnetRootBytes),
+			err:         "domain is not for a permitted network",
+		},
+		{
+			name:        "OtherNetwork",
+			credentials: &checker.Credentials{Client: "client1"},
+			domainType:  e2types.DomainBeaconAttester,
+			domain:      e2types.Domain(e2types.DomainBeaconAttester, []byte{0x00, 0x00, 0x00, 0x01}, testnetRootBytes),
+		},
+		{
+			name:        "PinnedNetwork",
+			credentials: &checker.Credentials{Client: "client2"},
+			domainType:  e2types.DomainBeaconAttester,
+			domain:      e2types.Domain(e2types.DomainBeaconAttester, []byte{0x00, 0x00, 0x00, 0x01}, testnetRootBytes),
+		},
+		{
+			name:        "PinnedOtherNetwork",
+			credentials: &checker.Credentials{Client: "client2"},
+			domainType:  e2types.DomainBeaconAttester,
+			domain:      e2types.Domain(e2types.DomainBeaconAttester, []byte{0x00, 0x00, 0x00, 0x00}, mainnetRootBytes),
+			err:         "domain is not for a permitted network",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := service.CheckDomain(ctx, test.credentials, test.domainType, test.domain)
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCheckDomainNoNetworks(t *testing.T) {
+	service, err := networks.New(context.Background(), nil, nil)
+	require.NoError(t, err)
+	ctx := context.Background()
+	domain := append(append([]byte{}, e2types.DomainBeaconAttester[:]...), make([]byte, 28)...)
+	assert.NoError(t, service.CheckDomain(ctx, nil, e2types.DomainBeaconAttester, domain))
+	assert.EqualError(t, service.CheckDomain(ctx, nil, e2types.DomainBeaconAttester, []byte{0x01}), "domain must be 32 bytes")
+	assert.EqualError(t, service.CheckDomain(ctx, nil, e2types.DomainBeaconProposer, domain), "domain type 0x01000000 does not match expected 0x00000000")
+}
+
+func TestSlotsPerEpoch(t *testing.T) {
+	service, err := networks.New(context.Background(), testNetworks(), nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name                  string
+		genesisValidatorsRoot string
+		slotsPerEpoch         uint64
+	}{
+		{
+			name:                  "Default",
+			genesisValidatorsRoot: mainnetRoot,
+			slotsPerEpoch:         32,
+		},
+		{
+			name:                  "Configured",
+			genesisValidatorsRoot: testnetRoot,
+			slotsPerEpoch:         8,
+		},
+		{
+			name:                  "UnknownNetwork",
+			genesisValidatorsRoot: "0x0303030303030303030303030303030303030303030303030303030303030303",
+			slotsPerEpoch:         32,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			genesisValidatorsRoot, err := hex.DecodeString(strings.TrimPrefix(test.genesisValidatorsRoot, "0x"))
+			require.NoError(t, err)
+			assert.Equal(t, test.slotsPerEpoch, service.SlotsPerEpoch(genesisValidatorsRoot))
+		})
+	}
+}
+
+func TestReload(t *testing.T) {
+	ctx := context.Background()
+	service, err := networks.New(ctx, testNetworks(), nil)
+	require.NoError(t, err)
+
+	credentials := &checker.Credentials{Client: "client1"}
+	mainnetDomain := e2types.Domain(e2types.DomainBeaconAttester, []byte{0x00, 0x00, 0x00, 0x00}, bytes.Repeat([]byte{0x01}, 32))
+	require.NoError(t, service.CheckDomain(ctx, credentials, e2types.DomainBeaconAttester, mainnetDomain))
+
+	require.EqualError(t, service.Reload(ctx, &core.Permissions{Certs: []*core.CertificateInfo{{Name: "client1", Networks: []string{"other"}}}}), "unknown network other for client1")
+	require.NoError(t, service.CheckDomain(ctx, credentials, e2types.DomainBeaconAttester, mainnetDomain))
+
+	// Prepared networks are not used until applied.
+	apply, err := service.PrepareReload(ctx, &core.Permissions{Certs: []*core.CertificateInfo{{Name: "client1", Networks: []string{"testnet"}}}})
+	require.NoError(t, err)
+	require.NoError(t, service.CheckDomain(ctx, credentials, e2types.DomainBeaconAttester, mainnetDomain))
+	apply()
+	require.EqualError(t, service.CheckDomain(ctx, credentials, e2types.DomainBeaconAttester, mainnetDomain), "domain is not for a permitted network")
+
+	require.NoError(t, service.Reload(ctx, &core.Permissions{Certs: []*core.CertificateInfo{{Name: "client1", Networks: []string{"testnet"}}}}))
+	require.EqualError(t, service.CheckDomain(ctx, credentials, e2types.DomainBeaconAttester, mainnetDomain), "domain is not for a permitted network")
+}
@@ -0,0 +1,28 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package relocker
+
+import (
+	"context"
+
+	e2wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+// Service provides an interface to relock accounts when they are no longer required.
+type Service interface {
+	// Acquire marks an account as in use, so that it will not be relocked until the returned function is called.
+	Acquire(context.Context, e2wtypes.Account) func()
+	// Unlocked notes that an account has been unlocked.
+	Unlocked(context.Context, e2wtypes.Account)
+}
@@ -0,0 +1,186 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package timed
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	e2wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+	"github.com/wealdtech/walletd/core"
+)
+
+const (
+	minReapInterval = 10 * time.Millisecond
+	maxReapInterval = time.Minute
+)
+
+// Service is a relocker service that locks accounts after an idle timeout or maximum lifetime.
+type Service struct {
+	idleTimeout time.Duration
+	maxLifetime time.Duration
+	accounts    map[[48]byte]*entry
+	accountsMx  sync.Mutex
+}
+
+// entry tracks the use of an unlocked account.
+type entry struct {
+	account    e2wtypes.Account
+	unlockedAt time.Time
+	lastUsed   time.Time
+	inUse      int
+}
+
+// New creates a new timed relocker service.
+// If neither an idle timeout nor a maximum lifetime is configured then accounts are never relocked.
+func New(ctx context.Context, config *core.RelockConfig) (*Service, error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "relocker.timed.New")
+	defer span.Finish()
+
+	s := &Service{
+		accounts: make(map[[48]byte]*entry),
+	}
+	if config != nil {
+		s.idleTimeout = config.IdleTimeout
+		s.maxLifetime = config.MaxLifetime
+	}
+
+	interval := reapInterval(s.idleTimeout, s.maxLifetime)
+	if interval > 0 {
+		log.Info().Dur("idle_timeout", s.idleTimeout).Dur("max_lifetime", s.maxLifetime).Msg("Enabling relocking of accounts")
+		go s.reaper(ctx, interval)
+	}
+
+	return s, nil
+}
+
+// Acquire marks an account as in use, so that it will not be relocked until the returned function is called.
+func (s *Service) Acquire(ctx context.Context, account e2wtypes.Account) func() {
+	key := accountKey(account)
+	now := time.Now()
+
+	s.accountsMx.Lock()
+	e, exists := s.accounts[key]
+	if !exists {
+		e = &entry{
+			account:    account,
+			unlockedAt: now,
+		}
+		s.accounts[key] = e
+	}
+	e.inUse++
+	e.lastUsed = now
+	s.accountsMx.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.accountsMx.Lock()
+			e.inUse--
+			e.lastUsed = time.Now()
+			s.accountsMx.Unlock()
+		})
+	}
+}
+
+// Unlocked notes that an account has been unlocked.
+func (s *Service) Unlocked(ctx context.Context, account e2wtypes.Account) {
+	key := accountKey(account)
+	now := time.Now()
+
+	s.accountsMx.Lock()
+	e, exists := s.accounts[key]
+	if !exists {
+		e = &entry{
+			account: account,
+		}
+		s.accounts[key] = e
+	}
+	e.unlockedAt = now
+	e.lastUsed = now
+	s.accountsMx.Unlock()
+}
+
+// reaper periodically relocks accounts.
+func (s *Service) reaper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.reap()
+		}
+	}
+}
+
+// reap relocks accounts that are not in use and have passed their idle timeout or maximum lifetime.
+func (s *Service) reap() {
+	now := time.Now()
+
+	s.accountsMx.Lock()
+	defer s.accountsMx.Unlock()
+	for key, e := range s.accounts {
+		if e.inUse > 0 {
+			continue
+		}
+		if !e.account.IsUnlocked() {
+			// Locked elsewhere; no longer need to track it.
+			delete(s.accounts, key)
+			continue
+		}
+		reason := ""
+		switch {
+		case s.maxLifetime > 0 && now.Sub(e.unlockedAt) > s.maxLifetime:
+			reason = "maximum lifetime reached"
+		case s.idleTimeout > 0 && now.Sub(e.lastUsed) > s.idleTimeout:
+			reason = "idle timeout reached"
+		default:
+			continue
+		}
+		e.account.Lock()
+		delete(s.accounts, key)
+		log.Debug().Str("account", e.account.Name()).Str("reason", reason).Msg("Relocked account")
+	}
+}
+
+// reapInterval calculates the interval between reaps, based on the shortest configured duration.
+// It returns 0 if relocking is disabled.
+func reapInterval(idleTimeout time.Duration, maxLifetime time.Duration) time.Duration {
+	shortest := idleTimeout
+	if shortest == 0 || (maxLifetime > 0 && maxLifetime < shortest) {
+		shortest = maxLifetime
+	}
+	if shortest <= 0 {
+		return 0
+	}
+	interval := shortest / 4
+	if interval < minReapInterval {
+		interval = minReapInterval
+	}
+	if interval > maxReapInterval {
+		interval = maxReapInterval
+	}
+	return interval
+}
+
+// accountKey returns the key by which an account is tracked.
+func accountKey(account e2wtypes.Account) [48]byte {
+	var key [48]byte
+	copy(key[:], account.PublicKey().Marshal())
+	return key
+}
@@ -0,0 +1,124 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package timed_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+	"github.com/wealdtech/walletd/core"
+	"github.com/wealdtech/walletd/services/relocker/timed"
+	"github.com/wealdtech/walletd/testing/mock"
+)
+
+func TestMain(m *testing.M) {
+	if err := e2types.InitBLS(); err != nil {
+		os.Exit(1)
+	}
+	os.Exit(m.Run())
+}
+
+// unlockedAccount creates an unlocked account.
+func unlockedAccount(t *testing.T, name string) *mock.Account {
+	account := mock.NewAccount(name, []byte("secret"))
+	require.NoError(t, account.Unlock([]byte("secret")))
+	return account
+}
+
+func TestDisabled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service, err := timed.New(ctx, nil)
+	require.NoError(t, err)
+
+	account := unlockedAccount(t, "Account 1")
+	service.Unlocked(ctx, account)
+	release := service.Acquire(ctx, account)
+	release()
+	time.Sleep(50 * time.Millisecond)
+	assert.True(t, account.IsUnlocked())
+}
+
+func TestIdleTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service, err := timed.New(ctx, &core.RelockConfig{
+		IdleTimeout: 100 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	idle := unlockedAccount(t, "Idle")
+	service.Unlocked(ctx, idle)
+	active := unlockedAccount(t, "Active")
+	service.Unlocked(ctx, active)
+
+	// Keep using the active account.
+	for i := 0; i < 5; i++ {
+		time.Sleep(40 * time.Millisecond)
+		release := service.Acquire(ctx, active)
+		release()
+	}
+	assert.False(t, idle.IsUnlocked())
+	assert.True(t, active.IsUnlocked())
+
+	// Active account should be relocked once it stops being used.
+	assert.Eventually(t, func() bool { return !active.IsUnlocked() }, time.Second, 10*time.Millisecond)
+}
+
+func TestMaxLifetime(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service, err := timed.New(ctx, &core.RelockConfig{
+		IdleTimeout: time.Hour,
+		MaxLifetime: 100 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	account := unlockedAccount(t, "Account 1")
+	service.Unlocked(ctx, account)
+	for i := 0; i < 3; i++ {
+		release := service.Acquire(ctx, account)
+		release()
+		time.Sleep(20 * time.Millisecond)
+	}
+	assert.Eventually(t, func() bool { return !account.IsUnlocked() }, time.Second, 10*time.Millisecond)
+}
+
+func TestInUse(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service, err := timed.New(ctx, &core.RelockConfig{
+		IdleTimeout: 20 * time.Millisecond,
+		MaxLifetime: 20 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	account := unlockedAccount(t, "Account 1")
+	service.Unlocked(ctx, account)
+	release := service.Acquire(ctx, account)
+
+	// Account should not be relocked while it is in use.
+	time.Sleep(100 * time.Millisecond)
+	assert.True(t, account.IsUnlocked())
+
+	// Multiple calls to release should be harmless.
+	release()
+	release()
+	assert.Eventually(t, func() bool { return !account.IsUnlocked() }, time.Second, 10*time.Millisecond)
+}
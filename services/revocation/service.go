@@ -0,0 +1,275 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package revocation
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/wealdtech/walletd/interceptors"
+)
+
+// Service checks client certificates against a certificate revocation list and a local deny-list.
+// The files are reloaded when they change.
+type Service struct {
+	cas          []*x509.Certificate
+	crl          *source
+	denyList     *source
+	serials      map[string]string
+	fingerprints map[string]bool
+	mutex        sync.RWMutex
+}
+
+// source is a file from which revocations are loaded.
+type source struct {
+	path    string
+	modTime time.Time
+	size    int64
+	serials map[string]string
+	// fingerprints is only populated for deny-lists.
+	fingerprints map[string]bool
+}
+
+// New creates a new revocation service.
+// CRLs are verified against the supplied certificate authorities.  Either path can be blank, but not both.
+func New(ctx context.Context, cas []*x509.Certificate, crlPath string, denyListPath string) (*Service, error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "revocation.New")
+	defer span.Finish()
+
+	if crlPath == "" && denyListPath == "" {
+		return nil, errors.New("no CRL or deny-list provided")
+	}
+
+	s := &Service{
+		cas: cas,
+	}
+	if crlPath != "" {
+		s.crl = &source{path: crlPath}
+		if _, err := s.reload(s.crl, s.loadCRL); err != nil {
+			return nil, fmt.Errorf("failed to load CRL: %v", err)
+		}
+	}
+	if denyListPath != "" {
+		s.denyList = &source{path: denyListPath}
+		if _, err := s.reload(s.denyList, loadDenyList); err != nil {
+			return nil, fmt.Errorf("failed to load deny-list: %v", err)
+		}
+	}
+	s.rebuild()
+
+	return s, nil
+}
+
+// VerifyPeerCertificate rejects revoked certificates.
+// It is suitable for use as the VerifyPeerCertificate function of a TLS configuration.
+func (s *Service) VerifyPeerCertificate(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+	s.refresh()
+
+	if len(verifiedChains) == 0 || len(verifiedChains[0]) == 0 {
+		// Nothing to check.
+		return nil
+	}
+	cert := verifiedChains[0][0]
+	if reason := s.Revoked(cert); reason != "" {
+		log.Warn().
+			Str("subject", cert.Subject.String()).
+			Str("issuer", cert.Issuer.String()).
+			Str("serial", cert.SerialNumber.Text(16)).
+			Str("fingerprint", interceptors.Fingerprint(cert)).
+			Str("reason", reason).
+			Msg("Rejected revoked client certificate")
+		return errors.New("certificate revoked")
+	}
+	return nil
+}
+
+// Revoked returns the reason that the certificate has been revoked, or an empty string if it has not.
+func (s *Service) Revoked(cert *x509.Certificate) string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if s.fingerprints[interceptors.Fingerprint(cert)] {
+		return "fingerprint in deny-list"
+	}
+	if reason, exists := s.serials[cert.SerialNumber.Text(16)]; exists {
+		return reason
+	}
+	return ""
+}
+
+// refresh reloads any sources that have changed since they were last loaded.
+func (s *Service) refresh() {
+	changed := false
+	if s.crl != nil {
+		reloaded, err := s.reload(s.crl, s.loadCRL)
+		if err != nil {
+			log.Warn().Err(err).Str("path", s.crl.path).Msg("Failed to reload CRL; retaining existing revocations")
+		}
+		changed = changed || reloaded
+	}
+	if s.denyList != nil {
+		reloaded, err := s.reload(s.denyList, loadDenyList)
+		if err != nil {
+			log.Warn().Err(err).Str("path", s.denyList.path).Msg("Failed to reload deny-list; retaining existing revocations")
+		}
+		changed = changed || reloaded
+	}
+	if changed {
+		s.rebuild()
+	}
+}
+
+// reload reloads the source if it has changed, returning true if it was reloaded.
+func (s *Service) reload(src *source, load func([]byte) (map[string]string, map[string]bool, error)) (bool, error) {
+	info, err := os.Stat(src.path)
+	if err != nil {
+		return false, err
+	}
+	s.mutex.RLock()
+	unchanged := info.ModTime().Equal(src.modTime) && info.Size() == src.size
+	s.mutex.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	data, err := ioutil.ReadFile(src.path)
+	if err != nil {
+		return false, err
+	}
+	serials, fingerprints, err := load(data)
+	if err != nil {
+		return false, err
+	}
+
+	s.mutex.Lock()
+	src.modTime = info.ModTime()
+	src.size = info.Size()
+	src.serials = serials
+	src.fingerprints = fingerprints
+	s.mutex.Unlock()
+	log.Info().Str("path", src.path).Int("serials", len(serials)).Int("fingerprints", len(fingerprints)).Msg("Loaded revocations")
+	return true, nil
+}
+
+// rebuild rebuilds the combined revocations from the sources.
+func (s *Service) rebuild() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.serials = make(map[string]string)
+	s.fingerprints = make(map[string]bool)
+	for _, src := range []*source{s.crl, s.denyList} {
+		if src == nil {
+			continue
+		}
+		for serial, reason := range src.serials {
+			s.serials[serial] = reason
+		}
+		for fingerprint := range src.fingerprints {
+			s.fingerprints[fingerprint] = true
+		}
+	}
+}
+
+// loadCRL loads the serial numbers from a CRL, which can be PEM or DER encoded.
+func (s *Service) loadCRL(data []byte) (map[string]string, map[string]bool, error) {
+	crl, err := x509.ParseCRL(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	verified := false
+	for _, ca := range s.cas {
+		if err := ca.CheckCRLSignature(crl); err == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, nil, errors.New("CRL not signed by a known certificate authority")
+	}
+	if crl.HasExpired(time.Now()) {
+		log.Warn().Time("next_update", crl.TBSCertList.NextUpdate).Msg("CRL has passed its next update time")
+	}
+
+	serials := make(map[string]string, len(crl.TBSCertList.RevokedCertificates))
+	for _, revoked := range crl.TBSCertList.RevokedCertificates {
+		serials[revoked.SerialNumber.Text(16)] = "serial in CRL"
+	}
+	return serials, nil, nil
+}
+
+// loadDenyList loads serial numbers and fingerprints from a deny-list.
+// The deny-list contains one entry per line; blank lines and lines starting with '#' are ignored.
+// An entry is either a SHA-256 fingerprint as 64 hex characters, or a serial number in decimal or as hex
+// with a '0x' prefix.  Colon separators are permitted in hex values.
+func loadDenyList(data []byte) (map[string]string, map[string]bool, error) {
+	serials := make(map[string]string)
+	fingerprints := make(map[string]bool)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	line := 0
+	for scanner.Scan() {
+		line++
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		entry = strings.ToLower(entry)
+
+		if strings.Contains(entry, ":") || strings.HasPrefix(entry, "0x") {
+			value, err := hex.DecodeString(strings.TrimPrefix(strings.ReplaceAll(entry, ":", ""), "0x"))
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid entry on line %d", line)
+			}
+			if len(value) == 32 && !strings.HasPrefix(entry, "0x") {
+				fingerprints[hex.EncodeToString(value)] = true
+			} else {
+				serials[new(big.Int).SetBytes(value).Text(16)] = "serial in deny-list"
+			}
+			continue
+		}
+
+		if len(entry) == 64 {
+			value, err := hex.DecodeString(entry)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid entry on line %d", line)
+			}
+			fingerprints[hex.EncodeToString(value)] = true
+			continue
+		}
+
+		serial, ok := new(big.Int).SetString(entry, 10)
+		if !ok {
+			return nil, nil, fmt.Errorf("invalid entry on line %d", line)
+		}
+		serials[serial.Text(16)] = "serial in deny-list"
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return serials, fingerprints, nil
+}
@@ -0,0 +1,175 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package revocation_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wealdtech/walletd/interceptors"
+	"github.com/wealdtech/walletd/services/revocation"
+)
+
+func createCertificate(t *testing.T, serial int64, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: fmt.Sprintf("cert%d", serial)},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil,
+	}
+	if parent == nil {
+		parent = template
+		parentKey = key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert, key
+}
+
+func chains(cert *x509.Certificate) [][]*x509.Certificate {
+	return [][]*x509.Certificate{{cert}}
+}
+
+func TestNew(t *testing.T) {
+	base, err := ioutil.TempDir("", "TestNew")
+	require.NoError(t, err)
+	defer os.RemoveAll(base)
+
+	ca, caKey := createCertificate(t, 1, nil, nil)
+	otherCA, otherCAKey := createCertificate(t, 2, nil, nil)
+
+	crl, err := ca.CreateCRL(rand.Reader, caKey, nil, time.Now(), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	crlPath := filepath.Join(base, "ca.crl")
+	require.NoError(t, ioutil.WriteFile(crlPath, crl, 0600))
+
+	otherCRL, err := otherCA.CreateCRL(rand.Reader, otherCAKey, nil, time.Now(), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	otherCRLPath := filepath.Join(base, "other.crl")
+	require.NoError(t, ioutil.WriteFile(otherCRLPath, otherCRL, 0600))
+
+	badDenyListPath := filepath.Join(base, "bad.txt")
+	require.NoError(t, ioutil.WriteFile(badDenyListPath, []byte("# Comment\nnot a serial\n"), 0600))
+
+	tests := []struct {
+		name     string
+		crl      string
+		denyList string
+		err      string
+	}{
+		{
+			name: "Empty",
+			err:  "no CRL or deny-list provided",
+		},
+		{
+			name: "CRLMissing",
+			crl:  filepath.Join(base, "missing.crl"),
+			err:  fmt.Sprintf("failed to load CRL: stat %s: no such file or directory", filepath.Join(base, "missing.crl")),
+		},
+		{
+			name: "CRLWrongCA",
+			crl:  otherCRLPath,
+			err:  "failed to load CRL: CRL not signed by a known certificate authority",
+		},
+		{
+			name:     "DenyListBad",
+			denyList: badDenyListPath,
+			err:      "failed to load deny-list: invalid entry on line 2",
+		},
+		{
+			name: "Good",
+			crl:  crlPath,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := revocation.New(context.Background(), []*x509.Certificate{ca}, test.crl, test.denyList)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestVerifyPeerCertificate(t *testing.T) {
+	base, err := ioutil.TempDir("", "TestVerifyPeerCertificate")
+	require.NoError(t, err)
+	defer os.RemoveAll(base)
+
+	ca, caKey := createCertificate(t, 1, nil, nil)
+	cert10, _ := createCertificate(t, 10, ca, caKey)
+	cert11, _ := createCertificate(t, 11, ca, caKey)
+	cert12, _ := createCertificate(t, 12, ca, caKey)
+	cert13, _ := createCertificate(t, 13, ca, caKey)
+	cert14, _ := createCertificate(t, 14, ca, caKey)
+
+	crl, err := ca.CreateCRL(rand.Reader, caKey, []pkix.RevokedCertificate{
+		{SerialNumber: cert10.SerialNumber, RevocationTime: time.Now()},
+	}, time.Now(), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	crlPath := filepath.Join(base, "ca.crl")
+	require.NoError(t, ioutil.WriteFile(crlPath, crl, 0600))
+
+	denyListPath := filepath.Join(base, "deny.txt")
+	require.NoError(t, ioutil.WriteFile(denyListPath, []byte(fmt.Sprintf("# Revoked certificates\n11\n\n%s\n", interceptors.Fingerprint(cert12))), 0600))
+
+	service, err := revocation.New(context.Background(), []*x509.Certificate{ca}, crlPath, denyListPath)
+	require.NoError(t, err)
+
+	assert.EqualError(t, service.VerifyPeerCertificate(nil, chains(cert10)), "certificate revoked")
+	assert.EqualError(t, service.VerifyPeerCertificate(nil, chains(cert11)), "certificate revoked")
+	assert.EqualError(t, service.VerifyPeerCertificate(nil, chains(cert12)), "certificate revoked")
+	assert.NoError(t, service.VerifyPeerCertificate(nil, chains(cert13)))
+	assert.NoError(t, service.VerifyPeerCertificate(nil, chains(cert14)))
+	assert.NoError(t, service.VerifyPeerCertificate(nil, nil))
+
+	// Update the deny-list; changes should be picked up.
+	require.NoError(t, ioutil.WriteFile(denyListPath, []byte("0x0d\n"), 0600))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(denyListPath, later, later))
+	assert.EqualError(t, service.VerifyPeerCertificate(nil, chains(cert10)), "certificate revoked")
+	assert.NoError(t, service.VerifyPeerCertificate(nil, chains(cert11)))
+	assert.NoError(t, service.VerifyPeerCertificate(nil, chains(cert12)))
+	assert.EqualError(t, service.VerifyPeerCertificate(nil, chains(cert13)), "certificate revoked")
+	assert.NoError(t, service.VerifyPeerCertificate(nil, chains(cert14)))
+
+	// A bad update should leave the existing revocations in place.
+	require.NoError(t, ioutil.WriteFile(denyListPath, []byte("bad\n"), 0600))
+	later = later.Add(time.Minute)
+	require.NoError(t, os.Chtimes(denyListPath, later, later))
+	assert.EqualError(t, service.VerifyPeerCertificate(nil, chains(cert13)), "certificate revoked")
+}
@@ -33,14 +33,17 @@ func (s *Service) RunRules(ctx context.Context,
 	span, ctx := opentracing.StartSpanFromContext(ctx, "ruler.golang.RunRules")
 	defer span.Finish()
 
+	log := log.With().Str("account", fmt.Sprintf("%s/%s", walletName, accountName)).Logger()
+
 	// Do not allow multiple parallel runs of tihs code for a public key.
 	var lockKey [48]byte
 	copy(lockKey[:], accountPubKey)
-	s.locker.Lock(lockKey)
+	if err := s.locker.LockContext(ctx, lockKey); err != nil {
+		log.Debug().Err(err).Str("result", "expired").Msg("Request expired waiting for lock")
+		return core.EXPIRED
+	}
 	defer s.locker.Unlock(lockKey)
 
-	log := log.With().Str("account", fmt.Sprintf("%s/%s", walletName, accountName)).Logger()
-
 	metadata := s.assembleMetadata(ctx, accountName, accountPubKey)
 	var result core.RulesResult
 	switch action {
@@ -50,6 +53,16 @@ func (s *Service) RunRules(ctx context.Context,
 		result = s.runSignBeaconProposalRule(ctx, metadata, req.(*ruler.SignBeaconProposalData))
 	case ruler.ActionSignBeaconAttestation:
 		result = s.runSignBeaconAttestationRule(ctx, metadata, req.(*ruler.SignBeaconAttestationData))
+	case ruler.ActionSignRANDAOReveal:
+		result = s.runSignRANDAORevealRule(ctx, metadata, req.(*ruler.SignRANDAORevealData))
+	case ruler.ActionSignSelectionProof:
+		result = s.runSignSelectionProofRule(ctx, metadata, req.(*ruler.SignSelectionProofData))
+	case ruler.ActionSignAggregateAndProof:
+		result = s.runSignAggregateAndProofRule(ctx, metadata, req.(*ruler.SignAggregateAndProofData))
+	case ruler.ActionSignVoluntaryExit:
+		result = s.runSignVoluntaryExitRule(ctx, metadata, req.(*ruler.SignVoluntaryExitData))
+	case ruler.ActionSignDeposit:
+		result = s.runSignDepositRule(ctx, metadata, req.(*ruler.SignDepositData))
 	case ruler.ActionAccessAccount:
 		result = s.runListAccountsRule(ctx, metadata, req.(*ruler.AccessAccountData))
 	}
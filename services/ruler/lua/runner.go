@@ -39,15 +39,20 @@ func (s *Service) RunRules(ctx context.Context,
 	span, ctx := opentracing.StartSpanFromContext(ctx, "ruler.lua.RunRules")
 	defer span.Finish()
 
+	account := fmt.Sprintf("%s/%s", walletName, accountName)
+	log := log.With().Str("account", account).Logger()
+
 	var lockKey [48]byte
 	copy(lockKey[:], accountPubKey)
-	s.locker.Lock(lockKey)
+	if err := s.locker.LockContext(ctx, lockKey); err != nil {
+		log.Debug().Err(err).Str("result", "expired").Msg("Request expired waiting for lock")
+		return core.EXPIRED
+	}
 	defer s.locker.Unlock(lockKey)
-
-	account := fmt.Sprintf("%s/%s", walletName, accountName)
-	log := log.With().Str("account", account).Logger()
 	rules := s.matchRules(ctx, action, account)
 	now := time.Now().Unix()
+	// A rule can require operator approval, but a later rule can still deny the request outright.
+	pending := false
 	if len(rules) > 0 {
 		req, err := s.populateReqData(ctx, accountName, accountPubKey, now, req)
 		if err != nil {
@@ -60,6 +65,7 @@ func (s *Service) RunRules(ctx context.Context,
 			log.Warn().Err(err).Msg("Failed to fetch state")
 			return core.FAILED
 		}
+		denied := false
 		for i := range rules {
 			messages, result := s.runRule(ctx, rules[i], req, state)
 
@@ -80,16 +86,33 @@ func (s *Service) RunRules(ctx context.Context,
 				return core.FAILED
 			}
 
-			// Update state prior to continuing.
-			if err := s.storeState(ctx, action, accountPubKey, state); err != nil {
-				log.Warn().Err(err).Msg("Failed to update state")
-				return core.FAILED
-			}
-
 			if result == core.DENIED {
-				return core.DENIED
+				denied = true
+				break
+			}
+			if result == core.PENDING {
+				pending = true
 			}
 		}
+
+		// State is not updated for a request that has expired, as it will not be signed.  This is the last point at
+		// which a request can expire: once state is updated the request must be answered.
+		if err := ctx.Err(); err != nil {
+			log.Debug().Err(err).Str("result", "expired").Msg("Request expired running rules")
+			return core.EXPIRED
+		}
+
+		if err := s.storeState(ctx, action, accountPubKey, state); err != nil {
+			log.Warn().Err(err).Msg("Failed to update state")
+			return core.FAILED
+		}
+
+		if denied {
+			return core.DENIED
+		}
+	}
+	if pending {
+		return core.PENDING
 	}
 	return core.APPROVED
 }
@@ -114,6 +137,16 @@ func (s *Service) populateReqData(ctx context.Context, accountName string, pubKe
 		s.populateBeaconAttestationReqData(ctx, reqData, typedReq)
 	case *ruler.SignBeaconProposalData:
 		s.populateBeaconProposalReqData(ctx, reqData, typedReq)
+	case *ruler.SignRANDAORevealData:
+		s.populateRANDAORevealReqData(ctx, reqData, typedReq)
+	case *ruler.SignSelectionProofData:
+		s.populateSelectionProofReqData(ctx, reqData, typedReq)
+	case *ruler.SignAggregateAndProofData:
+		s.populateAggregateAndProofReqData(ctx, reqData, typedReq)
+	case *ruler.SignVoluntaryExitData:
+		s.populateVoluntaryExitReqData(ctx, reqData, typedReq)
+	case *ruler.SignDepositData:
+		s.populateDepositReqData(ctx, reqData, typedReq)
 	}
 
 	return reqData, nil
@@ -131,10 +164,14 @@ func (s *Service) populateBeaconAttestationReqData(ctx context.Context, reqData
 	reqData.RawSetString("domain", lua.LString(fmt.Sprintf("%0x", req.Domain)))
 	reqData.RawSetString("slot", lua.LNumber(req.Slot))
 	reqData.RawSetString("committeeIndex", lua.LNumber(req.CommitteeIndex))
-	reqData.RawSetString("sourceEpoch", lua.LNumber(req.Source.Epoch))
-	reqData.RawSetString("sourceRoot", lua.LString(fmt.Sprintf("%0x", req.Source.Root)))
-	reqData.RawSetString("targetEpoch", lua.LNumber(req.Target.Epoch))
-	reqData.RawSetString("targetRoot", lua.LString(fmt.Sprintf("%0x", req.Target.Root)))
+	if req.Source != nil {
+		reqData.RawSetString("sourceEpoch", lua.LNumber(req.Source.Epoch))
+		reqData.RawSetString("sourceRoot", lua.LString(fmt.Sprintf("%0x", req.Source.Root)))
+	}
+	if req.Target != nil {
+		reqData.RawSetString("targetEpoch", lua.LNumber(req.Target.Epoch))
+		reqData.RawSetString("targetRoot", lua.LString(fmt.Sprintf("%0x", req.Target.Root)))
+	}
 }
 
 func (s *Service) populateBeaconProposalReqData(ctx context.Context, reqData *lua.LTable, req *ruler.SignBeaconProposalData) {
@@ -146,6 +183,54 @@ func (s *Service) populateBeaconProposalReqData(ctx context.Context, reqData *lu
 	reqData.RawSetString("stateRoot", lua.LString(fmt.Sprintf("%0x", req.StateRoot)))
 }
 
+func (s *Service) populateRANDAORevealReqData(ctx context.Context, reqData *lua.LTable, req *ruler.SignRANDAORevealData) {
+	reqData.RawSetString("domain", lua.LString(fmt.Sprintf("%0x", req.Domain)))
+	reqData.RawSetString("epoch", lua.LNumber(req.Epoch))
+}
+
+func (s *Service) populateSelectionProofReqData(ctx context.Context, reqData *lua.LTable, req *ruler.SignSelectionProofData) {
+	reqData.RawSetString("domain", lua.LString(fmt.Sprintf("%0x", req.Domain)))
+	reqData.RawSetString("slot", lua.LNumber(req.Slot))
+}
+
+func (s *Service) populateAggregateAndProofReqData(ctx context.Context, reqData *lua.LTable, req *ruler.SignAggregateAndProofData) {
+	reqData.RawSetString("domain", lua.LString(fmt.Sprintf("%0x", req.Domain)))
+	reqData.RawSetString("aggregatorIndex", lua.LNumber(req.AggregatorIndex))
+	reqData.RawSetString("selectionProof", lua.LString(fmt.Sprintf("%0x", req.SelectionProof)))
+	if req.Aggregate == nil {
+		return
+	}
+	reqData.RawSetString("aggregationBits", lua.LString(fmt.Sprintf("%0x", req.Aggregate.AggregationBits)))
+	reqData.RawSetString("signature", lua.LString(fmt.Sprintf("%0x", req.Aggregate.Signature)))
+	if req.Aggregate.Data == nil {
+		return
+	}
+	reqData.RawSetString("slot", lua.LNumber(req.Aggregate.Data.Slot))
+	reqData.RawSetString("committeeIndex", lua.LNumber(req.Aggregate.Data.CommitteeIndex))
+	reqData.RawSetString("beaconBlockRoot", lua.LString(fmt.Sprintf("%0x", req.Aggregate.Data.BeaconBlockRoot)))
+	if req.Aggregate.Data.Source != nil {
+		reqData.RawSetString("sourceEpoch", lua.LNumber(req.Aggregate.Data.Source.Epoch))
+		reqData.RawSetString("sourceRoot", lua.LString(fmt.Sprintf("%0x", req.Aggregate.Data.Source.Root)))
+	}
+	if req.Aggregate.Data.Target != nil {
+		reqData.RawSetString("targetEpoch", lua.LNumber(req.Aggregate.Data.Target.Epoch))
+		reqData.RawSetString("targetRoot", lua.LString(fmt.Sprintf("%0x", req.Aggregate.Data.Target.Root)))
+	}
+}
+
+func (s *Service) populateVoluntaryExitReqData(ctx context.Context, reqData *lua.LTable, req *ruler.SignVoluntaryExitData) {
+	reqData.RawSetString("domain", lua.LString(fmt.Sprintf("%0x", req.Domain)))
+	reqData.RawSetString("epoch", lua.LNumber(req.Epoch))
+	reqData.RawSetString("validatorIndex", lua.LNumber(req.ValidatorIndex))
+}
+
+func (s *Service) populateDepositReqData(ctx context.Context, reqData *lua.LTable, req *ruler.SignDepositData) {
+	reqData.RawSetString("domain", lua.LString(fmt.Sprintf("%0x", req.Domain)))
+	reqData.RawSetString("depositPubKey", lua.LString(fmt.Sprintf("%0x", req.PubKey)))
+	reqData.RawSetString("withdrawalCredentials", lua.LString(fmt.Sprintf("%0x", req.WithdrawalCredentials)))
+	reqData.RawSetString("amount", lua.LNumber(req.Amount))
+}
+
 // matchRules fetches rules that match with the request.
 func (s *Service) matchRules(ctx context.Context, request string, account string) []*core.Rule {
 	span, _ := opentracing.StartSpanFromContext(ctx, "ruler.lua.matchRules")
@@ -190,6 +275,8 @@ func (s *Service) runRule(ctx context.Context, rule *core.Rule, req *lua.LTable,
 		return messages, core.APPROVED
 	case "Denied":
 		return messages, core.DENIED
+	case "Pending":
+		return messages, core.PENDING
 	case "Error":
 		return messages, core.FAILED
 	default:
@@ -20,11 +20,13 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/shibukawa/configdir"
 	"github.com/stretchr/testify/require"
 	"github.com/wealdtech/walletd/core"
 	"github.com/wealdtech/walletd/services/locker"
+	"github.com/wealdtech/walletd/services/ruler"
 	"github.com/wealdtech/walletd/services/ruler/lua"
 	"github.com/wealdtech/walletd/services/storage/mem"
 )
@@ -78,3 +80,139 @@ end`), 0644)
 	result2 := ruler.RunRules(context.Background(), "sign", "Test wallet", "Test account", []byte{}, nil)
 	fmt.Printf("Result 2 is %v\n", result2)
 }
+
+func TestVoluntaryExitRequest(t *testing.T) {
+	configDirs := configdir.New("wealdtech", "walletd")
+	scriptFile := filepath.Join(configDirs.QueryFolders(configdir.Global)[0].Path, "scripts", "voluntary_exit.lua")
+	defer os.Remove(scriptFile)
+	err := ioutil.WriteFile(scriptFile, []byte(`function approve(request, storage, messages)
+  if request.epoch ~= 5 or request.validatorIndex ~= 6 then
+    return "Denied"
+  end
+  return "Approved"
+end`), 0644)
+	require.NoError(t, err)
+
+	locker, err := locker.New()
+	require.NoError(t, err)
+	store, err := mem.New()
+	require.NoError(t, err)
+
+	rules, err := core.InitRules(context.Background(), []*core.RuleDefinition{
+		{
+			Name:    "test",
+			Request: ruler.ActionSignVoluntaryExit,
+			Script:  "voluntary_exit.lua",
+		},
+	})
+	require.NoError(t, err)
+
+	rulerSvc, err := lua.New(locker, store, rules)
+	require.NoError(t, err)
+
+	result := rulerSvc.RunRules(context.Background(), ruler.ActionSignVoluntaryExit, "Test wallet", "Test account", []byte{}, &ruler.SignVoluntaryExitData{Epoch: 5, ValidatorIndex: 6})
+	require.Equal(t, core.APPROVED, result)
+	result = rulerSvc.RunRules(context.Background(), ruler.ActionSignVoluntaryExit, "Test wallet", "Test account", []byte{}, &ruler.SignVoluntaryExitData{Epoch: 5, ValidatorIndex: 7})
+	require.Equal(t, core.DENIED, result)
+}
+
+func TestPendingRequest(t *testing.T) {
+	configDirs := configdir.New("wealdtech", "walletd")
+	pendingFile := filepath.Join(configDirs.QueryFolders(configdir.Global)[0].Path, "scripts", "pending.lua")
+	defer os.Remove(pendingFile)
+	err := ioutil.WriteFile(pendingFile, []byte(`function approve(request, storage, messages)
+  return "Pending"
+end`), 0644)
+	require.NoError(t, err)
+	onceFile := filepath.Join(configDirs.QueryFolders(configdir.Global)[0].Path, "scripts", "once.lua")
+	defer os.Remove(onceFile)
+	err = ioutil.WriteFile(onceFile, []byte(`function approve(request, storage, messages)
+  if storage.signed ~= nil then
+    return "Denied"
+  end
+  storage.signed = 1
+  return "Approved"
+end`), 0644)
+	require.NoError(t, err)
+
+	locker, err := locker.New()
+	require.NoError(t, err)
+	store, err := mem.New()
+	require.NoError(t, err)
+
+	rules, err := core.InitRules(context.Background(), []*core.RuleDefinition{
+		{
+			Name:    "pending",
+			Request: ruler.ActionSign,
+			Script:  "pending.lua",
+		},
+		{
+			Name:    "once",
+			Request: ruler.ActionSign,
+			Script:  "once.lua",
+		},
+	})
+	require.NoError(t, err)
+
+	rulerSvc, err := lua.New(locker, store, rules)
+	require.NoError(t, err)
+
+	// Pending overrides approval by other rules.
+	result := rulerSvc.RunRules(context.Background(), ruler.ActionSign, "Test wallet", "Test account", []byte{}, &ruler.SignData{})
+	require.Equal(t, core.PENDING, result)
+	// Denial overrides pending.
+	result = rulerSvc.RunRules(context.Background(), ruler.ActionSign, "Test wallet", "Test account", []byte{}, &ruler.SignData{})
+	require.Equal(t, core.DENIED, result)
+}
+
+func TestExpiredRequest(t *testing.T) {
+	configDirs := configdir.New("wealdtech", "walletd")
+	scriptFile := filepath.Join(configDirs.QueryFolders(configdir.Global)[0].Path, "scripts", "expire_once.lua")
+	defer os.Remove(scriptFile)
+	err := ioutil.WriteFile(scriptFile, []byte(`function approve(request, storage, messages)
+  if storage.signed ~= nil then
+    return "Denied"
+  end
+  storage.signed = 1
+  return "Approved"
+end`), 0644)
+	require.NoError(t, err)
+
+	locker, err := locker.New()
+	require.NoError(t, err)
+	store, err := mem.New()
+	require.NoError(t, err)
+
+	rules, err := core.InitRules(context.Background(), []*core.RuleDefinition{
+		{
+			Name:    "once",
+			Request: ruler.ActionSign,
+			Script:  "expire_once.lua",
+		},
+	})
+	require.NoError(t, err)
+
+	rulerSvc, err := lua.New(locker, store, rules)
+	require.NoError(t, err)
+
+	// Deadline passes while waiting for the lock.
+	var lockKey [48]byte
+	locker.Lock(lockKey)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	result := rulerSvc.RunRules(ctx, ruler.ActionSign, "Test wallet", "Test account", []byte{}, &ruler.SignData{})
+	require.Equal(t, core.EXPIRED, result)
+	locker.Unlock(lockKey)
+
+	// Request cancelled before it is run.
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	result = rulerSvc.RunRules(ctx, ruler.ActionSign, "Test wallet", "Test account", []byte{}, &ruler.SignData{})
+	require.Equal(t, core.EXPIRED, result)
+
+	// State was not updated by the expired requests.
+	result = rulerSvc.RunRules(context.Background(), ruler.ActionSign, "Test wallet", "Test account", []byte{}, &ruler.SignData{})
+	require.Equal(t, core.APPROVED, result)
+	result = rulerSvc.RunRules(context.Background(), ruler.ActionSign, "Test wallet", "Test account", []byte{}, &ruler.SignData{})
+	require.Equal(t, core.DENIED, result)
+}
@@ -26,10 +26,56 @@ var (
 	ActionSignBeaconAttestation = "Sign beacon attestation"
 	// ActionSignBeaconProposal is the action of signing a beacon proposal.
 	ActionSignBeaconProposal = "Sign beacon proposal"
+	// ActionSignRANDAOReveal is the action of signing a RANDAO reveal.
+	ActionSignRANDAOReveal = "Sign RANDAO reveal"
+	// ActionSignSelectionProof is the action of signing an aggregation selection proof.
+	ActionSignSelectionProof = "Sign selection proof"
+	// ActionSignAggregateAndProof is the action of signing an aggregate and proof.
+	ActionSignAggregateAndProof = "Sign aggregate and proof"
+	// ActionSignVoluntaryExit is the action of signing a voluntary exit.
+	ActionSignVoluntaryExit = "Sign voluntary exit"
+	// ActionSignDeposit is the action of signing deposit data.
+	ActionSignDeposit = "Sign deposit"
 	// ActionAccessAccount is the action of accessing an account.
 	ActionAccessAccount = "Access account"
+	// ActionLockWallet is the action of locking a wallet.
+	ActionLockWallet = "LockWallet"
+	// ActionUnlockWallet is the action of unlocking a wallet.
+	ActionUnlockWallet = "UnlockWallet"
+	// ActionLockAccount is the action of locking an account.
+	ActionLockAccount = "LockAccount"
+	// ActionUnlockAccount is the action of unlocking an account.
+	ActionUnlockAccount = "UnlockAccount"
+	// ActionClearLockout is the action of clearing a lockout caused by failed unlock attempts.
+	ActionClearLockout = "ClearLockout"
+	// ActionApproveVoluntaryExit is the action of approving or rejecting a request to sign a voluntary exit.
+	ActionApproveVoluntaryExit = "ApproveVoluntaryExit"
+	// ActionApprove is the action of approving or rejecting any other request that is awaiting approval.
+	ActionApprove = "Approve"
 )
 
+// Actions returns all known actions.
+func Actions() []string {
+	return []string{
+		ActionSign,
+		ActionSignBeaconAttestation,
+		ActionSignBeaconProposal,
+		ActionSignRANDAOReveal,
+		ActionSignSelectionProof,
+		ActionSignAggregateAndProof,
+		ActionSignVoluntaryExit,
+		ActionSignDeposit,
+		ActionAccessAccount,
+		ActionLockWallet,
+		ActionUnlockWallet,
+		ActionLockAccount,
+		ActionUnlockAccount,
+		ActionClearLockout,
+		ActionApproveVoluntaryExit,
+		ActionApprove,
+	}
+}
+
 // SignData is passed to 'Sign' ruler requests.
 type SignData struct {
 	Domain []byte
@@ -62,6 +108,57 @@ type SignBeaconProposalData struct {
 	BodyRoot      []byte
 }
 
+// SignRANDAORevealData is passed to 'SignRANDAOReveal' ruler requests.
+type SignRANDAORevealData struct {
+	Domain []byte
+	Epoch  uint64
+}
+
+// SignSelectionProofData is passed to 'SignSelectionProof' ruler requests.
+type SignSelectionProofData struct {
+	Domain []byte
+	Slot   uint64
+}
+
+// SignAggregateAndProofData is passed to 'SignAggregateAndProof' ruler requests.
+type SignAggregateAndProofData struct {
+	Domain          []byte
+	AggregatorIndex uint64
+	Aggregate       *Attestation
+	SelectionProof  []byte
+}
+
+// Attestation is part of SignAggregateAndProofData.
+type Attestation struct {
+	AggregationBits []byte
+	Data            *AttestationData
+	Signature       []byte
+}
+
+// AttestationData is part of Attestation.
+type AttestationData struct {
+	Slot            uint64
+	CommitteeIndex  uint64
+	BeaconBlockRoot []byte
+	Source          *Checkpoint
+	Target          *Checkpoint
+}
+
+// SignVoluntaryExitData is passed to 'SignVoluntaryExit' ruler requests.
+type SignVoluntaryExitData struct {
+	Domain         []byte
+	Epoch          uint64
+	ValidatorIndex uint64
+}
+
+// SignDepositData is passed to 'SignDeposit' ruler requests.
+type SignDepositData struct {
+	Domain                []byte
+	PubKey                []byte
+	WithdrawalCredentials []byte
+	Amount                uint64
+}
+
 // AccessAccountData is passed to 'AccessAccount' ruler requests.
 type AccessAccountData struct {
 	Paths []string
@@ -0,0 +1,141 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signer
+
+import (
+	"sync"
+	"time"
+
+	"github.com/wealdtech/go-bytesutil"
+)
+
+const (
+	// defaultSignatureCacheWindow is the default time for which signatures are cached.
+	defaultSignatureCacheWindow = 2 * time.Minute
+	// defaultSignatureCacheSize is the default number of signatures cached for each account.
+	defaultSignatureCacheSize = 64
+)
+
+// signatureCache holds recent signatures for each account, keyed by action and signing root, so that identical
+// requests can be answered without signing again.
+type signatureCache struct {
+	window   time.Duration
+	size     int
+	mutex    sync.Mutex
+	accounts map[[48]byte]*accountSignatures
+}
+
+// signatureKey is the key for a cached signature.
+type signatureKey struct {
+	action      string
+	signingRoot [32]byte
+}
+
+// cachedSignature is a cached signature.
+type cachedSignature struct {
+	signature []byte
+	expires   time.Time
+}
+
+// accountSignatures are the cached signatures for a single account, along with the order in which they were added.
+type accountSignatures struct {
+	signatures map[signatureKey]*cachedSignature
+	order      []signatureKey
+}
+
+// newSignatureCache creates a new signature cache.
+// A window or size of 0 disables the cache.
+func newSignatureCache(window time.Duration, size int) *signatureCache {
+	return &signatureCache{
+		window:   window,
+		size:     size,
+		accounts: make(map[[48]byte]*accountSignatures),
+	}
+}
+
+// enabled returns true if the cache is enabled.
+func (c *signatureCache) enabled() bool {
+	return c.window > 0 && c.size > 0
+}
+
+// fetch returns the cached signature for the action and signing root with the given account, if present.
+func (c *signatureCache) fetch(pubKey []byte, action string, signingRoot [32]byte) ([]byte, bool) {
+	if !c.enabled() {
+		return nil, false
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	account, exists := c.accounts[bytesutil.ToBytes48(pubKey)]
+	if !exists {
+		return nil, false
+	}
+	cached, exists := account.signatures[signatureKey{action: action, signingRoot: signingRoot}]
+	if !exists || time.Now().After(cached.expires) {
+		return nil, false
+	}
+	return cached.signature, true
+}
+
+// store caches the signature for the action and signing root with the given account.
+// If the account has reached its limit the oldest signature is removed.
+func (c *signatureCache) store(pubKey []byte, action string, signingRoot [32]byte, signature []byte) {
+	if !c.enabled() {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	key := bytesutil.ToBytes48(pubKey)
+	account, exists := c.accounts[key]
+	if !exists {
+		account = &accountSignatures{
+			signatures: make(map[signatureKey]*cachedSignature),
+		}
+		c.accounts[key] = account
+	}
+	now := time.Now()
+	account.prune(now)
+
+	sigKey := signatureKey{action: action, signingRoot: signingRoot}
+	if _, exists := account.signatures[sigKey]; exists {
+		// Already cached by a concurrent request.
+		return
+	}
+	if len(account.order) >= c.size {
+		delete(account.signatures, account.order[0])
+		account.order = account.order[1:]
+	}
+	account.order = append(account.order, sigKey)
+	account.signatures[sigKey] = &cachedSignature{
+		signature: signature,
+		expires:   now.Add(c.window),
+	}
+}
+
+// prune removes expired signatures.
+// Signatures are added in order, so expired signatures are always at the start of the order.
+func (a *accountSignatures) prune(now time.Time) {
+	expired := 0
+	for _, key := range a.order {
+		if now.Before(a.signatures[key].expires) {
+			break
+		}
+		delete(a.signatures, key)
+		expired++
+	}
+	a.order = a.order[expired:]
+}
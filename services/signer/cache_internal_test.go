@@ -0,0 +1,84 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignatureCache(t *testing.T) {
+	pubKey1 := []byte{0x01}
+	pubKey2 := []byte{0x02}
+	root1 := [32]byte{0x01}
+	root2 := [32]byte{0x02}
+	root3 := [32]byte{0x03}
+
+	cache := newSignatureCache(time.Minute, 2)
+	_, exists := cache.fetch(pubKey1, "action", root1)
+	assert.False(t, exists)
+
+	cache.store(pubKey1, "action", root1, []byte{0x11})
+	signature, exists := cache.fetch(pubKey1, "action", root1)
+	require.True(t, exists)
+	assert.Equal(t, []byte{0x11}, signature)
+
+	// Signatures are specific to the account and action.
+	_, exists = cache.fetch(pubKey2, "action", root1)
+	assert.False(t, exists)
+	_, exists = cache.fetch(pubKey1, "other action", root1)
+	assert.False(t, exists)
+
+	// The oldest signature is removed when the account is full.
+	cache.store(pubKey1, "action", root2, []byte{0x12})
+	cache.store(pubKey1, "action", root3, []byte{0x13})
+	_, exists = cache.fetch(pubKey1, "action", root1)
+	assert.False(t, exists)
+	_, exists = cache.fetch(pubKey1, "action", root2)
+	assert.True(t, exists)
+	_, exists = cache.fetch(pubKey1, "action", root3)
+	assert.True(t, exists)
+
+	// Other accounts are unaffected.
+	cache.store(pubKey2, "action", root1, []byte{0x21})
+	_, exists = cache.fetch(pubKey1, "action", root3)
+	assert.True(t, exists)
+}
+
+func TestSignatureCacheExpiry(t *testing.T) {
+	pubKey := []byte{0x01}
+	cache := newSignatureCache(50*time.Millisecond, 2)
+	cache.store(pubKey, "action", [32]byte{0x01}, []byte{0x11})
+	_, exists := cache.fetch(pubKey, "action", [32]byte{0x01})
+	require.True(t, exists)
+
+	time.Sleep(100 * time.Millisecond)
+	_, exists = cache.fetch(pubKey, "action", [32]byte{0x01})
+	assert.False(t, exists)
+
+	// Expired signatures are pruned and do not count towards the limit.
+	cache.store(pubKey, "action", [32]byte{0x02}, []byte{0x12})
+	assert.Len(t, cache.accounts[[48]byte{0x01}].order, 1)
+}
+
+func TestSignatureCacheDisabled(t *testing.T) {
+	for _, cache := range []*signatureCache{newSignatureCache(0, 2), newSignatureCache(time.Minute, 0)} {
+		cache.store([]byte{0x01}, "action", [32]byte{0x01}, []byte{0x11})
+		_, exists := cache.fetch([]byte{0x01}, "action", [32]byte{0x01})
+		assert.False(t, exists)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signer
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+)
+
+// The hash tree root methods in this file are written by hand rather than generated, so that the build does not
+// depend on an SSZ code generator.  They compute roots directly from the fields of each structure, avoiding the cost of
+// go-ssz's reflection for the most frequently signed objects; other objects are hashed by go-ssz.  The methods must be
+// kept in step with the structures manually, and are checked against go-ssz in the tests.
+
+// errFieldTooLong is returned when a fixed-size field is longer than its SSZ size.
+var errFieldTooLong = errors.New("field longer than its ssz size")
+
+// hashTreeRooter is implemented by objects that can calculate their own hash tree root.
+type hashTreeRooter interface {
+	HashTreeRoot() ([32]byte, error)
+}
+
+// signingData is the Ethereum 2 SigningData struct with SSZ size information.
+type signingData struct {
+	ObjectRoot []byte `ssz-size:"32"`
+	Domain     []byte `ssz-size:"32"`
+}
+
+// HashTreeRoot calculates the hash tree root of the signing data.
+func (s *signingData) HashTreeRoot() ([32]byte, error) {
+	var chunks [2][32]byte
+	if err := putBytes32(&chunks[0], s.ObjectRoot); err != nil {
+		return [32]byte{}, err
+	}
+	if err := putBytes32(&chunks[1], s.Domain); err != nil {
+		return [32]byte{}, err
+	}
+	return merkleize(chunks[:]), nil
+}
+
+// HashTreeRoot calculates the hash tree root of the checkpoint.
+func (c *Checkpoint) HashTreeRoot() ([32]byte, error) {
+	var chunks [2][32]byte
+	if c == nil {
+		return merkleize(chunks[:]), nil
+	}
+	putUint64(&chunks[0], c.Epoch)
+	if err := putBytes32(&chunks[1], c.Root); err != nil {
+		return [32]byte{}, err
+	}
+	return merkleize(chunks[:]), nil
+}
+
+// HashTreeRoot calculates the hash tree root of the beacon attestation.
+func (a *BeaconAttestation) HashTreeRoot() ([32]byte, error) {
+	var chunks [5][32]byte
+	var err error
+	putUint64(&chunks[0], a.Slot)
+	putUint64(&chunks[1], a.CommitteeIndex)
+	if err = putBytes32(&chunks[2], a.BeaconBlockRoot); err != nil {
+		return [32]byte{}, err
+	}
+	if chunks[3], err = a.Source.HashTreeRoot(); err != nil {
+		return [32]byte{}, err
+	}
+	if chunks[4], err = a.Target.HashTreeRoot(); err != nil {
+		return [32]byte{}, err
+	}
+	return merkleize(chunks[:]), nil
+}
+
+// HashTreeRoot calculates the hash tree root of the beacon block header.
+func (b *BeaconBlockHeader) HashTreeRoot() ([32]byte, error) {
+	var chunks [5][32]byte
+	putUint64(&chunks[0], b.Slot)
+	putUint64(&chunks[1], b.ProposerIndex)
+	if err := putBytes32(&chunks[2], b.ParentRoot); err != nil {
+		return [32]byte{}, err
+	}
+	if err := putBytes32(&chunks[3], b.StateRoot); err != nil {
+		return [32]byte{}, err
+	}
+	if err := putBytes32(&chunks[4], b.BodyRoot); err != nil {
+		return [32]byte{}, err
+	}
+	return merkleize(chunks[:]), nil
+}
+
+// putUint64 places a little-endian uint64 in a chunk.
+func putUint64(chunk *[32]byte, val uint64) {
+	binary.LittleEndian.PutUint64(chunk[:8], val)
+}
+
+// putBytes32 places a fixed-size byte field in a chunk.  Short fields are padded with zeros, as go-ssz does.
+func putBytes32(chunk *[32]byte, val []byte) error {
+	if len(val) > 32 {
+		return errFieldTooLong
+	}
+	copy(chunk[:], val)
+	return nil
+}
+
+// merkleize calculates the merkle root of a set of chunks, padding with zero chunks to the next power of two.
+// The supplied chunks are overwritten.
+func merkleize(chunks [][32]byte) [32]byte {
+	var buf [64]byte
+	var zero [32]byte
+	for len(chunks) > 1 {
+		pairs := (len(chunks) + 1) / 2
+		for i := 0; i < pairs; i++ {
+			copy(buf[:32], chunks[2*i][:])
+			if 2*i+1 < len(chunks) {
+				copy(buf[32:], chunks[2*i+1][:])
+			} else {
+				copy(buf[32:], zero[:])
+			}
+			chunks[i] = sha256.Sum256(buf[:])
+		}
+		chunks = chunks[:pairs]
+		copy(buf[:32], zero[:])
+		copy(buf[32:], zero[:])
+		zero = sha256.Sum256(buf[:])
+	}
+	return chunks[0]
+}
@@ -0,0 +1,249 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signer
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	ssz "github.com/prysmaticlabs/go-ssz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sequence returns 32 bytes incrementing from the given start.
+func sequence(start byte) []byte {
+	res := make([]byte, 32)
+	for i := range res {
+		res[i] = start + byte(i)
+	}
+	return res
+}
+
+// randomBytes returns random bytes of the given length.
+func randomBytes(rng *rand.Rand, length int) []byte {
+	res := make([]byte, length)
+	rng.Read(res)
+	return res
+}
+
+func TestHashTreeRootGolden(t *testing.T) {
+	tests := []struct {
+		name string
+		obj  hashTreeRooter
+		root string
+	}{
+		{
+			name: "BeaconAttestationEmpty",
+			obj:  &BeaconAttestation{},
+			root: "01f278ee83d4e438cf8f563ce108974d64c029a20280ab8eca07741df7ee5290",
+		},
+		{
+			name: "BeaconAttestationZero",
+			obj:  &BeaconAttestation{Source: &Checkpoint{}, Target: &Checkpoint{}},
+			root: "01f278ee83d4e438cf8f563ce108974d64c029a20280ab8eca07741df7ee5290",
+		},
+		{
+			name: "BeaconAttestation",
+			obj: &BeaconAttestation{
+				Slot:            12345,
+				CommitteeIndex:  12,
+				BeaconBlockRoot: sequence(0x00),
+				Source:          &Checkpoint{Epoch: 384, Root: sequence(0x20)},
+				Target:          &Checkpoint{Epoch: 385, Root: sequence(0x40)},
+			},
+			root: "3af5981073bfc95f585f7e39d436dd37c18b7c54921ca18ec1c10127f2d41780",
+		},
+		{
+			name: "BeaconBlockHeaderEmpty",
+			obj:  &BeaconBlockHeader{},
+			root: "c78009fdf07fc56a11f122370658a353aaa542ed63e44c4bc15ff4cd105ab33c",
+		},
+		{
+			name: "BeaconBlockHeader",
+			obj: &BeaconBlockHeader{
+				Slot:          12345,
+				ProposerIndex: 678,
+				ParentRoot:    sequence(0x00),
+				StateRoot:     sequence(0x20),
+				BodyRoot:      sequence(0x40),
+			},
+			root: "016cf01e5e836559acb1038f3d4c28d2d214e3e709dc88f176d8d55997e7f7ee",
+		},
+		{
+			name: "SigningData",
+			obj:  &signingData{ObjectRoot: sequence(0x00), Domain: sequence(0x20)},
+			root: "fdeab9acf3710362bd2658cdc9a29e8f9c757fcf9811603a8c447cd1d9151108",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root, err := test.obj.HashTreeRoot()
+			require.NoError(t, err)
+			assert.Equal(t, test.root, fmt.Sprintf("%x", root))
+			reflectedRoot, err := ssz.HashTreeRoot(test.obj)
+			require.NoError(t, err)
+			assert.Equal(t, reflectedRoot, root)
+		})
+	}
+}
+
+func TestHashTreeRootMatchesReflection(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	// Lengths include short fields, which are padded with zeros.
+	lengths := []int{0, 1, 31, 32}
+	for i := 0; i < 1000; i++ {
+		length := lengths[i%len(lengths)]
+		objs := []hashTreeRooter{
+			&BeaconAttestation{
+				Slot:            rng.Uint64(),
+				CommitteeIndex:  rng.Uint64(),
+				BeaconBlockRoot: randomBytes(rng, length),
+				Source:          &Checkpoint{Epoch: rng.Uint64(), Root: randomBytes(rng, 32)},
+				Target:          &Checkpoint{Epoch: rng.Uint64(), Root: randomBytes(rng, length)},
+			},
+			&BeaconBlockHeader{
+				Slot:          rng.Uint64(),
+				ProposerIndex: rng.Uint64(),
+				ParentRoot:    randomBytes(rng, 32),
+				StateRoot:     randomBytes(rng, length),
+				BodyRoot:      randomBytes(rng, 32),
+			},
+			&signingData{
+				ObjectRoot: randomBytes(rng, length),
+				Domain:     randomBytes(rng, 32),
+			},
+		}
+		for _, obj := range objs {
+			root, err := obj.HashTreeRoot()
+			require.NoError(t, err)
+			reflectedRoot, err := ssz.HashTreeRoot(obj)
+			require.NoError(t, err)
+			require.Equal(t, reflectedRoot, root, "mismatch for %#v", obj)
+		}
+	}
+}
+
+func TestHashTreeRootFieldTooLong(t *testing.T) {
+	tests := []struct {
+		name string
+		obj  hashTreeRooter
+	}{
+		{
+			name: "BeaconAttestation",
+			obj:  &BeaconAttestation{BeaconBlockRoot: make([]byte, 33)},
+		},
+		{
+			name: "BeaconAttestationSource",
+			obj:  &BeaconAttestation{Source: &Checkpoint{Root: make([]byte, 33)}},
+		},
+		{
+			name: "BeaconBlockHeader",
+			obj:  &BeaconBlockHeader{BodyRoot: make([]byte, 33)},
+		},
+		{
+			name: "SigningData",
+			obj:  &signingData{ObjectRoot: make([]byte, 33)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.obj.HashTreeRoot()
+			assert.EqualError(t, err, "field longer than its ssz size")
+		})
+	}
+}
+
+func benchmarkAttestation() *BeaconAttestation {
+	return &BeaconAttestation{
+		Slot:            12345,
+		CommitteeIndex:  12,
+		BeaconBlockRoot: sequence(0x00),
+		Source:          &Checkpoint{Epoch: 384, Root: sequence(0x20)},
+		Target:          &Checkpoint{Epoch: 385, Root: sequence(0x40)},
+	}
+}
+
+func benchmarkBlockHeader() *BeaconBlockHeader {
+	return &BeaconBlockHeader{
+		Slot:          12345,
+		ProposerIndex: 678,
+		ParentRoot:    sequence(0x00),
+		StateRoot:     sequence(0x20),
+		BodyRoot:      sequence(0x40),
+	}
+}
+
+func BenchmarkBeaconAttestationHashTreeRoot(b *testing.B) {
+	attestation := benchmarkAttestation()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := attestation.HashTreeRoot(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBeaconAttestationHashTreeRootReflection(b *testing.B) {
+	attestation := benchmarkAttestation()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := ssz.HashTreeRoot(attestation); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBeaconBlockHeaderHashTreeRoot(b *testing.B) {
+	header := benchmarkBlockHeader()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := header.HashTreeRoot(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBeaconBlockHeaderHashTreeRootReflection(b *testing.B) {
+	header := benchmarkBlockHeader()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := ssz.HashTreeRoot(header); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSigningDataHashTreeRoot(b *testing.B) {
+	data := &signingData{ObjectRoot: sequence(0x00), Domain: sequence(0x20)}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := data.HashTreeRoot(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSigningDataHashTreeRootReflection(b *testing.B) {
+	data := &signingData{ObjectRoot: sequence(0x00), Domain: sequence(0x20)}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := ssz.HashTreeRoot(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
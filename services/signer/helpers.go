@@ -16,14 +16,20 @@ package signer
 import (
 	context "context"
 	"fmt"
+	"time"
 
 	"github.com/opentracing/opentracing-go"
+	"github.com/rs/zerolog"
+	"github.com/wealdtech/go-bytesutil"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
 	e2wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
 	"github.com/wealdtech/walletd/core"
+	"github.com/wealdtech/walletd/interceptors"
 	"github.com/wealdtech/walletd/services/checker"
 )
 
-// preCheck carries out pre-checks for all signing requests.
+// preCheck carries out pre-checks for all signing requests, fetching the account and confirming that the client can
+// carry out the requested action with it.
 func (s *Service) preCheck(ctx context.Context, credentials *checker.Credentials, name string, pubKey []byte, action string) (e2wtypes.Wallet, e2wtypes.Account, core.RulesResult) {
 	span, ctx := opentracing.StartSpanFromContext(ctx, "services.signer.preCheck")
 	defer span.Finish()
@@ -41,13 +47,216 @@ func (s *Service) preCheck(ctx context.Context, credentials *checker.Credentials
 		return nil, nil, result
 	}
 
+	return wallet, account, core.APPROVED
+}
+
+// prepareAccount unlocks the account if necessary.
+// If successful the account is held unlocked until the returned release function is called.
+func (s *Service) prepareAccount(ctx context.Context, wallet e2wtypes.Wallet, account e2wtypes.Account) (func(), core.RulesResult) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "services.signer.prepareAccount")
+	defer span.Finish()
+
+	// Ensure the account is not relocked while it is in use.
+	release := s.relocker.Acquire(ctx, account)
+
 	// Unlock the account if necessary.
-	result = s.unlockAccount(ctx, wallet, account)
+	result := s.unlockAccount(ctx, wallet, account)
 	if result != core.APPROVED {
-		return nil, nil, result
+		release()
+		return nil, result
 	}
 
-	return wallet, account, core.APPROVED
+	return release, core.APPROVED
+}
+
+// signObject carries out the common flow for typed signing requests: checking the domain and signing the hash tree
+// root of the supplied object.
+func (s *Service) signObject(ctx context.Context, credentials *checker.Credentials, accountName string, pubKey []byte, action string, domainType e2types.DomainType, domain []byte, ruleData interface{}, obj interface{}) (core.RulesResult, []byte, string) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "services.signer.signObject")
+	defer span.Finish()
+
+	if checkRes := s.checkDomain(ctx, credentials, domainType, domain); checkRes != core.APPROVED {
+		return checkRes, nil, ""
+	}
+
+	signingRoot, err := generateSigningRootFromData(ctx, obj, domain)
+	if err != nil {
+		log.Warn().Err(err).Str("action", action).Str("result", "failed").Msg("Failed to generate signing root")
+		return core.FAILED, nil, ""
+	}
+
+	return s.approveAndSign(ctx, credentials, accountName, pubKey, action, ruleData, signingRoot, false)
+}
+
+// approveAndSign carries out the common flow for all signing requests once the signing root is known: pre-checking the
+// account, running the rules, obtaining approval by operators if required, and signing the signing root.
+// If the same request has been signed recently the previous signature is returned without running the rules again.
+func (s *Service) approveAndSign(ctx context.Context, credentials *checker.Credentials, accountName string, pubKey []byte, action string, ruleData interface{}, signingRoot [32]byte, requireApproval bool) (core.RulesResult, []byte, string) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "services.signer.approveAndSign")
+	defer span.Finish()
+	log := log.With().Str("action", action).Logger()
+
+	if expired(ctx, log, "pre-check") {
+		return core.EXPIRED, nil, ""
+	}
+	wallet, account, checkRes := s.preCheck(ctx, credentials, accountName, pubKey, action)
+	if checkRes != core.APPROVED {
+		return checkRes, nil, ""
+	}
+	accountName = fmt.Sprintf("%s/%s", wallet.Name(), account.Name())
+	log = log.With().Str("account", accountName).Logger()
+	accountPubKey := account.PublicKey().Marshal()
+
+	// Signing the same data again cannot be slashable, so a request that has already been signed can be given the same
+	// signature without unlocking the account or running the rules again.
+	if signature, exists := s.signatures.fetch(accountPubKey, action, signingRoot); exists {
+		log.Debug().Str("result", "succeeded").Msg("Returning recent signature for identical request")
+		return core.APPROVED, signature, ""
+	}
+
+	// Requests for the account are handled one at a time from here until they are signed, so that a retry that arrives
+	// while the original request is in progress waits for it and is given its signature.
+	lockKey := bytesutil.ToBytes48(accountPubKey)
+	if err := s.accountLocks.LockContext(ctx, lockKey); err != nil {
+		expired(ctx, log, "account lock")
+		return core.EXPIRED, nil, ""
+	}
+	locked := true
+	unlock := func() {
+		if locked {
+			s.accountLocks.Unlock(lockKey)
+			locked = false
+		}
+	}
+	defer unlock()
+	if signature, exists := s.signatures.fetch(accountPubKey, action, signingRoot); exists {
+		log.Debug().Str("result", "succeeded").Msg("Returning recent signature for identical request")
+		return core.APPROVED, signature, ""
+	}
+
+	release, checkRes := s.prepareAccount(ctx, wallet, account)
+	if checkRes != core.APPROVED {
+		return checkRes, nil, ""
+	}
+	defer release()
+	if expired(ctx, log, "rules") {
+		return core.EXPIRED, nil, ""
+	}
+
+	// Confirm approval via rules.
+	result := s.ruler.RunRules(ctx, action, wallet.Name(), account.Name(), accountPubKey, ruleData)
+	switch result {
+	case core.DENIED:
+		log.Debug().Str("result", "denied").Msg("Denied by rules")
+		return core.DENIED, nil, ""
+	case core.FAILED:
+		log.Warn().Str("result", "failed").Msg("Rules check failed")
+		return core.FAILED, nil, ""
+	case core.EXPIRED:
+		log.Debug().Str("result", "expired").Msg("Request expired running rules")
+		return core.EXPIRED, nil, ""
+	}
+
+	// The rules have updated their state, so from here on the request is answered even if it has expired.
+
+	// Confirm approval by operators if required.
+	approvalID := ""
+	if requireApproval || result == core.PENDING {
+		// Signatures released by operators are not cached, so there is nothing for other requests to wait for.
+		unlock()
+		result, approvalID = s.checkApproval(ctx, credentials, accountName, action, signingRoot[:])
+		if result != core.APPROVED {
+			return result, nil, approvalID
+		}
+	}
+
+	// Sign it.
+	signature, err := signRoot(ctx, account, signingRoot[:])
+	if err != nil {
+		log.Warn().Err(err).Str("result", "failed").Msg("Failed to sign")
+		return core.FAILED, nil, ""
+	}
+	if approvalID == "" {
+		// Signatures released by operators are not cached, so that each approval releases a single signature.
+		s.signatures.store(accountPubKey, action, signingRoot, signature)
+	}
+
+	log.Debug().Str("result", "succeeded").Msg("Success")
+	return core.APPROVED, signature, approvalID
+}
+
+// approvalWaitMargin is the time before the deadline of a request at which waiting for approval stops, so that the
+// pending result can be returned before the deadline passes.
+const approvalWaitMargin = 500 * time.Millisecond
+
+// checkApproval obtains approval by operators for the client to carry out the action with the signing root.
+// If the request has a deadline, this waits for a decision until shortly before the deadline.
+// The ID of the approval is returned alongside the result.
+func (s *Service) checkApproval(ctx context.Context, credentials *checker.Credentials, accountName string, action string, signingRoot []byte) (core.RulesResult, string) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "services.signer.checkApproval")
+	defer span.Finish()
+
+	client := ""
+	if credentials != nil {
+		client = credentials.Client
+	}
+	id, _ := ctx.Value(&interceptors.ApprovalID{}).(string)
+	log := log.With().Str("client", client).Str("account", accountName).Str("action", action).Logger()
+
+	approval, result := s.approvals.Request(id, credentials, accountName, action, signingRoot)
+	if result == core.PENDING {
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) > approvalWaitMargin {
+			log.Debug().Str("id", approval.ID).Time("deadline", deadline).Msg("Waiting for approval")
+			waitCtx, cancel := context.WithDeadline(ctx, deadline.Add(-approvalWaitMargin))
+			s.approvals.Wait(waitCtx, approval.ID)
+			cancel()
+			if err := ctx.Err(); err != nil {
+				// Do not collect an approval for a request that can no longer be answered.
+				log.Debug().Err(err).Str("id", approval.ID).Str("result", "expired").Msg("Request expired awaiting approval")
+				return core.EXPIRED, approval.ID
+			}
+			approval, result = s.approvals.Request(approval.ID, credentials, accountName, action, signingRoot)
+		}
+	}
+	if approval == nil {
+		return result, ""
+	}
+
+	switch result {
+	case core.APPROVED:
+		log.Info().Str("id", approval.ID).Strs("approvers", approval.Approvers).Msg("Approved by operators")
+	case core.DENIED:
+		log.Info().Str("id", approval.ID).Str("rejecter", approval.Rejecter).Str("result", "denied").Msg("Rejected by operators")
+	case core.PENDING:
+		log.Info().Str("id", approval.ID).Str("result", "pending").Msg("Awaiting approval by operators")
+	}
+	return result, approval.ID
+}
+
+// expired returns true if the request has been cancelled or its deadline has passed, logging the stage at which this
+// was noticed.
+func expired(ctx context.Context, log zerolog.Logger, stage string) bool {
+	if err := ctx.Err(); err != nil {
+		log.Debug().Err(err).Str("stage", stage).Str("result", "expired").Msg("Request expired")
+		return true
+	}
+	return false
+}
+
+// checkDomain checks that the domain is of the expected type, and is for a network permitted to the client.
+func (s *Service) checkDomain(ctx context.Context, credentials *checker.Credentials, domainType e2types.DomainType, domain []byte) core.RulesResult {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "services.signer.checkDomain")
+	defer span.Finish()
+
+	if err := s.networks.CheckDomain(ctx, credentials, domainType, domain); err != nil {
+		client := ""
+		if credentials != nil {
+			client = credentials.Client
+		}
+		log.Warn().Err(err).Str("client", client).Str("domain", fmt.Sprintf("%#x", domain)).Str("result", "denied").Msg("Invalid domain")
+		return core.DENIED
+	}
+	return core.APPROVED
 }
 
 // fetchAccount fetches an account by either name or public key, depending on which has been supplied.
@@ -64,9 +273,9 @@ func (s *Service) fetchAccount(ctx context.Context, credentials *checker.Credent
 	var account e2wtypes.Account
 	var err error
 	if pubKey == nil {
-		wallet, account, err = s.fetcher.FetchAccount(name)
+		wallet, account, err = s.fetcher.FetchAccount(ctx, name)
 	} else {
-		wallet, account, err = s.fetcher.FetchAccountByKey(pubKey)
+		wallet, account, err = s.fetcher.FetchAccountByKey(ctx, pubKey)
 	}
 
 	if err != nil {
@@ -115,5 +324,6 @@ func (s *Service) unlockAccount(ctx context.Context, wallet e2wtypes.Wallet, acc
 		log.Debug().Str("result", "denied").Msg("Account is locked; signing request denied")
 		return core.DENIED
 	}
+	s.relocker.Unlocked(ctx, account)
 	return core.APPROVED
 }
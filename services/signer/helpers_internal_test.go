@@ -30,6 +30,8 @@ import (
 	mockchecker "github.com/wealdtech/walletd/services/checker/mock"
 	"github.com/wealdtech/walletd/services/fetcher/memfetcher"
 	"github.com/wealdtech/walletd/services/locker"
+	"github.com/wealdtech/walletd/services/networks"
+	timedrelocker "github.com/wealdtech/walletd/services/relocker/timed"
 	"github.com/wealdtech/walletd/services/ruler"
 	"github.com/wealdtech/walletd/services/ruler/lua"
 	"github.com/wealdtech/walletd/services/storage/mem"
@@ -57,7 +59,7 @@ func TestUnlockAccount(t *testing.T) {
 
 	lockerSvc, err := locker.New()
 	require.NoError(t, err)
-	fetcherSvc, err := memfetcher.New([]e2wtypes.Store{store})
+	fetcherSvc, err := memfetcher.New(context.Background(), []e2wtypes.Store{store})
 	require.NoError(t, err)
 	storageSvc, err := mem.New()
 	require.NoError(t, err)
@@ -74,7 +76,13 @@ func TestUnlockAccount(t *testing.T) {
 	checkerSvc, err := mockchecker.New()
 	require.NoError(t, err)
 
-	signerSvc, err := New(unlockerSvc, checkerSvc, fetcherSvc, rulerSvc)
+	relockerSvc, err := timedrelocker.New(context.Background(), nil)
+	require.NoError(t, err)
+
+	networksSvc, err := networks.New(context.Background(), nil, nil)
+	require.NoError(t, err)
+
+	signerSvc, err := New(unlockerSvc, relockerSvc, checkerSvc, fetcherSvc, rulerSvc, networksSvc)
 	require.NoError(t, err)
 
 	tests := []struct {
@@ -144,7 +152,7 @@ func TestCheckAccess(t *testing.T) {
 
 	lockerSvc, err := locker.New()
 	require.NoError(t, err)
-	fetcherSvc, err := memfetcher.New([]e2wtypes.Store{store})
+	fetcherSvc, err := memfetcher.New(context.Background(), []e2wtypes.Store{store})
 	require.NoError(t, err)
 	storageSvc, err := mem.New()
 	require.NoError(t, err)
@@ -161,7 +169,13 @@ func TestCheckAccess(t *testing.T) {
 	checkerSvc, err := mockchecker.New()
 	require.NoError(t, err)
 
-	signerSvc, err := New(unlockerSvc, checkerSvc, fetcherSvc, rulerSvc)
+	relockerSvc, err := timedrelocker.New(context.Background(), nil)
+	require.NoError(t, err)
+
+	networksSvc, err := networks.New(context.Background(), nil, nil)
+	require.NoError(t, err)
+
+	signerSvc, err := New(unlockerSvc, relockerSvc, checkerSvc, fetcherSvc, rulerSvc, networksSvc)
 	require.NoError(t, err)
 
 	tests := []struct {
@@ -223,7 +237,7 @@ func TestFetchAccount(t *testing.T) {
 
 	lockerSvc, err := locker.New()
 	require.NoError(t, err)
-	fetcherSvc, err := memfetcher.New([]e2wtypes.Store{store})
+	fetcherSvc, err := memfetcher.New(context.Background(), []e2wtypes.Store{store})
 	require.NoError(t, err)
 	storageSvc, err := mem.New()
 	require.NoError(t, err)
@@ -240,7 +254,13 @@ func TestFetchAccount(t *testing.T) {
 	checkerSvc, err := mockchecker.New()
 	require.NoError(t, err)
 
-	signerSvc, err := New(unlockerSvc, checkerSvc, fetcherSvc, rulerSvc)
+	relockerSvc, err := timedrelocker.New(context.Background(), nil)
+	require.NoError(t, err)
+
+	networksSvc, err := networks.New(context.Background(), nil, nil)
+	require.NoError(t, err)
+
+	signerSvc, err := New(unlockerSvc, relockerSvc, checkerSvc, fetcherSvc, rulerSvc, networksSvc)
 	require.NoError(t, err)
 
 	tests := []struct {
@@ -308,7 +328,7 @@ func TestPreCheck(t *testing.T) {
 
 	lockerSvc, err := locker.New()
 	require.NoError(t, err)
-	fetcherSvc, err := memfetcher.New([]e2wtypes.Store{store})
+	fetcherSvc, err := memfetcher.New(context.Background(), []e2wtypes.Store{store})
 	require.NoError(t, err)
 	storageSvc, err := mem.New()
 	require.NoError(t, err)
@@ -325,7 +345,13 @@ func TestPreCheck(t *testing.T) {
 	checkerSvc, err := mockchecker.New()
 	require.NoError(t, err)
 
-	signerSvc, err := New(unlockerSvc, checkerSvc, fetcherSvc, rulerSvc)
+	relockerSvc, err := timedrelocker.New(context.Background(), nil)
+	require.NoError(t, err)
+
+	networksSvc, err := networks.New(context.Background(), nil, nil)
+	require.NoError(t, err)
+
+	signerSvc, err := New(unlockerSvc, relockerSvc, checkerSvc, fetcherSvc, rulerSvc, networksSvc)
 	require.NoError(t, err)
 
 	tests := []struct {
@@ -361,7 +387,14 @@ func TestPreCheck(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			_, _, res := signerSvc.preCheck(context.Background(), test.credentials, test.accountName, test.pubKey, test.action)
+			wallet, account, res := signerSvc.preCheck(context.Background(), test.credentials, test.accountName, test.pubKey, test.action)
+			if res == core.APPROVED {
+				var release func()
+				release, res = signerSvc.prepareAccount(context.Background(), wallet, account)
+				if release != nil {
+					release()
+				}
+			}
 			assert.Equal(t, test.res, res)
 		})
 	}
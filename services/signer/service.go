@@ -14,27 +14,75 @@
 package signer
 
 import (
+	"context"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+	"github.com/wealdtech/walletd/services/approvals"
 	"github.com/wealdtech/walletd/services/autounlocker"
 	"github.com/wealdtech/walletd/services/checker"
 	"github.com/wealdtech/walletd/services/fetcher"
+	"github.com/wealdtech/walletd/services/locker"
+	"github.com/wealdtech/walletd/services/networks"
+	"github.com/wealdtech/walletd/services/relocker"
 	"github.com/wealdtech/walletd/services/ruler"
 )
 
 // Service is the signer handler.
 type Service struct {
-	checker      checker.Service
-	fetcher      fetcher.Service
-	ruler        ruler.Service
-	autounlocker autounlocker.Service
+	allowedDomainTypes map[e2types.DomainType]bool
+	checker            checker.Service
+	fetcher            fetcher.Service
+	ruler              ruler.Service
+	autounlocker       autounlocker.Service
+	relocker           relocker.Service
+	networks           *networks.Service
+	approvals          *approvals.Service
+	signatures         *signatureCache
+	accountLocks       *locker.Service
+}
+
+// Option is an option for the signer.
+type Option func(*Service)
+
+// WithAllowedDomainTypes allows generic signing requests to sign with the given domain types, even if they are
+// reserved for beacon chain messages.
+func WithAllowedDomainTypes(domainTypes []e2types.DomainType) Option {
+	return func(s *Service) {
+		for _, domainType := range domainTypes {
+			s.allowedDomainTypes[domainType] = true
+		}
+	}
+}
+
+// WithApprovals sets the service that holds requests awaiting approval by an operator.
+func WithApprovals(approvals *approvals.Service) Option {
+	return func(s *Service) {
+		s.approvals = approvals
+	}
+}
+
+// WithSignatureCache sets the time for which signatures are cached, and the number of signatures cached for each
+// account.  Identical requests made within the window are given the cached signature without running the rules again.
+// A window or size of 0 disables the cache.
+func WithSignatureCache(window time.Duration, size int) Option {
+	return func(s *Service) {
+		s.signatures = newSignatureCache(window, size)
+	}
 }
 
 // New creates a new signer handler.
-func New(unlocker autounlocker.Service, checker checker.Service, fetcher fetcher.Service, ruler ruler.Service) (*Service, error) {
+func New(unlocker autounlocker.Service, relocker relocker.Service, checker checker.Service, fetcher fetcher.Service, ruler ruler.Service, networks *networks.Service, opts ...Option) (*Service, error) {
 	if unlocker == nil {
 		return nil, errors.New("no unlocker provided")
 	}
+	if relocker == nil {
+		return nil, errors.New("no relocker provided")
+	}
 	if checker == nil {
 		return nil, errors.New("no checker provided")
 	}
@@ -44,11 +92,55 @@ func New(unlocker autounlocker.Service, checker checker.Service, fetcher fetcher
 	if ruler == nil {
 		return nil, errors.New("no ruler provided")
 	}
+	if networks == nil {
+		return nil, errors.New("no networks provided")
+	}
+
+	s := &Service{
+		allowedDomainTypes: make(map[e2types.DomainType]bool),
+		autounlocker:       unlocker,
+		relocker:           relocker,
+		checker:            checker,
+		fetcher:            fetcher,
+		ruler:              ruler,
+		networks:           networks,
+		signatures:         newSignatureCache(defaultSignatureCacheWindow, defaultSignatureCacheSize),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.allowedDomainTypes[e2types.DomainVoluntaryExit] {
+		return nil, errors.New("voluntary exits cannot be signed with generic signing requests")
+	}
+	var err error
+	if s.approvals == nil {
+		s.approvals, err = approvals.New(context.Background(), nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// These locks are separate from those of the ruler, which are taken while they are held.
+	s.accountLocks, err = locker.New()
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// ParseDomainType parses a domain type from a hex string.
+func ParseDomainType(input string) (e2types.DomainType, error) {
+	var domainType e2types.DomainType
+	data, err := hex.DecodeString(strings.TrimPrefix(input, "0x"))
+	if err != nil || len(data) != len(domainType) {
+		return domainType, fmt.Errorf("invalid domain type %q", input)
+	}
+	copy(domainType[:], data)
+	return domainType, nil
+}
 
-	return &Service{
-		autounlocker: unlocker,
-		checker:      checker,
-		fetcher:      fetcher,
-		ruler:        ruler,
-	}, nil
+// reservedDomainType returns true if the domain type is reserved for beacon chain messages.
+// The beacon chain reserves domain types 0x00000000 to 0x0a000000.
+func reservedDomainType(domainType e2types.DomainType) bool {
+	return domainType[0] <= 0x0a && domainType[1] == 0x00 && domainType[2] == 0x00 && domainType[3] == 0x00
 }
@@ -33,6 +33,9 @@ import (
 	"github.com/wealdtech/walletd/services/fetcher"
 	"github.com/wealdtech/walletd/services/fetcher/memfetcher"
 	"github.com/wealdtech/walletd/services/locker"
+	"github.com/wealdtech/walletd/services/networks"
+	"github.com/wealdtech/walletd/services/relocker"
+	timedrelocker "github.com/wealdtech/walletd/services/relocker/timed"
 	"github.com/wealdtech/walletd/services/ruler"
 	"github.com/wealdtech/walletd/services/ruler/lua"
 	"github.com/wealdtech/walletd/services/signer"
@@ -66,7 +69,7 @@ func TestService(t *testing.T) {
 
 	lockerSvc, err := locker.New()
 	require.NoError(t, err)
-	fetcherSvc, err := memfetcher.New([]e2wtypes.Store{store})
+	fetcherSvc, err := memfetcher.New(context.Background(), []e2wtypes.Store{store})
 	require.NoError(t, err)
 	storageSvc, err := mem.New()
 	require.NoError(t, err)
@@ -80,51 +83,90 @@ func TestService(t *testing.T) {
 	unlockerSvc, err := keysunlocker.New(context.Background(), keysConfig)
 	require.NoError(t, err)
 
+	relockerSvc, err := timedrelocker.New(context.Background(), nil)
+	require.NoError(t, err)
+
 	checkerSvc, err := mockchecker.New()
 	require.NoError(t, err)
 
+	networksSvc, err := networks.New(context.Background(), nil, nil)
+	require.NoError(t, err)
+
 	tests := []struct {
 		name     string
 		unlocker autounlocker.Service
+		relocker relocker.Service
 		checker  checker.Service
 		fetcher  fetcher.Service
 		ruler    ruler.Service
+		networks *networks.Service
+		opts     []signer.Option
 		err      string
 	}{
 		{
 			name: "Empty",
 			err:  "no unlocker provided",
 		},
+		{
+			name:     "NoRelocker",
+			unlocker: unlockerSvc,
+			err:      "no relocker provided",
+		},
 		{
 			name:     "NoChecker",
 			unlocker: unlockerSvc,
+			relocker: relockerSvc,
 			err:      "no checker provided",
 		},
 		{
 			name:     "NoFetcher",
 			unlocker: unlockerSvc,
+			relocker: relockerSvc,
 			checker:  checkerSvc,
 			err:      "no fetcher provided",
 		},
 		{
 			name:     "NoRuler",
 			unlocker: unlockerSvc,
+			relocker: relockerSvc,
 			checker:  checkerSvc,
 			fetcher:  fetcherSvc,
 			err:      "no ruler provided",
 		},
+		{
+			name:     "NoNetworks",
+			unlocker: unlockerSvc,
+			relocker: relockerSvc,
+			checker:  checkerSvc,
+			fetcher:  fetcherSvc,
+			ruler:    rulerSvc,
+			err:      "no networks provided",
+		},
+		{
+			name:     "VoluntaryExitDomainAllowed",
+			unlocker: unlockerSvc,
+			relocker: relockerSvc,
+			checker:  checkerSvc,
+			fetcher:  fetcherSvc,
+			ruler:    rulerSvc,
+			networks: networksSvc,
+			opts:     []signer.Option{signer.WithAllowedDomainTypes([]e2types.DomainType{e2types.DomainVoluntaryExit})},
+			err:      "voluntary exits cannot be signed with generic signing requests",
+		},
 		{
 			name:     "Good",
 			unlocker: unlockerSvc,
+			relocker: relockerSvc,
 			checker:  checkerSvc,
 			fetcher:  fetcherSvc,
 			ruler:    rulerSvc,
+			networks: networksSvc,
 		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			_, err := signer.New(test.unlocker, test.checker, test.fetcher, test.ruler)
+			_, err := signer.New(test.unlocker, test.relocker, test.checker, test.fetcher, test.ruler, test.networks, test.opts...)
 			if test.err == "" {
 				assert.NoError(t, err)
 			} else {
@@ -133,3 +175,49 @@ func TestService(t *testing.T) {
 		})
 	}
 }
+
+func TestParseDomainType(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		res   e2types.DomainType
+		err   string
+	}{
+		{
+			name: "Empty",
+			err:  `invalid domain type ""`,
+		},
+		{
+			name:  "Short",
+			input: "0x0300",
+			err:   `invalid domain type "0x0300"`,
+		},
+		{
+			name:  "Invalid",
+			input: "0xzz000000",
+			err:   `invalid domain type "0xzz000000"`,
+		},
+		{
+			name:  "Good",
+			input: "0x03000000",
+			res:   e2types.DomainDeposit,
+		},
+		{
+			name:  "NoPrefix",
+			input: "04000000",
+			res:   e2types.DomainVoluntaryExit,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := signer.ParseDomainType(test.input)
+			if test.err == "" {
+				require.NoError(t, err)
+				assert.Equal(t, test.res, res)
+			} else {
+				assert.EqualError(t, err, test.err)
+			}
+		})
+	}
+}
@@ -20,52 +20,37 @@ import (
 	"github.com/opentracing/opentracing-go"
 	"github.com/wealdtech/walletd/core"
 	"github.com/wealdtech/walletd/services/checker"
+	"github.com/wealdtech/walletd/services/networks"
 	"github.com/wealdtech/walletd/services/ruler"
 )
 
 // Sign signs generic data.
-func (s *Service) Sign(ctx context.Context, credentials *checker.Credentials, accountName string, pubKey []byte, data *ruler.SignData) (core.RulesResult, []byte) {
+func (s *Service) Sign(ctx context.Context, credentials *checker.Credentials, accountName string, pubKey []byte, data *ruler.SignData) (core.RulesResult, []byte, string) {
 	span, ctx := opentracing.StartSpanFromContext(ctx, "service.signer.Sign")
 	defer span.Finish()
 	log := log.With().Str("action", "Sign").Logger()
 	log.Debug().Msg("Request received")
 
 	if data == nil {
-		return core.DENIED, nil
+		return core.DENIED, nil, ""
 	}
-	wallet, account, checkRes := s.preCheck(ctx, credentials, accountName, pubKey, ruler.ActionSign)
-	if checkRes != core.APPROVED {
-		return checkRes, nil
+	if len(data.Domain) != 32 {
+		log.Debug().Str("result", "denied").Msg("Domain must be 32 bytes")
+		return core.DENIED, nil, ""
 	}
-	accountName = fmt.Sprintf("%s/%s", wallet.Name(), account.Name())
-	log = log.With().Str("account", accountName).Logger()
-
-	// Confirm approval via rules.
-	result := s.ruler.RunRules(ctx, ruler.ActionSign, wallet.Name(), account.Name(), account.PublicKey().Marshal(), data)
-	switch result {
-	case core.DENIED:
-		log.Debug().Str("result", "denied").Msg("Denied by rules")
-		return core.DENIED, nil
-	case core.FAILED:
-		log.Warn().Str("result", "failed").Msg("Rules check failed")
-		return core.FAILED, nil
+	domainType := networks.DomainType(data.Domain)
+	if reservedDomainType(domainType) && !s.allowedDomainTypes[domainType] {
+		log.Warn().Str("domain_type", fmt.Sprintf("%#x", domainType[:])).Str("result", "denied").Msg("Domain type is reserved for beacon chain messages; use the specific signing request")
+		return core.DENIED, nil, ""
+	}
+	if checkRes := s.checkDomain(ctx, credentials, domainType, data.Domain); checkRes != core.APPROVED {
+		return checkRes, nil, ""
 	}
-
-	// Sign it.
 	signingRoot, err := generateSigningRootFromRoot(ctx, data.Data, data.Domain)
 	if err != nil {
 		log.Warn().Err(err).Str("result", "failed").Msg("Failed to generate signing root")
-		return core.FAILED, nil
-	}
-	span, _ = opentracing.StartSpanFromContext(ctx, "service.signer.Sign/Sign")
-	signature, err := account.Sign(signingRoot[:])
-	if err != nil {
-		log.Warn().Err(err).Str("result", "failed").Msg("Failed to sign")
-		span.Finish()
-		return core.FAILED, nil
+		return core.FAILED, nil, ""
 	}
-	span.Finish()
 
-	log.Debug().Str("result", "succeeded").Msg("Success")
-	return core.APPROVED, signature.Marshal()
+	return s.approveAndSign(ctx, credentials, accountName, pubKey, ruler.ActionSign, data, signingRoot, false)
 }
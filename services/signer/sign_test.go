@@ -14,22 +14,35 @@
 package signer_test
 
 import (
+	"bytes"
 	context "context"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/shibukawa/configdir"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
 	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
 	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
 	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
 	e2wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
 	"github.com/wealdtech/walletd/core"
+	"github.com/wealdtech/walletd/interceptors"
+	"github.com/wealdtech/walletd/services/approvals"
+	"github.com/wealdtech/walletd/services/autounlocker"
 	keysunlocker "github.com/wealdtech/walletd/services/autounlocker/keys"
 	"github.com/wealdtech/walletd/services/checker"
 	mockchecker "github.com/wealdtech/walletd/services/checker/mock"
 	"github.com/wealdtech/walletd/services/fetcher/memfetcher"
 	"github.com/wealdtech/walletd/services/locker"
+	"github.com/wealdtech/walletd/services/networks"
+	timedrelocker "github.com/wealdtech/walletd/services/relocker/timed"
 	"github.com/wealdtech/walletd/services/ruler"
 	"github.com/wealdtech/walletd/services/ruler/lua"
 	"github.com/wealdtech/walletd/services/signer"
@@ -60,7 +73,7 @@ func TestSign(t *testing.T) {
 
 	lockerSvc, err := locker.New()
 	require.NoError(t, err)
-	fetcherSvc, err := memfetcher.New([]e2wtypes.Store{store})
+	fetcherSvc, err := memfetcher.New(context.Background(), []e2wtypes.Store{store})
 	require.NoError(t, err)
 	storageSvc, err := mem.New()
 	require.NoError(t, err)
@@ -78,7 +91,13 @@ func TestSign(t *testing.T) {
 	checkerSvc, err := mockchecker.New()
 	require.NoError(t, err)
 
-	signerSvc, err := signer.New(unlockerSvc, checkerSvc, fetcherSvc, rulerSvc)
+	relockerSvc, err := timedrelocker.New(context.Background(), nil)
+	require.NoError(t, err)
+
+	networksSvc, err := networks.New(context.Background(), nil, nil)
+	require.NoError(t, err)
+
+	signerSvc, err := signer.New(unlockerSvc, relockerSvc, checkerSvc, fetcherSvc, rulerSvc, networksSvc, signer.WithAllowedDomainTypes([]e2types.DomainType{e2types.DomainDeposit}))
 	require.NoError(t, err)
 
 	tests := []struct {
@@ -95,22 +114,549 @@ func TestSign(t *testing.T) {
 		},
 		{
 			name: "FailPreCheck",
-			data: &ruler.SignData{},
+			data: &ruler.SignData{Domain: domain(0x80)},
 			res:  core.DENIED,
 		},
 		{
-			name:        "Good",
+			name:        "NoDomain",
 			data:        &ruler.SignData{},
 			credentials: &checker.Credentials{Client: "client1"},
 			accountName: "Test wallet/Test account 1",
+			res:         core.DENIED,
+		},
+		{
+			name:        "ShortDomain",
+			data:        &ruler.SignData{Domain: []byte{0x80, 0x00, 0x00, 0x00}},
+			credentials: &checker.Credentials{Client: "client1"},
+			accountName: "Test wallet/Test account 1",
+			res:         core.DENIED,
+		},
+		{
+			name:        "BeaconProposerDomain",
+			data:        &ruler.SignData{Domain: domain(0x00)},
+			credentials: &checker.Credentials{Client: "client1"},
+			accountName: "Test wallet/Test account 1",
+			res:         core.DENIED,
+		},
+		{
+			name:        "BeaconAttesterDomain",
+			data:        &ruler.SignData{Domain: domain(0x01)},
+			credentials: &checker.Credentials{Client: "client1"},
+			accountName: "Test wallet/Test account 1",
+			res:         core.DENIED,
+		},
+		{
+			name:        "RANDAODomain",
+			data:        &ruler.SignData{Domain: domain(0x02)},
+			credentials: &checker.Credentials{Client: "client1"},
+			accountName: "Test wallet/Test account 1",
+			res:         core.DENIED,
+		},
+		{
+			name:        "AllowedDomain",
+			data:        &ruler.SignData{Domain: domain(0x03)},
+			credentials: &checker.Credentials{Client: "client1"},
+			accountName: "Test wallet/Test account 1",
+			res:         core.APPROVED,
+		},
+		{
+			name:        "Good",
+			data:        &ruler.SignData{Domain: domain(0x80)},
+			credentials: &checker.Credentials{Client: "client1"},
+			accountName: "Test wallet/Test account 1",
 			res:         core.APPROVED,
 		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			res, _ := signerSvc.Sign(context.Background(), test.credentials, test.accountName, test.pubKey, test.data)
+			res, _, _ := signerSvc.Sign(context.Background(), test.credentials, test.accountName, test.pubKey, test.data)
+			assert.Equal(t, test.res, res)
+		})
+	}
+}
+
+// domain returns a domain with the given first byte of domain type.
+func domain(domainType byte) []byte {
+	res := make([]byte, 32)
+	res[0] = domainType
+	return res
+}
+
+func TestSignNetworks(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+
+	wallet, err := hd.CreateWallet("Test wallet", []byte("secret"), store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock([]byte("secret")))
+	_, err = wallet.CreateAccount("Test account 1", []byte("Test account 1 passphrase"))
+	require.NoError(t, err)
+	wallet.Lock()
+
+	lockerSvc, err := locker.New()
+	require.NoError(t, err)
+	fetcherSvc, err := memfetcher.New(context.Background(), []e2wtypes.Store{store})
+	require.NoError(t, err)
+	storageSvc, err := mem.New()
+	require.NoError(t, err)
+	rulerSvc, err := lua.New(lockerSvc, storageSvc, []*core.Rule{{}})
+	require.NoError(t, err)
+	unlockerSvc, err := keysunlocker.New(context.Background(), &core.KeysConfig{
+		Keys: []string{"Test account 1 passphrase"},
+	})
+	require.NoError(t, err)
+	checkerSvc, err := mockchecker.New()
+	require.NoError(t, err)
+	relockerSvc, err := timedrelocker.New(context.Background(), nil)
+	require.NoError(t, err)
+	genesisValidatorsRoot := bytes.Repeat([]byte{0x01}, 32)
+	networksSvc, err := networks.New(context.Background(), []*core.NetworkConfig{
+		{
+			Name:                  "mainnet",
+			GenesisValidatorsRoot: fmt.Sprintf("%#x", genesisValidatorsRoot),
+			ForkVersions:          []string{"0x00000000"},
+		},
+	}, nil)
+	require.NoError(t, err)
+
+	signerSvc, err := signer.New(unlockerSvc, relockerSvc, checkerSvc, fetcherSvc, rulerSvc, networksSvc)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		domain []byte
+		res    core.RulesResult
+	}{
+		{
+			name: "NoDomain",
+			res:  core.DENIED,
+		},
+		{
+			name:   "UnknownFork",
+			domain: e2types.Domain(e2types.DomainType{0x80, 0x00, 0x00, 0x00}, []byte{0x01, 0x00, 0x00, 0x00}, genesisValidatorsRoot),
+			res:    core.DENIED,
+		},
+		{
+			name:   "UnknownNetwork",
+			domain: e2types.Domain(e2types.DomainType{0x80, 0x00, 0x00, 0x00}, []byte{0x00, 0x00, 0x00, 0x00}, bytes.Repeat([]byte{0x02}, 32)),
+			res:    core.DENIED,
+		},
+		{
+			name:   "Good",
+			domain: e2types.Domain(e2types.DomainType{0x80, 0x00, 0x00, 0x00}, []byte{0x00, 0x00, 0x00, 0x00}, genesisValidatorsRoot),
+			res:    core.APPROVED,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := &ruler.SignData{
+				Domain: test.domain,
+				Data:   bytes.Repeat([]byte{0x03}, 32),
+			}
+			res, _, _ := signerSvc.Sign(context.Background(), &checker.Credentials{Client: "client1"}, "Test wallet/Test account 1", nil, data)
 			assert.Equal(t, test.res, res)
 		})
 	}
 }
+
+// TestSignPending confirms that a signing request that a rule marks as pending is signed only once it has been
+// approved by the required number of operators.
+func TestSignPending(t *testing.T) {
+	configDirs := configdir.New("wealdtech", "walletd")
+	scriptFile := filepath.Join(configDirs.QueryFolders(configdir.Global)[0].Path, "scripts", "sign_pending.lua")
+	defer os.Remove(scriptFile)
+	require.NoError(t, ioutil.WriteFile(scriptFile, []byte(`function approve(request, storage, messages)
+  return "Pending"
+end`), 0644))
+
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("Test wallet", []byte("secret"), store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock([]byte("secret")))
+	_, err = wallet.CreateAccount("Test account 1", []byte("Test account 1 passphrase"))
+	require.NoError(t, err)
+	wallet.Lock()
+
+	lockerSvc, err := locker.New()
+	require.NoError(t, err)
+	fetcherSvc, err := memfetcher.New(context.Background(), []e2wtypes.Store{store})
+	require.NoError(t, err)
+	storageSvc, err := mem.New()
+	require.NoError(t, err)
+	rules, err := core.InitRules(context.Background(), []*core.RuleDefinition{
+		{
+			Name:    "pending",
+			Request: ruler.ActionSign,
+			Script:  "sign_pending.lua",
+		},
+	})
+	require.NoError(t, err)
+	rulerSvc, err := lua.New(lockerSvc, storageSvc, rules)
+	require.NoError(t, err)
+	unlockerSvc, err := keysunlocker.New(context.Background(), &core.KeysConfig{
+		Keys: []string{"Test account 1 passphrase"},
+	})
+	require.NoError(t, err)
+	checkerSvc, err := mockchecker.New()
+	require.NoError(t, err)
+	relockerSvc, err := timedrelocker.New(context.Background(), nil)
+	require.NoError(t, err)
+	networksSvc, err := networks.New(context.Background(), nil, nil)
+	require.NoError(t, err)
+	approvalsSvc, err := approvals.New(context.Background(), &core.ApprovalsConfig{Required: 2})
+	require.NoError(t, err)
+
+	signerSvc, err := signer.New(unlockerSvc, relockerSvc, checkerSvc, fetcherSvc, rulerSvc, networksSvc, signer.WithApprovals(approvalsSvc))
+	require.NoError(t, err)
+
+	credentials := &checker.Credentials{Client: "client1"}
+	data := &ruler.SignData{
+		Domain: domain(0x80),
+		Data:   bytes.Repeat([]byte{0x03}, 32),
+	}
+
+	res, signature, id := signerSvc.Sign(context.Background(), credentials, "Test wallet/Test account 1", nil, data)
+	require.Equal(t, core.PENDING, res)
+	require.Nil(t, signature)
+	require.NotEmpty(t, id)
+	ctx := context.WithValue(context.Background(), &interceptors.ApprovalID{}, id)
+
+	// A single approval is not sufficient.
+	_, err = approvalsSvc.Approve(id, &checker.Credentials{Client: "operator1"})
+	require.NoError(t, err)
+	res, _, _ = signerSvc.Sign(ctx, credentials, "Test wallet/Test account 1", nil, data)
+	require.Equal(t, core.PENDING, res)
+
+	// A request with a deadline waits for the remaining approval.
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		_, err := approvalsSvc.Approve(id, &checker.Credentials{Client: "operator2"})
+		assert.NoError(t, err)
+	}()
+	waitCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	res, signature, _ = signerSvc.Sign(waitCtx, credentials, "Test wallet/Test account 1", nil, data)
+	require.Equal(t, core.APPROVED, res)
+	require.Len(t, signature, 96)
+
+	// The approval cannot be reused.
+	res, _, _ = signerSvc.Sign(ctx, credentials, "Test wallet/Test account 1", nil, data)
+	require.Equal(t, core.DENIED, res)
+
+	// A rejected request is denied.
+	res, _, id = signerSvc.Sign(context.Background(), credentials, "Test wallet/Test account 1", nil, data)
+	require.Equal(t, core.PENDING, res)
+	_, err = approvalsSvc.Reject(id, &checker.Credentials{Client: "operator1"})
+	require.NoError(t, err)
+	res, _, _ = signerSvc.Sign(context.WithValue(context.Background(), &interceptors.ApprovalID{}, id), credentials, "Test wallet/Test account 1", nil, data)
+	require.Equal(t, core.DENIED, res)
+}
+
+// TestSignRetry confirms that an identical request is given the same signature without the rules being run again.
+func TestSignRetry(t *testing.T) {
+	configDirs := configdir.New("wealdtech", "walletd")
+	scriptFile := filepath.Join(configDirs.QueryFolders(configdir.Global)[0].Path, "scripts", "sign_once.lua")
+	defer os.Remove(scriptFile)
+	require.NoError(t, ioutil.WriteFile(scriptFile, []byte(`function approve(request, storage, messages)
+  if storage.signed ~= nil then
+    return "Denied"
+  end
+  storage.signed = 1
+  return "Approved"
+end`), 0644))
+
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("Test wallet", []byte("secret"), store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock([]byte("secret")))
+	_, err = wallet.CreateAccount("Test account 1", []byte("Test account 1 passphrase"))
+	require.NoError(t, err)
+	wallet.Lock()
+
+	lockerSvc, err := locker.New()
+	require.NoError(t, err)
+	fetcherSvc, err := memfetcher.New(context.Background(), []e2wtypes.Store{store})
+	require.NoError(t, err)
+	storageSvc, err := mem.New()
+	require.NoError(t, err)
+	rules, err := core.InitRules(context.Background(), []*core.RuleDefinition{
+		{
+			Name:    "once",
+			Request: ruler.ActionSign,
+			Script:  "sign_once.lua",
+		},
+	})
+	require.NoError(t, err)
+	rulerSvc, err := lua.New(lockerSvc, storageSvc, rules)
+	require.NoError(t, err)
+	unlockerSvc, err := keysunlocker.New(context.Background(), &core.KeysConfig{
+		Keys: []string{"Test account 1 passphrase"},
+	})
+	require.NoError(t, err)
+	checkerSvc, err := mockchecker.New()
+	require.NoError(t, err)
+	relockerSvc, err := timedrelocker.New(context.Background(), nil)
+	require.NoError(t, err)
+	networksSvc, err := networks.New(context.Background(), nil, nil)
+	require.NoError(t, err)
+
+	signerSvc, err := signer.New(unlockerSvc, relockerSvc, checkerSvc, fetcherSvc, rulerSvc, networksSvc)
+	require.NoError(t, err)
+	uncachedSignerSvc, err := signer.New(unlockerSvc, relockerSvc, checkerSvc, fetcherSvc, rulerSvc, networksSvc, signer.WithSignatureCache(0, 0))
+	require.NoError(t, err)
+
+	data := &ruler.SignData{
+		Domain: domain(0x80),
+		Data:   bytes.Repeat([]byte{0x03}, 32),
+	}
+	res, signature, _ := signerSvc.Sign(context.Background(), &checker.Credentials{Client: "client1"}, "Test wallet/Test account 1", nil, data)
+	require.Equal(t, core.APPROVED, res)
+
+	// An identical request returns the same signature.
+	res, retrySignature, _ := signerSvc.Sign(context.Background(), &checker.Credentials{Client: "client1"}, "Test wallet/Test account 1", nil, data)
+	require.Equal(t, core.APPROVED, res)
+	assert.Equal(t, signature, retrySignature)
+
+	// Permissions are still checked.
+	res, _, _ = signerSvc.Sign(context.Background(), &checker.Credentials{Client: "Deny client"}, "Test wallet/Test account 1", nil, data)
+	require.Equal(t, core.DENIED, res)
+
+	// Different data runs the rules.
+	res, _, _ = signerSvc.Sign(context.Background(), &checker.Credentials{Client: "client1"}, "Test wallet/Test account 1", nil, &ruler.SignData{
+		Domain: domain(0x80),
+		Data:   bytes.Repeat([]byte{0x04}, 32),
+	})
+	require.Equal(t, core.DENIED, res)
+
+	// Without the cache, an identical request runs the rules.
+	res, _, _ = uncachedSignerSvc.Sign(context.Background(), &checker.Credentials{Client: "client1"}, "Test wallet/Test account 1", nil, data)
+	require.Equal(t, core.DENIED, res)
+}
+
+// countingUnlocker counts attempts to unlock accounts.
+type countingUnlocker struct {
+	autounlocker.Service
+	unlocks int32
+}
+
+func (u *countingUnlocker) Unlock(ctx context.Context, wallet e2wtypes.Wallet, account e2wtypes.Account) (bool, error) {
+	atomic.AddInt32(&u.unlocks, 1)
+	return u.Service.Unlock(ctx, wallet, account)
+}
+
+// pausingRuler signals when the rules start to run, and waits to be allowed to proceed before running them.
+type pausingRuler struct {
+	ruler.Service
+	runs    int32
+	started chan struct{}
+	proceed chan struct{}
+}
+
+func (r *pausingRuler) RunRules(ctx context.Context, action string, walletName string, accountName string, accountPubKey []byte, req interface{}) core.RulesResult {
+	if atomic.AddInt32(&r.runs, 1) == 1 {
+		close(r.started)
+	}
+	<-r.proceed
+	return r.Service.RunRules(ctx, action, walletName, accountName, accountPubKey, req)
+}
+
+func TestSignRetryInFlight(t *testing.T) {
+	configDirs := configdir.New("wealdtech", "walletd")
+	scriptFile := filepath.Join(configDirs.QueryFolders(configdir.Global)[0].Path, "scripts", "inflight_sign_once.lua")
+	defer os.Remove(scriptFile)
+	require.NoError(t, ioutil.WriteFile(scriptFile, []byte(`function approve(request, storage, messages)
+  if storage.signed ~= nil then
+    return "Denied"
+  end
+  storage.signed = 1
+  return "Approved"
+end`), 0644))
+
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("Test wallet", []byte("secret"), store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock([]byte("secret")))
+	_, err = wallet.CreateAccount("Test account 1", []byte("Test account 1 passphrase"))
+	require.NoError(t, err)
+	wallet.Lock()
+
+	lockerSvc, err := locker.New()
+	require.NoError(t, err)
+	fetcherSvc, err := memfetcher.New(context.Background(), []e2wtypes.Store{store})
+	require.NoError(t, err)
+	storageSvc, err := mem.New()
+	require.NoError(t, err)
+	rules, err := core.InitRules(context.Background(), []*core.RuleDefinition{
+		{
+			Name:    "once",
+			Request: ruler.ActionSign,
+			Script:  "inflight_sign_once.lua",
+		},
+	})
+	require.NoError(t, err)
+	luaRulerSvc, err := lua.New(lockerSvc, storageSvc, rules)
+	require.NoError(t, err)
+	rulerSvc := &pausingRuler{
+		Service: luaRulerSvc,
+		started: make(chan struct{}),
+		proceed: make(chan struct{}),
+	}
+	keysUnlockerSvc, err := keysunlocker.New(context.Background(), &core.KeysConfig{
+		Keys: []string{"Test account 1 passphrase"},
+	})
+	require.NoError(t, err)
+	unlockerSvc := &countingUnlocker{Service: keysUnlockerSvc}
+	checkerSvc, err := mockchecker.New()
+	require.NoError(t, err)
+	relockerSvc, err := timedrelocker.New(context.Background(), nil)
+	require.NoError(t, err)
+	networksSvc, err := networks.New(context.Background(), nil, nil)
+	require.NoError(t, err)
+
+	signerSvc, err := signer.New(unlockerSvc, relockerSvc, checkerSvc, fetcherSvc, rulerSvc, networksSvc)
+	require.NoError(t, err)
+
+	credentials := &checker.Credentials{Client: "client1"}
+	data := &ruler.SignData{
+		Domain: domain(0x80),
+		Data:   bytes.Repeat([]byte{0x03}, 32),
+	}
+
+	// A retry that arrives while the original request is running its rules is given the same signature.
+	type response struct {
+		res       core.RulesResult
+		signature []byte
+	}
+	responses := make(chan *response, 2)
+	sign := func() {
+		res, signature, _ := signerSvc.Sign(context.Background(), credentials, "Test wallet/Test account 1", nil, data)
+		responses <- &response{res: res, signature: signature}
+	}
+	go sign()
+	<-rulerSvc.started
+	go sign()
+	time.Sleep(50 * time.Millisecond)
+	close(rulerSvc.proceed)
+	original := <-responses
+	retry := <-responses
+	require.Equal(t, core.APPROVED, original.res)
+	require.Equal(t, core.APPROVED, retry.res)
+	assert.Equal(t, original.signature, retry.signature)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&rulerSvc.runs))
+
+	// A retry of a signed request does not need the account to be unlocked.
+	unlocks := atomic.LoadInt32(&unlockerSvc.unlocks)
+	_, fetchedAccount, err := fetcherSvc.FetchAccount(context.Background(), "Test wallet/Test account 1")
+	require.NoError(t, err)
+	fetchedAccount.Lock()
+	res, signature, _ := signerSvc.Sign(context.Background(), credentials, "Test wallet/Test account 1", nil, data)
+	require.Equal(t, core.APPROVED, res)
+	assert.Equal(t, original.signature, signature)
+	assert.Equal(t, unlocks, atomic.LoadInt32(&unlockerSvc.unlocks))
+}
+
+// cancellingRuler cancels the request once the rules have run.
+type cancellingRuler struct {
+	ruler.Service
+	cancel context.CancelFunc
+}
+
+func (r *cancellingRuler) RunRules(ctx context.Context, action string, walletName string, accountName string, accountPubKey []byte, req interface{}) core.RulesResult {
+	result := r.Service.RunRules(ctx, action, walletName, accountName, accountPubKey, req)
+	r.cancel()
+	return result
+}
+
+func TestSignExpired(t *testing.T) {
+	configDirs := configdir.New("wealdtech", "walletd")
+	scriptFile := filepath.Join(configDirs.QueryFolders(configdir.Global)[0].Path, "scripts", "expire_sign_once.lua")
+	defer os.Remove(scriptFile)
+	require.NoError(t, ioutil.WriteFile(scriptFile, []byte(`function approve(request, storage, messages)
+  if storage.signed ~= nil then
+    return "Denied"
+  end
+  storage.signed = 1
+  return "Approved"
+end`), 0644))
+
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("Test wallet", []byte("secret"), store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock([]byte("secret")))
+	account, err := wallet.CreateAccount("Test account 1", []byte("Test account 1 passphrase"))
+	require.NoError(t, err)
+	wallet.Lock()
+
+	lockerSvc, err := locker.New()
+	require.NoError(t, err)
+	fetcherSvc, err := memfetcher.New(context.Background(), []e2wtypes.Store{store})
+	require.NoError(t, err)
+	storageSvc, err := mem.New()
+	require.NoError(t, err)
+	rules, err := core.InitRules(context.Background(), []*core.RuleDefinition{
+		{
+			Name:    "once",
+			Request: ruler.ActionSign,
+			Script:  "expire_sign_once.lua",
+		},
+	})
+	require.NoError(t, err)
+	rulerSvc, err := lua.New(lockerSvc, storageSvc, rules)
+	require.NoError(t, err)
+	unlockerSvc, err := keysunlocker.New(context.Background(), &core.KeysConfig{
+		Keys: []string{"Test account 1 passphrase"},
+	})
+	require.NoError(t, err)
+	checkerSvc, err := mockchecker.New()
+	require.NoError(t, err)
+	relockerSvc, err := timedrelocker.New(context.Background(), nil)
+	require.NoError(t, err)
+	networksSvc, err := networks.New(context.Background(), nil, nil)
+	require.NoError(t, err)
+
+	signerSvc, err := signer.New(unlockerSvc, relockerSvc, checkerSvc, fetcherSvc, rulerSvc, networksSvc)
+	require.NoError(t, err)
+
+	credentials := &checker.Credentials{Client: "client1"}
+	data := &ruler.SignData{
+		Domain: domain(0x80),
+		Data:   bytes.Repeat([]byte{0x03}, 32),
+	}
+
+	// Request cancelled before it is received.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res, signature, _ := signerSvc.Sign(ctx, credentials, "Test wallet/Test account 1", nil, data)
+	require.Equal(t, core.EXPIRED, res)
+	require.Nil(t, signature)
+
+	// Deadline passes while another request for the account holds the lock.
+	var lockKey [48]byte
+	copy(lockKey[:], account.PublicKey().Marshal())
+	lockerSvc.Lock(lockKey)
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	res, signature, _ = signerSvc.Sign(ctx, credentials, "Test wallet/Test account 1", nil, data)
+	lockerSvc.Unlock(lockKey)
+	require.Equal(t, core.EXPIRED, res)
+	require.Nil(t, signature)
+
+	// Request cancelled once the rules have approved it; the rules have updated their state, so the signature is
+	// returned.
+	ctx, cancel = context.WithCancel(context.Background())
+	cancellingSignerSvc, err := signer.New(unlockerSvc, relockerSvc, checkerSvc, fetcherSvc, &cancellingRuler{Service: rulerSvc, cancel: cancel}, networksSvc, signer.WithSignatureCache(0, 0))
+	require.NoError(t, err)
+	res, signature, _ = cancellingSignerSvc.Sign(ctx, credentials, "Test wallet/Test account 1", nil, data)
+	require.Equal(t, core.APPROVED, res)
+	require.NotNil(t, signature)
+
+	// The rules approved the request only once, so the expired requests did not update state.
+	res, _, _ = signerSvc.Sign(context.Background(), credentials, "Test wallet/Test account 1", nil, data)
+	require.Equal(t, core.DENIED, res)
+}
@@ -0,0 +1,83 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signer
+
+import (
+	context "context"
+
+	"github.com/opentracing/opentracing-go"
+	bitfield "github.com/prysmaticlabs/go-bitfield"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+	"github.com/wealdtech/walletd/core"
+	"github.com/wealdtech/walletd/services/checker"
+	"github.com/wealdtech/walletd/services/ruler"
+)
+
+// domainAggregateAndProof is the domain type for aggregate and proofs.
+// The upstream constant is a byte slice, so it is redefined here as a domain type.
+var domainAggregateAndProof = e2types.DomainType{0x06, 0x00, 0x00, 0x00}
+
+// Attestation is a copy of the Ethereum 2 Attestation struct with SSZ size information.
+type Attestation struct {
+	AggregationBits bitfield.Bitlist `ssz-max:"2048"`
+	Data            *BeaconAttestation
+	Signature       []byte `ssz-size:"96"`
+}
+
+// AggregateAndProof is a copy of the Ethereum 2 AggregateAndProof struct with SSZ size information.
+type AggregateAndProof struct {
+	AggregatorIndex uint64
+	Aggregate       *Attestation
+	SelectionProof  []byte `ssz-size:"96"`
+}
+
+// SignAggregateAndProof signs an aggregate attestation and its selection proof.
+func (s *Service) SignAggregateAndProof(ctx context.Context, credentials *checker.Credentials, accountName string, pubKey []byte, data *ruler.SignAggregateAndProofData) (core.RulesResult, []byte, string) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "services.signer.SignAggregateAndProof")
+	defer span.Finish()
+	log.Debug().Str("action", "SignAggregateAndProof").Msg("Request received")
+
+	if data == nil ||
+		data.Aggregate == nil ||
+		data.Aggregate.Data == nil ||
+		data.Aggregate.Data.Source == nil ||
+		data.Aggregate.Data.Target == nil {
+		return core.DENIED, nil, ""
+	}
+
+	// Create a local copy of the data; we need ssz size information to calculate the correct root.
+	aggregateAndProof := &AggregateAndProof{
+		AggregatorIndex: data.AggregatorIndex,
+		Aggregate: &Attestation{
+			AggregationBits: bitfield.Bitlist(data.Aggregate.AggregationBits),
+			Data: &BeaconAttestation{
+				Slot:            data.Aggregate.Data.Slot,
+				CommitteeIndex:  data.Aggregate.Data.CommitteeIndex,
+				BeaconBlockRoot: data.Aggregate.Data.BeaconBlockRoot,
+				Source: &Checkpoint{
+					Epoch: data.Aggregate.Data.Source.Epoch,
+					Root:  data.Aggregate.Data.Source.Root,
+				},
+				Target: &Checkpoint{
+					Epoch: data.Aggregate.Data.Target.Epoch,
+					Root:  data.Aggregate.Data.Target.Root,
+				},
+			},
+			Signature: data.Aggregate.Signature,
+		},
+		SelectionProof: data.SelectionProof,
+	}
+
+	return s.signObject(ctx, credentials, accountName, pubKey, ruler.ActionSignAggregateAndProof, domainAggregateAndProof, data.Domain, data, aggregateAndProof)
+}
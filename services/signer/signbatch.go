@@ -0,0 +1,132 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signer
+
+import (
+	context "context"
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/opentracing/opentracing-go"
+	e2wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+	"github.com/wealdtech/walletd/core"
+	"github.com/wealdtech/walletd/interceptors"
+	"github.com/wealdtech/walletd/services/checker"
+	"github.com/wealdtech/walletd/services/ruler"
+)
+
+// BatchAttestation is a request to sign a beacon attestation as part of a batch.
+type BatchAttestation struct {
+	AccountName string
+	PubKey      []byte
+	Data        *ruler.SignBeaconAttestationData
+}
+
+// BatchProposal is a request to sign a beacon proposal as part of a batch.
+type BatchProposal struct {
+	AccountName string
+	PubKey      []byte
+	Data        *ruler.SignBeaconProposalData
+}
+
+// BatchResult is the result of a single request in a batch.
+type BatchResult struct {
+	Result     core.RulesResult
+	Signature  []byte
+	ApprovalID string
+}
+
+// SignBatch signs a batch of beacon attestations and proposals.
+// Requests for different accounts are processed in parallel; requests for the same account are processed in the
+// order supplied, so that rules see them as they would individual requests.  Each request is handled independently,
+// so a request that is denied or fails does not affect the others.
+// Results are returned in the same order as the requests.
+func (s *Service) SignBatch(ctx context.Context, credentials *checker.Credentials, attestations []*BatchAttestation, proposals []*BatchProposal) ([]*BatchResult, []*BatchResult) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "services.signer.SignBatch")
+	defer span.Finish()
+	log.Debug().Int("attestations", len(attestations)).Int("proposals", len(proposals)).Msg("Batch request received")
+
+	// An approval ID supplied with the batch cannot identify a single request within it, so requests that await
+	// approval are matched by their contents instead.
+	ctx = context.WithValue(ctx, &interceptors.ApprovalID{}, "")
+
+	attestationResults := make([]*BatchResult, len(attestations))
+	proposalResults := make([]*BatchResult, len(proposals))
+
+	// Group the requests by account.
+	groups := make(map[string][]func())
+	order := make([]string, 0)
+	addItem := func(accountName string, pubKey []byte, item func()) {
+		key := s.batchKey(ctx, accountName, pubKey)
+		if _, exists := groups[key]; !exists {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], item)
+	}
+	for i := range attestations {
+		i := i
+		addItem(attestations[i].AccountName, attestations[i].PubKey, func() {
+			result, signature, approvalID := s.SignBeaconAttestation(ctx, credentials, attestations[i].AccountName, attestations[i].PubKey, attestations[i].Data)
+			attestationResults[i] = &BatchResult{Result: result, Signature: signature, ApprovalID: approvalID}
+		})
+	}
+	for i := range proposals {
+		i := i
+		addItem(proposals[i].AccountName, proposals[i].PubKey, func() {
+			result, signature, approvalID := s.SignBeaconProposal(ctx, credentials, proposals[i].AccountName, proposals[i].PubKey, proposals[i].Data)
+			proposalResults[i] = &BatchResult{Result: result, Signature: signature, ApprovalID: approvalID}
+		})
+	}
+
+	// Process each account's requests in parallel, limiting concurrency to the number of processors as signing is
+	// CPU-bound.
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+	var wg sync.WaitGroup
+	for _, key := range order {
+		wg.Add(1)
+		go func(items []func()) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			for _, item := range items {
+				item()
+			}
+		}(groups[key])
+	}
+	wg.Wait()
+
+	return attestationResults, proposalResults
+}
+
+// batchKey returns the key by which a request in a batch is grouped.
+// Requests are grouped by the public key of their account, so that requests referring to the same account by name and
+// by public key are processed in order.  Requests whose account cannot be found are grouped by their reference, and
+// fail when processed.
+func (s *Service) batchKey(ctx context.Context, accountName string, pubKey []byte) string {
+	var account e2wtypes.Account
+	var err error
+	if accountName != "" {
+		_, account, err = s.fetcher.FetchAccount(ctx, accountName)
+	} else {
+		_, account, err = s.fetcher.FetchAccountByKey(ctx, pubKey)
+	}
+	if err != nil {
+		if accountName != "" {
+			return accountName
+		}
+		return fmt.Sprintf("%#x", pubKey)
+	}
+	return fmt.Sprintf("%#x", account.PublicKey().Marshal())
+}
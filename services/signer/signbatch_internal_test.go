@@ -0,0 +1,79 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signer
+
+import (
+	context "context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+	e2wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+	"github.com/wealdtech/walletd/services/fetcher/memfetcher"
+)
+
+func TestBatchKey(t *testing.T) {
+	store := scratch.New()
+	wallet, err := hd.CreateWallet("Test wallet", []byte("secret"), store, keystorev4.New())
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock([]byte("secret")))
+	account, err := wallet.CreateAccount("Test account 1", []byte("Test account 1 passphrase"))
+	require.NoError(t, err)
+	wallet.Lock()
+
+	fetcherSvc, err := memfetcher.New(context.Background(), []e2wtypes.Store{store})
+	require.NoError(t, err)
+	s := &Service{fetcher: fetcherSvc}
+
+	accountKey := fmt.Sprintf("%#x", account.PublicKey().Marshal())
+	unknownPubKey := make([]byte, 48)
+
+	tests := []struct {
+		name        string
+		accountName string
+		pubKey      []byte
+		key         string
+	}{
+		{
+			name:        "Name",
+			accountName: "Test wallet/Test account 1",
+			key:         accountKey,
+		},
+		{
+			name:   "PubKey",
+			pubKey: account.PublicKey().Marshal(),
+			key:    accountKey,
+		},
+		{
+			name:        "UnknownName",
+			accountName: "Test wallet/Unknown",
+			key:         "Test wallet/Unknown",
+		},
+		{
+			name:   "UnknownPubKey",
+			pubKey: unknownPubKey,
+			key:    fmt.Sprintf("%#x", unknownPubKey),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.key, s.batchKey(context.Background(), test.accountName, test.pubKey))
+		})
+	}
+}
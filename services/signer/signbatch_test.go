@@ -0,0 +1,108 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signer_test
+
+import (
+	"bytes"
+	context "context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+	"github.com/wealdtech/walletd/core"
+	"github.com/wealdtech/walletd/services/checker"
+	"github.com/wealdtech/walletd/services/ruler"
+	"github.com/wealdtech/walletd/services/signer"
+)
+
+func TestSignBatch(t *testing.T) {
+	genesisValidatorsRoot := bytes.Repeat([]byte{0x01}, 32)
+	signerSvc, _, pubKey := setupDutiesSigner(t, genesisValidatorsRoot)
+	forkVersion := []byte{0x00, 0x00, 0x00, 0x00}
+	attesterDomain := e2types.Domain(e2types.DomainBeaconAttester, forkVersion, genesisValidatorsRoot)
+	proposerDomain := e2types.Domain(e2types.DomainBeaconProposer, forkVersion, genesisValidatorsRoot)
+	attestationData := func(domain []byte, slot uint64) *ruler.SignBeaconAttestationData {
+		return &ruler.SignBeaconAttestationData{
+			Domain:          domain,
+			Slot:            slot,
+			CommitteeIndex:  1,
+			BeaconBlockRoot: bytes.Repeat([]byte{0x02}, 32),
+			Source: &ruler.Checkpoint{
+				Epoch: 0,
+				Root:  bytes.Repeat([]byte{0x03}, 32),
+			},
+			Target: &ruler.Checkpoint{
+				Epoch: 1,
+				Root:  bytes.Repeat([]byte{0x04}, 32),
+			},
+		}
+	}
+
+	attestations := []*signer.BatchAttestation{
+		{
+			AccountName: "Test wallet/Test account 1",
+			Data:        attestationData(attesterDomain, 1),
+		},
+		{
+			AccountName: "Test wallet/Test account 1",
+			Data:        attestationData(proposerDomain, 2),
+		},
+		{
+			AccountName: "Test wallet/Unknown",
+			Data:        attestationData(attesterDomain, 3),
+		},
+		{
+			AccountName: "Test wallet/Test account 1",
+		},
+		{
+			PubKey: pubKey.Marshal(),
+			Data:   attestationData(attesterDomain, 5),
+		},
+	}
+	proposals := []*signer.BatchProposal{
+		{
+			AccountName: "Test wallet/Test account 1",
+			Data: &ruler.SignBeaconProposalData{
+				Domain:        proposerDomain,
+				Slot:          6,
+				ProposerIndex: 7,
+				ParentRoot:    bytes.Repeat([]byte{0x05}, 32),
+				StateRoot:     bytes.Repeat([]byte{0x06}, 32),
+				BodyRoot:      bytes.Repeat([]byte{0x07}, 32),
+			},
+		},
+	}
+
+	attestationResults, proposalResults := signerSvc.SignBatch(context.Background(), &checker.Credentials{Client: "client1"}, attestations, proposals)
+	require.Len(t, attestationResults, len(attestations))
+	require.Len(t, proposalResults, len(proposals))
+
+	// Denied requests do not affect the others.
+	expected := []core.RulesResult{core.APPROVED, core.DENIED, core.DENIED, core.DENIED, core.APPROVED}
+	for i := range expected {
+		assert.Equal(t, expected[i], attestationResults[i].Result, "attestation %d", i)
+		if expected[i] == core.APPROVED {
+			assert.Len(t, attestationResults[i].Signature, 96)
+		} else {
+			assert.Nil(t, attestationResults[i].Signature)
+		}
+	}
+	assert.Equal(t, core.APPROVED, proposalResults[0].Result)
+	assert.Len(t, proposalResults[0].Signature, 96)
+
+	// Results match those of individual requests.
+	_, signature, _ := signerSvc.SignBeaconAttestation(context.Background(), &checker.Credentials{Client: "client1"}, "Test wallet/Test account 1", nil, attestationData(attesterDomain, 1))
+	assert.Equal(t, signature, attestationResults[0].Signature)
+}
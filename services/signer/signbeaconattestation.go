@@ -15,9 +15,9 @@ package signer
 
 import (
 	context "context"
-	"fmt"
 
 	"github.com/opentracing/opentracing-go"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
 	"github.com/wealdtech/walletd/core"
 	"github.com/wealdtech/walletd/services/checker"
 	"github.com/wealdtech/walletd/services/ruler"
@@ -39,28 +39,19 @@ type BeaconAttestation struct {
 }
 
 // SignBeaconAttestation signs a attestation for a beacon block.
-func (s *Service) SignBeaconAttestation(ctx context.Context, credentials *checker.Credentials, accountName string, pubKey []byte, data *ruler.SignBeaconAttestationData) (core.RulesResult, []byte) {
+func (s *Service) SignBeaconAttestation(ctx context.Context, credentials *checker.Credentials, accountName string, pubKey []byte, data *ruler.SignBeaconAttestationData) (core.RulesResult, []byte, string) {
 	span, ctx := opentracing.StartSpanFromContext(ctx, "services.signer.SignBeaconAttestation")
 	defer span.Finish()
 	log := log.With().Str("action", "SignBeaconAttestation").Logger()
 	log.Debug().Msg("Request received")
 
-	wallet, account, checkRes := s.preCheck(ctx, credentials, accountName, pubKey, ruler.ActionSignBeaconAttestation)
-	if checkRes != core.APPROVED {
-		return checkRes, nil
+	if data == nil ||
+		data.Source == nil ||
+		data.Target == nil {
+		return core.DENIED, nil, ""
 	}
-	accountName = fmt.Sprintf("%s/%s", wallet.Name(), account.Name())
-	log = log.With().Str("account", accountName).Logger()
-
-	// Confirm approval via rules.
-	result := s.ruler.RunRules(ctx, ruler.ActionSignBeaconAttestation, wallet.Name(), account.Name(), account.PublicKey().Marshal(), data)
-	switch result {
-	case core.DENIED:
-		log.Debug().Str("result", "denied").Msg("Denied by rules")
-		return core.DENIED, nil
-	case core.FAILED:
-		log.Warn().Str("result", "failed").Msg("Rules check failed")
-		return core.FAILED, nil
+	if checkRes := s.checkDomain(ctx, credentials, e2types.DomainBeaconAttester, data.Domain); checkRes != core.APPROVED {
+		return checkRes, nil, ""
 	}
 
 	// Create a local copy of the data; we need ssz size information to calculate the correct root.
@@ -77,19 +68,11 @@ func (s *Service) SignBeaconAttestation(ctx context.Context, credentials *checke
 			Root:  data.Target.Root,
 		},
 	}
-
-	// Sign it.
 	signingRoot, err := generateSigningRootFromData(ctx, attestation, data.Domain)
 	if err != nil {
 		log.Warn().Err(err).Str("result", "failed").Msg("Failed to generate signing root")
-		return core.FAILED, nil
-	}
-	signature, err := signRoot(ctx, account, signingRoot[:])
-	if err != nil {
-		log.Warn().Err(err).Str("result", "failed").Msg("Failed to sign")
-		return core.FAILED, nil
+		return core.FAILED, nil, ""
 	}
 
-	log.Debug().Str("result", "succeeded").Msg("Success")
-	return core.APPROVED, signature
+	return s.approveAndSign(ctx, credentials, accountName, pubKey, ruler.ActionSignBeaconAttestation, data, signingRoot, false)
 }
@@ -15,9 +15,9 @@ package signer
 
 import (
 	context "context"
-	"fmt"
 
 	"github.com/opentracing/opentracing-go"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
 	"github.com/wealdtech/walletd/core"
 	"github.com/wealdtech/walletd/services/checker"
 	"github.com/wealdtech/walletd/services/ruler"
@@ -33,31 +33,20 @@ type BeaconBlockHeader struct {
 }
 
 // SignBeaconProposal signs a proposal for a beacon block.
-func (s *Service) SignBeaconProposal(ctx context.Context, credentials *checker.Credentials, accountName string, pubKey []byte, data *ruler.SignBeaconProposalData) (core.RulesResult, []byte) {
+func (s *Service) SignBeaconProposal(ctx context.Context, credentials *checker.Credentials, accountName string, pubKey []byte, data *ruler.SignBeaconProposalData) (core.RulesResult, []byte, string) {
 	span, ctx := opentracing.StartSpanFromContext(ctx, "service.signer.SignBeaconProposal")
 	defer span.Finish()
 	log := log.With().Str("action", "SignBeaconProposal").Logger()
 	log.Debug().Msg("Request received")
 
-	wallet, account, checkRes := s.preCheck(ctx, credentials, accountName, pubKey, ruler.ActionSignBeaconProposal)
-	if checkRes != core.APPROVED {
-		return checkRes, nil
+	if data == nil {
+		return core.DENIED, nil, ""
 	}
-	accountName = fmt.Sprintf("%s/%s", wallet.Name(), account.Name())
-	log = log.With().Str("account", accountName).Logger()
-
-	// Confirm approval via rules.
-	result := s.ruler.RunRules(ctx, ruler.ActionSignBeaconProposal, wallet.Name(), account.Name(), account.PublicKey().Marshal(), data)
-	switch result {
-	case core.DENIED:
-		log.Debug().Str("result", "denied").Msg("Denied by rules")
-		return core.DENIED, nil
-	case core.FAILED:
-		log.Warn().Str("result", "failed").Msg("Rules check failed")
-		return core.FAILED, nil
+	if checkRes := s.checkDomain(ctx, credentials, e2types.DomainBeaconProposer, data.Domain); checkRes != core.APPROVED {
+		return checkRes, nil, ""
 	}
 
-	// Obtain the signing root of the data.
+	// Create a local copy of the data; we need ssz size information to calculate the correct root.
 	blockHeader := &BeaconBlockHeader{
 		Slot:          data.Slot,
 		ProposerIndex: data.ProposerIndex,
@@ -65,19 +54,11 @@ func (s *Service) SignBeaconProposal(ctx context.Context, credentials *checker.C
 		StateRoot:     data.StateRoot,
 		BodyRoot:      data.BodyRoot,
 	}
-
-	// Sign it.
 	signingRoot, err := generateSigningRootFromData(ctx, blockHeader, data.Domain)
 	if err != nil {
 		log.Warn().Err(err).Str("result", "failed").Msg("Failed to generate signing root")
-		return core.FAILED, nil
-	}
-	signature, err := account.Sign(signingRoot[:])
-	if err != nil {
-		log.Warn().Err(err).Str("result", "failed").Msg("Failed to sign")
-		return core.FAILED, nil
+		return core.FAILED, nil, ""
 	}
 
-	log.Debug().Str("result", "succeeded").Msg("Success")
-	return core.APPROVED, signature.Marshal()
+	return s.approveAndSign(ctx, credentials, accountName, pubKey, ruler.ActionSignBeaconProposal, data, signingRoot, false)
 }
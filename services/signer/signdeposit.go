@@ -0,0 +1,50 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signer
+
+import (
+	context "context"
+
+	"github.com/opentracing/opentracing-go"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+	"github.com/wealdtech/walletd/core"
+	"github.com/wealdtech/walletd/services/checker"
+	"github.com/wealdtech/walletd/services/ruler"
+)
+
+// DepositMessage is a copy of the Ethereum 2 DepositMessage struct with SSZ size information.
+type DepositMessage struct {
+	PubKey                []byte `ssz-size:"48"`
+	WithdrawalCredentials []byte `ssz-size:"32"`
+	Amount                uint64
+}
+
+// SignDeposit signs deposit data for a validator.
+func (s *Service) SignDeposit(ctx context.Context, credentials *checker.Credentials, accountName string, pubKey []byte, data *ruler.SignDepositData) (core.RulesResult, []byte, string) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "services.signer.SignDeposit")
+	defer span.Finish()
+	log.Debug().Str("action", "SignDeposit").Msg("Request received")
+
+	if data == nil {
+		return core.DENIED, nil, ""
+	}
+
+	depositMessage := &DepositMessage{
+		PubKey:                data.PubKey,
+		WithdrawalCredentials: data.WithdrawalCredentials,
+		Amount:                data.Amount,
+	}
+
+	return s.signObject(ctx, credentials, accountName, pubKey, ruler.ActionSignDeposit, e2types.DomainDeposit, data.Domain, data, depositMessage)
+}
@@ -0,0 +1,37 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signer
+
+import (
+	context "context"
+
+	"github.com/opentracing/opentracing-go"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+	"github.com/wealdtech/walletd/core"
+	"github.com/wealdtech/walletd/services/checker"
+	"github.com/wealdtech/walletd/services/ruler"
+)
+
+// SignRANDAOReveal signs a RANDAO reveal for an epoch.
+func (s *Service) SignRANDAOReveal(ctx context.Context, credentials *checker.Credentials, accountName string, pubKey []byte, data *ruler.SignRANDAORevealData) (core.RulesResult, []byte, string) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "services.signer.SignRANDAOReveal")
+	defer span.Finish()
+	log.Debug().Str("action", "SignRANDAOReveal").Msg("Request received")
+
+	if data == nil {
+		return core.DENIED, nil, ""
+	}
+
+	return s.signObject(ctx, credentials, accountName, pubKey, ruler.ActionSignRANDAOReveal, e2types.DomainRANDAO, data.Domain, data, data.Epoch)
+}
@@ -0,0 +1,41 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signer
+
+import (
+	context "context"
+
+	"github.com/opentracing/opentracing-go"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+	"github.com/wealdtech/walletd/core"
+	"github.com/wealdtech/walletd/services/checker"
+	"github.com/wealdtech/walletd/services/ruler"
+)
+
+// domainSelectionProof is the domain type for selection proofs.
+// The upstream constant is a byte slice, so it is redefined here as a domain type.
+var domainSelectionProof = e2types.DomainType{0x05, 0x00, 0x00, 0x00}
+
+// SignSelectionProof signs a slot to prove selection as an aggregator.
+func (s *Service) SignSelectionProof(ctx context.Context, credentials *checker.Credentials, accountName string, pubKey []byte, data *ruler.SignSelectionProofData) (core.RulesResult, []byte, string) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "services.signer.SignSelectionProof")
+	defer span.Finish()
+	log.Debug().Str("action", "SignSelectionProof").Msg("Request received")
+
+	if data == nil {
+		return core.DENIED, nil, ""
+	}
+
+	return s.signObject(ctx, credentials, accountName, pubKey, ruler.ActionSignSelectionProof, domainSelectionProof, data.Domain, data, data.Slot)
+}
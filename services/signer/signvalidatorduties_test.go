@@ -0,0 +1,358 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signer_test
+
+import (
+	"bytes"
+	context "context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+	e2wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+	"github.com/wealdtech/walletd/core"
+	"github.com/wealdtech/walletd/services/approvals"
+	keysunlocker "github.com/wealdtech/walletd/services/autounlocker/keys"
+	"github.com/wealdtech/walletd/services/checker"
+	mockchecker "github.com/wealdtech/walletd/services/checker/mock"
+	"github.com/wealdtech/walletd/services/fetcher/memfetcher"
+	"github.com/wealdtech/walletd/services/locker"
+	"github.com/wealdtech/walletd/services/networks"
+	timedrelocker "github.com/wealdtech/walletd/services/relocker/timed"
+	"github.com/wealdtech/walletd/services/ruler"
+	"github.com/wealdtech/walletd/services/ruler/lua"
+	"github.com/wealdtech/walletd/services/signer"
+	"github.com/wealdtech/walletd/services/storage/mem"
+)
+
+func TestSignValidatorDuties(t *testing.T) {
+	genesisValidatorsRoot := bytes.Repeat([]byte{0x01}, 32)
+	signerSvc, _, _ := setupDutiesSigner(t, genesisValidatorsRoot)
+	forkVersion := []byte{0x00, 0x00, 0x00, 0x00}
+	credentials := &checker.Credentials{Client: "client1"}
+	accountName := "Test wallet/Test account 1"
+	randaoDomain := e2types.Domain(e2types.DomainRANDAO, forkVersion, genesisValidatorsRoot)
+	attesterDomain := e2types.Domain(e2types.DomainBeaconAttester, forkVersion, genesisValidatorsRoot)
+	selectionProofDomain := e2types.Domain(e2types.DomainType{0x05, 0x00, 0x00, 0x00}, forkVersion, genesisValidatorsRoot)
+	aggregateAndProofDomain := e2types.Domain(e2types.DomainType{0x06, 0x00, 0x00, 0x00}, forkVersion, genesisValidatorsRoot)
+	voluntaryExitDomain := e2types.Domain(e2types.DomainVoluntaryExit, forkVersion, genesisValidatorsRoot)
+	depositDomain := e2types.Domain(e2types.DomainDeposit, forkVersion, make([]byte, 32))
+	aggregate := &ruler.Attestation{
+		AggregationBits: []byte{0x0f},
+		Data: &ruler.AttestationData{
+			Slot:            1,
+			CommitteeIndex:  2,
+			BeaconBlockRoot: bytes.Repeat([]byte{0x03}, 32),
+			Source: &ruler.Checkpoint{
+				Epoch: 0,
+				Root:  bytes.Repeat([]byte{0x04}, 32),
+			},
+			Target: &ruler.Checkpoint{
+				Epoch: 1,
+				Root:  bytes.Repeat([]byte{0x05}, 32),
+			},
+		},
+		Signature: bytes.Repeat([]byte{0x06}, 96),
+	}
+	withoutSource := *aggregate.Data
+	withoutSource.Source = nil
+	withoutTarget := *aggregate.Data
+	withoutTarget.Target = nil
+
+	tests := []struct {
+		name string
+		sign func() (core.RulesResult, []byte, string)
+		res  core.RulesResult
+	}{
+		{
+			name: "RANDAORevealNoData",
+			sign: func() (core.RulesResult, []byte, string) {
+				return signerSvc.SignRANDAOReveal(context.Background(), credentials, accountName, nil, nil)
+			},
+			res: core.DENIED,
+		},
+		{
+			name: "RANDAORevealWrongDomain",
+			sign: func() (core.RulesResult, []byte, string) {
+				return signerSvc.SignRANDAOReveal(context.Background(), credentials, accountName, nil, &ruler.SignRANDAORevealData{Domain: voluntaryExitDomain, Epoch: 1})
+			},
+			res: core.DENIED,
+		},
+		{
+			name: "RANDAOReveal",
+			sign: func() (core.RulesResult, []byte, string) {
+				return signerSvc.SignRANDAOReveal(context.Background(), credentials, accountName, nil, &ruler.SignRANDAORevealData{Domain: randaoDomain, Epoch: 1})
+			},
+			res: core.APPROVED,
+		},
+		{
+			name: "SelectionProofWrongDomain",
+			sign: func() (core.RulesResult, []byte, string) {
+				return signerSvc.SignSelectionProof(context.Background(), credentials, accountName, nil, &ruler.SignSelectionProofData{Domain: randaoDomain, Slot: 1})
+			},
+			res: core.DENIED,
+		},
+		{
+			name: "SelectionProof",
+			sign: func() (core.RulesResult, []byte, string) {
+				return signerSvc.SignSelectionProof(context.Background(), credentials, accountName, nil, &ruler.SignSelectionProofData{Domain: selectionProofDomain, Slot: 1})
+			},
+			res: core.APPROVED,
+		},
+		{
+			name: "AggregateAndProofMissingAggregate",
+			sign: func() (core.RulesResult, []byte, string) {
+				return signerSvc.SignAggregateAndProof(context.Background(), credentials, accountName, nil, &ruler.SignAggregateAndProofData{Domain: aggregateAndProofDomain})
+			},
+			res: core.DENIED,
+		},
+		{
+			name: "AggregateAndProofMissingSource",
+			sign: func() (core.RulesResult, []byte, string) {
+				return signerSvc.SignAggregateAndProof(context.Background(), credentials, accountName, nil, &ruler.SignAggregateAndProofData{
+					Domain:          aggregateAndProofDomain,
+					AggregatorIndex: 7,
+					Aggregate: &ruler.Attestation{
+						AggregationBits: aggregate.AggregationBits,
+						Data:            &withoutSource,
+						Signature:       aggregate.Signature,
+					},
+					SelectionProof: bytes.Repeat([]byte{0x07}, 96),
+				})
+			},
+			res: core.DENIED,
+		},
+		{
+			name: "AggregateAndProofMissingTarget",
+			sign: func() (core.RulesResult, []byte, string) {
+				return signerSvc.SignAggregateAndProof(context.Background(), credentials, accountName, nil, &ruler.SignAggregateAndProofData{
+					Domain:          aggregateAndProofDomain,
+					AggregatorIndex: 7,
+					Aggregate: &ruler.Attestation{
+						AggregationBits: aggregate.AggregationBits,
+						Data:            &withoutTarget,
+						Signature:       aggregate.Signature,
+					},
+					SelectionProof: bytes.Repeat([]byte{0x07}, 96),
+				})
+			},
+			res: core.DENIED,
+		},
+		{
+			name: "AggregateAndProof",
+			sign: func() (core.RulesResult, []byte, string) {
+				return signerSvc.SignAggregateAndProof(context.Background(), credentials, accountName, nil, &ruler.SignAggregateAndProofData{
+					Domain:          aggregateAndProofDomain,
+					AggregatorIndex: 7,
+					Aggregate:       aggregate,
+					SelectionProof:  bytes.Repeat([]byte{0x07}, 96),
+				})
+			},
+			res: core.APPROVED,
+		},
+		{
+			name: "BeaconAttestationNoData",
+			sign: func() (core.RulesResult, []byte, string) {
+				return signerSvc.SignBeaconAttestation(context.Background(), credentials, accountName, nil, nil)
+			},
+			res: core.DENIED,
+		},
+		{
+			name: "BeaconAttestationMissingSource",
+			sign: func() (core.RulesResult, []byte, string) {
+				return signerSvc.SignBeaconAttestation(context.Background(), credentials, accountName, nil, &ruler.SignBeaconAttestationData{
+					Domain:          attesterDomain,
+					Slot:            1,
+					CommitteeIndex:  2,
+					BeaconBlockRoot: bytes.Repeat([]byte{0x03}, 32),
+					Target:          aggregate.Data.Target,
+				})
+			},
+			res: core.DENIED,
+		},
+		{
+			name: "BeaconAttestationMissingTarget",
+			sign: func() (core.RulesResult, []byte, string) {
+				return signerSvc.SignBeaconAttestation(context.Background(), credentials, accountName, nil, &ruler.SignBeaconAttestationData{
+					Domain:          attesterDomain,
+					Slot:            1,
+					CommitteeIndex:  2,
+					BeaconBlockRoot: bytes.Repeat([]byte{0x03}, 32),
+					Source:          aggregate.Data.Source,
+				})
+			},
+			res: core.DENIED,
+		},
+		{
+			name: "BeaconAttestation",
+			sign: func() (core.RulesResult, []byte, string) {
+				return signerSvc.SignBeaconAttestation(context.Background(), credentials, accountName, nil, &ruler.SignBeaconAttestationData{
+					Domain:          attesterDomain,
+					Slot:            1,
+					CommitteeIndex:  2,
+					BeaconBlockRoot: bytes.Repeat([]byte{0x03}, 32),
+					Source:          aggregate.Data.Source,
+					Target:          aggregate.Data.Target,
+				})
+			},
+			res: core.APPROVED,
+		},
+		{
+			name: "VoluntaryExitWrongDomain",
+			sign: func() (core.RulesResult, []byte, string) {
+				return signerSvc.SignVoluntaryExit(context.Background(), credentials, accountName, nil, &ruler.SignVoluntaryExitData{Domain: randaoDomain, Epoch: 1, ValidatorIndex: 2})
+			},
+			res: core.DENIED,
+		},
+		{
+			name: "VoluntaryExit",
+			sign: func() (core.RulesResult, []byte, string) {
+				return signerSvc.SignVoluntaryExit(context.Background(), credentials, accountName, nil, &ruler.SignVoluntaryExitData{Domain: voluntaryExitDomain, Epoch: 1, ValidatorIndex: 2})
+			},
+			res: core.PENDING,
+		},
+		{
+			name: "DepositWrongDomain",
+			sign: func() (core.RulesResult, []byte, string) {
+				return signerSvc.SignDeposit(context.Background(), credentials, accountName, nil, &ruler.SignDepositData{
+					Domain:                e2types.Domain(e2types.DomainDeposit, forkVersion, genesisValidatorsRoot),
+					PubKey:                bytes.Repeat([]byte{0x08}, 48),
+					WithdrawalCredentials: bytes.Repeat([]byte{0x09}, 32),
+					Amount:                32000000000,
+				})
+			},
+			res: core.DENIED,
+		},
+		{
+			name: "Deposit",
+			sign: func() (core.RulesResult, []byte, string) {
+				return signerSvc.SignDeposit(context.Background(), credentials, accountName, nil, &ruler.SignDepositData{
+					Domain:                depositDomain,
+					PubKey:                bytes.Repeat([]byte{0x08}, 48),
+					WithdrawalCredentials: bytes.Repeat([]byte{0x09}, 32),
+					Amount:                32000000000,
+				})
+			},
+			res: core.APPROVED,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, signature, _ := test.sign()
+			assert.Equal(t, test.res, res)
+			if test.res == core.APPROVED {
+				assert.Len(t, signature, 96)
+			}
+		})
+	}
+}
+
+// TestSignVoluntaryExit confirms that voluntary exits are signed only after approval, and that the signature is over
+// the signing root defined by the specification.
+func TestSignVoluntaryExit(t *testing.T) {
+	genesisValidatorsRoot := bytes.Repeat([]byte{0x01}, 32)
+	signerSvc, approvalsSvc, pubKey := setupDutiesSigner(t, genesisValidatorsRoot)
+	domain := e2types.Domain(e2types.DomainVoluntaryExit, []byte{0x00, 0x00, 0x00, 0x00}, genesisValidatorsRoot)
+	credentials := &checker.Credentials{Client: "client1"}
+	data := &ruler.SignVoluntaryExitData{
+		Domain:         domain,
+		Epoch:          5,
+		ValidatorIndex: 6,
+	}
+
+	res, signature, id := signerSvc.SignVoluntaryExit(context.Background(), credentials, "Test wallet/Test account 1", nil, data)
+	require.Equal(t, core.PENDING, res)
+	require.Nil(t, signature)
+	require.NotEmpty(t, id)
+
+	// Still pending until approved.
+	res, _, pendingID := signerSvc.SignVoluntaryExit(context.Background(), credentials, "Test wallet/Test account 1", nil, data)
+	require.Equal(t, core.PENDING, res)
+	require.Equal(t, id, pendingID)
+
+	_, err := approvalsSvc.Approve(id, &checker.Credentials{Client: "operator"})
+	require.NoError(t, err)
+	res, signature, _ = signerSvc.SignVoluntaryExit(context.Background(), credentials, "Test wallet/Test account 1", nil, data)
+	require.Equal(t, core.APPROVED, res)
+
+	// hash_tree_root(VoluntaryExit) is the hash of its two fields, each packed into a 32-byte chunk.
+	chunks := make([]byte, 64)
+	binary.LittleEndian.PutUint64(chunks[0:8], 5)
+	binary.LittleEndian.PutUint64(chunks[32:40], 6)
+	objectRoot := sha256.Sum256(chunks)
+	// The signing root is the hash of the object root and the domain.
+	signingRoot := sha256.Sum256(append(objectRoot[:], domain...))
+
+	sig, err := e2types.BLSSignatureFromBytes(signature)
+	require.NoError(t, err)
+	assert.True(t, sig.Verify(signingRoot[:], pubKey))
+
+	// Approval is consumed.
+	res, _, _ = signerSvc.SignVoluntaryExit(context.Background(), credentials, "Test wallet/Test account 1", nil, data)
+	require.Equal(t, core.PENDING, res)
+}
+
+// setupDutiesSigner creates a signer with a single unlockable account on a single network.
+func setupDutiesSigner(t *testing.T, genesisValidatorsRoot []byte) (*signer.Service, *approvals.Service, e2types.PublicKey) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+
+	wallet, err := hd.CreateWallet("Test wallet", []byte("secret"), store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock([]byte("secret")))
+	account, err := wallet.CreateAccount("Test account 1", []byte("Test account 1 passphrase"))
+	require.NoError(t, err)
+	wallet.Lock()
+
+	lockerSvc, err := locker.New()
+	require.NoError(t, err)
+	fetcherSvc, err := memfetcher.New(context.Background(), []e2wtypes.Store{store})
+	require.NoError(t, err)
+	storageSvc, err := mem.New()
+	require.NoError(t, err)
+	rulerSvc, err := lua.New(lockerSvc, storageSvc, []*core.Rule{})
+	require.NoError(t, err)
+	unlockerSvc, err := keysunlocker.New(context.Background(), &core.KeysConfig{
+		Keys: []string{"Test account 1 passphrase"},
+	})
+	require.NoError(t, err)
+	checkerSvc, err := mockchecker.New()
+	require.NoError(t, err)
+	relockerSvc, err := timedrelocker.New(context.Background(), nil)
+	require.NoError(t, err)
+	networksSvc, err := networks.New(context.Background(), []*core.NetworkConfig{
+		{
+			Name:                  "mainnet",
+			GenesisValidatorsRoot: fmt.Sprintf("%#x", genesisValidatorsRoot),
+			ForkVersions:          []string{"0x00000000"},
+		},
+	}, nil)
+	require.NoError(t, err)
+
+	approvalsSvc, err := approvals.New(context.Background(), nil)
+	require.NoError(t, err)
+
+	signerSvc, err := signer.New(unlockerSvc, relockerSvc, checkerSvc, fetcherSvc, rulerSvc, networksSvc, signer.WithApprovals(approvalsSvc))
+	require.NoError(t, err)
+
+	return signerSvc, approvalsSvc, account.PublicKey()
+}
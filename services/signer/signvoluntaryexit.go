@@ -0,0 +1,66 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signer
+
+import (
+	context "context"
+
+	"github.com/opentracing/opentracing-go"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+	"github.com/wealdtech/walletd/core"
+	"github.com/wealdtech/walletd/services/checker"
+	"github.com/wealdtech/walletd/services/ruler"
+)
+
+// VoluntaryExit is a copy of the Ethereum 2 VoluntaryExit struct with SSZ size information.
+type VoluntaryExit struct {
+	Epoch          uint64
+	ValidatorIndex uint64
+}
+
+// SignVoluntaryExit signs a voluntary exit for a validator.
+// A voluntary exit is irreversible, so it is never signed immediately.  The first request results in a pending
+// approval, and the signature is returned only when the request is repeated after operators have approved it, or
+// when the request waits for approval.
+// The ID of the approval is returned alongside a pending result.
+func (s *Service) SignVoluntaryExit(ctx context.Context, credentials *checker.Credentials, accountName string, pubKey []byte, data *ruler.SignVoluntaryExitData) (core.RulesResult, []byte, string) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "services.signer.SignVoluntaryExit")
+	defer span.Finish()
+	log := log.With().Str("action", "SignVoluntaryExit").Logger()
+	log.Debug().Msg("Request received")
+
+	if data == nil {
+		return core.DENIED, nil, ""
+	}
+	if checkRes := s.checkDomain(ctx, credentials, e2types.DomainVoluntaryExit, data.Domain); checkRes != core.APPROVED {
+		return checkRes, nil, ""
+	}
+
+	voluntaryExit := &VoluntaryExit{
+		Epoch:          data.Epoch,
+		ValidatorIndex: data.ValidatorIndex,
+	}
+	signingRoot, err := generateSigningRootFromData(ctx, voluntaryExit, data.Domain)
+	if err != nil {
+		log.Warn().Err(err).Str("result", "failed").Msg("Failed to generate signing root")
+		return core.FAILED, nil, ""
+	}
+
+	// Approval by operators is always required for voluntary exits.
+	result, signature, approvalID := s.approveAndSign(ctx, credentials, accountName, pubKey, ruler.ActionSignVoluntaryExit, data, signingRoot, true)
+	if result == core.APPROVED {
+		log.Info().Str("id", approvalID).Str("result", "succeeded").Msg("Voluntary exit signed")
+	}
+	return result, signature, approvalID
+}
@@ -25,7 +25,7 @@ func generateSigningRootFromData(ctx context.Context, data interface{}, domain [
 	span, ctx := opentracing.StartSpanFromContext(ctx, "handlers.signer.generateSigningRootFromData")
 	defer span.Finish()
 
-	objRoot, err := ssz.HashTreeRoot(data)
+	objRoot, err := hashTreeRoot(data)
 	if err != nil {
 		return [32]byte{}, err
 	}
@@ -37,14 +37,22 @@ func generateSigningRootFromRoot(ctx context.Context, root []byte, domain []byte
 	span, _ := opentracing.StartSpanFromContext(ctx, "handlers.signer.generateSigningRootFromRoot")
 	defer span.Finish()
 
-	signingData := struct {
-		Hash   []byte `ssz-size:"32"`
-		Domain []byte `ssz-size:"32"`
-	}{
-		Hash:   root,
-		Domain: domain,
+	return hashTreeRoot(&signingData{
+		ObjectRoot: root,
+		Domain:     domain,
+	})
+}
+
+// hashTreeRoot calculates the hash tree root of an object.
+// Objects that calculate their own root avoid the cost of reflection.  Objects that do not, or that have fields
+// longer than their SSZ size, are hashed by go-ssz so that the root is unchanged for all input.
+func hashTreeRoot(data interface{}) ([32]byte, error) {
+	if hasher, ok := data.(hashTreeRooter); ok {
+		if root, err := hasher.HashTreeRoot(); err == nil {
+			return root, nil
+		}
 	}
-	return ssz.HashTreeRoot(signingData)
+	return ssz.HashTreeRoot(data)
 }
 
 func signRoot(ctx context.Context, account e2wtypes.Account, root []byte) ([]byte, error) {
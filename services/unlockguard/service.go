@@ -0,0 +1,266 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package unlockguard
+
+import (
+	"context"
+	"expvar"
+	"sync"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/wealdtech/walletd/core"
+)
+
+const (
+	defaultFreeAttempts    = 3
+	defaultBackoff         = time.Second
+	defaultMaxBackoff      = time.Minute
+	defaultLockoutAttempts = 10
+	defaultLockoutDuration = time.Hour
+)
+
+// metrics are the metrics for unlock attempts, available at /debug/vars.
+var metrics = expvar.NewMap("unlockguard")
+
+// Service guards against repeated failed attempts to unlock accounts.
+type Service struct {
+	freeAttempts    int
+	backoff         time.Duration
+	maxBackoff      time.Duration
+	lockoutAttempts int
+	lockoutDuration time.Duration
+
+	clients  map[string]*history
+	accounts map[string]*history
+	mutex    sync.Mutex
+}
+
+// history is the history of failed attempts for a client or account.
+type history struct {
+	failures int
+	// inFlight is the number of attempts allowed but not yet settled as failed or succeeded.
+	inFlight     int
+	lastFailure  time.Time
+	blockedUntil time.Time
+	lockedOut    bool
+}
+
+// New creates a new unlock guard service.
+func New(ctx context.Context, config *core.UnlockGuardConfig) (*Service, error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "unlockguard.New")
+	defer span.Finish()
+
+	s := &Service{
+		freeAttempts:    defaultFreeAttempts,
+		backoff:         defaultBackoff,
+		maxBackoff:      defaultMaxBackoff,
+		lockoutAttempts: defaultLockoutAttempts,
+		lockoutDuration: defaultLockoutDuration,
+		clients:         make(map[string]*history),
+		accounts:        make(map[string]*history),
+	}
+	if config != nil {
+		if config.FreeAttempts != 0 {
+			s.freeAttempts = config.FreeAttempts
+		}
+		if config.Backoff != 0 {
+			s.backoff = config.Backoff
+		}
+		if config.MaxBackoff != 0 {
+			s.maxBackoff = config.MaxBackoff
+		}
+		if config.LockoutAttempts != 0 {
+			s.lockoutAttempts = config.LockoutAttempts
+		}
+		if config.LockoutDuration != 0 {
+			s.lockoutDuration = config.LockoutDuration
+		}
+	}
+
+	metrics.Set("locked_out", expvar.Func(s.lockedOut))
+
+	return s, nil
+}
+
+// Allowed returns true if the client is allowed to attempt to unlock the account.
+// An allowed attempt is reserved, and counts against the limits until it is settled by a call to Failed or Succeeded,
+// so that parallel attempts cannot get past the limits before their failures are recorded.  Once the free attempts are
+// used up attempts are allowed one at a time.
+func (s *Service) Allowed(client string, account string) bool {
+	log := log.With().Str("client", client).Str("account", account).Logger()
+	now := time.Now()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entries := []struct {
+		kind    string
+		records map[string]*history
+		key     string
+	}{
+		{kind: "client", records: s.clients, key: client},
+		{kind: "account", records: s.accounts, key: account},
+	}
+	for _, entry := range entries {
+		record, exists := entry.records[entry.key]
+		if !exists {
+			continue
+		}
+		if now.Before(record.blockedUntil) {
+			metrics.Add("blocked", 1)
+			log.Info().Str("blocked", entry.kind).Dur("remaining", record.blockedUntil.Sub(now)).Bool("locked_out", record.lockedOut).Msg("Unlock attempt blocked")
+			return false
+		}
+		if record.lockedOut {
+			// Lockout has expired.
+			log.Info().Str(entry.kind, entry.key).Msg("Lockout expired")
+			delete(entry.records, entry.key)
+			continue
+		}
+		attempts := record.failures + record.inFlight
+		if attempts >= s.lockoutAttempts || (attempts >= s.freeAttempts && record.inFlight > 0) {
+			metrics.Add("blocked", 1)
+			log.Info().Str("blocked", entry.kind).Int("in_flight", record.inFlight).Msg("Unlock attempt blocked pending other attempts")
+			return false
+		}
+	}
+
+	// Reserve the attempt.
+	for _, entry := range entries {
+		record, exists := entry.records[entry.key]
+		if !exists {
+			record = &history{}
+			entry.records[entry.key] = record
+		}
+		record.inFlight++
+	}
+	return true
+}
+
+// Failed records a failed attempt by the client to unlock the account, settling the attempt reserved by Allowed.
+func (s *Service) Failed(client string, account string) {
+	log := log.With().Str("client", client).Str("account", account).Logger()
+	now := time.Now()
+	metrics.Add("failures", 1)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.prune(now)
+	for _, entry := range []struct {
+		kind    string
+		records map[string]*history
+		key     string
+	}{
+		{kind: "client", records: s.clients, key: client},
+		{kind: "account", records: s.accounts, key: account},
+	} {
+		record, exists := entry.records[entry.key]
+		if !exists {
+			record = &history{}
+			entry.records[entry.key] = record
+		}
+		if record.inFlight > 0 {
+			record.inFlight--
+		}
+		record.failures++
+		record.lastFailure = now
+		switch {
+		case record.lockedOut:
+			// Already locked out; nothing more to do.
+		case record.failures >= s.lockoutAttempts:
+			record.lockedOut = true
+			record.blockedUntil = now.Add(s.lockoutDuration)
+			metrics.Add("lockouts", 1)
+			log.Warn().Str("locked_out", entry.kind).Int("failures", record.failures).Dur("duration", s.lockoutDuration).Msg("Too many failed unlock attempts; locked out")
+		case record.failures > s.freeAttempts:
+			backoff := s.backoff << uint(record.failures-s.freeAttempts-1)
+			if backoff > s.maxBackoff || backoff <= 0 {
+				backoff = s.maxBackoff
+			}
+			record.blockedUntil = now.Add(backoff)
+			log.Info().Str("backoff", entry.kind).Int("failures", record.failures).Dur("duration", backoff).Msg("Failed unlock attempt; backing off")
+		}
+	}
+}
+
+// Succeeded records a successful attempt by the client to unlock the account.
+// Failed attempts for the account are cleared.  Failed attempts for the client are kept until they age out, as
+// unlocking an account for which it knows the passphrase would otherwise allow a client to reset its own record.
+func (s *Service) Succeeded(client string, account string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if record, exists := s.clients[client]; exists && record.inFlight > 0 {
+		record.inFlight--
+	}
+	delete(s.accounts, account)
+}
+
+// Clear clears any record of failed attempts for the account and, if supplied, the client.
+// It returns true if a block was in place.
+func (s *Service) Clear(client string, account string) bool {
+	now := time.Now()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	cleared := false
+	if record, exists := s.accounts[account]; exists {
+		cleared = cleared || now.Before(record.blockedUntil)
+		delete(s.accounts, account)
+	}
+	if client != "" {
+		if record, exists := s.clients[client]; exists {
+			cleared = cleared || now.Before(record.blockedUntil)
+			delete(s.clients, client)
+		}
+	}
+	if cleared {
+		metrics.Add("cleared", 1)
+	}
+	log.Info().Str("client", client).Str("account", account).Bool("cleared", cleared).Msg("Cleared failed unlock attempts")
+	return cleared
+}
+
+// prune removes records that are no longer relevant.
+// This assumes that the mutex is held.
+func (s *Service) prune(now time.Time) {
+	for _, records := range []map[string]*history{s.clients, s.accounts} {
+		for key, record := range records {
+			if record.inFlight == 0 && now.After(record.blockedUntil) && now.Sub(record.lastFailure) > s.lockoutDuration {
+				delete(records, key)
+			}
+		}
+	}
+}
+
+// lockedOut returns the number of clients and accounts that are currently locked out.
+func (s *Service) lockedOut() interface{} {
+	now := time.Now()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	count := 0
+	for _, records := range []map[string]*history{s.clients, s.accounts} {
+		for _, record := range records {
+			if record.lockedOut && now.Before(record.blockedUntil) {
+				count++
+			}
+		}
+	}
+	return count
+}
@@ -0,0 +1,182 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package unlockguard_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wealdtech/walletd/core"
+	"github.com/wealdtech/walletd/services/unlockguard"
+)
+
+func TestBackoff(t *testing.T) {
+	service, err := unlockguard.New(context.Background(), &core.UnlockGuardConfig{
+		FreeAttempts:    2,
+		Backoff:         50 * time.Millisecond,
+		MaxBackoff:      time.Second,
+		LockoutAttempts: 10,
+		LockoutDuration: time.Hour,
+	})
+	require.NoError(t, err)
+
+	// Free attempts.
+	for i := 0; i < 2; i++ {
+		require.True(t, service.Allowed("client1", "Wallet/Account"))
+		service.Failed("client1", "Wallet/Account")
+	}
+	require.True(t, service.Allowed("client1", "Wallet/Account"))
+
+	// Third failure should result in a backoff for both the client and the account.
+	service.Failed("client1", "Wallet/Account")
+	assert.False(t, service.Allowed("client1", "Wallet/Account"))
+	assert.False(t, service.Allowed("client1", "Wallet/Other account"))
+	assert.False(t, service.Allowed("client2", "Wallet/Account"))
+	assert.True(t, service.Allowed("client2", "Wallet/Other account"))
+	service.Succeeded("client2", "Wallet/Other account")
+
+	// Backoff should expire.
+	time.Sleep(60 * time.Millisecond)
+	assert.True(t, service.Allowed("client1", "Wallet/Account"))
+
+	// Next backoff should be longer.
+	service.Failed("client1", "Wallet/Account")
+	time.Sleep(60 * time.Millisecond)
+	assert.False(t, service.Allowed("client1", "Wallet/Account"))
+	time.Sleep(60 * time.Millisecond)
+	assert.True(t, service.Allowed("client1", "Wallet/Account"))
+
+	// Success should reset the account, but not the client.
+	service.Succeeded("client1", "Wallet/Account")
+	assert.True(t, service.Allowed("client2", "Wallet/Account"))
+	service.Succeeded("client2", "Wallet/Account")
+	service.Failed("client1", "Wallet/Account")
+	assert.False(t, service.Allowed("client1", "Wallet/Other account"))
+	assert.True(t, service.Allowed("client2", "Wallet/Account"))
+}
+
+func TestLockout(t *testing.T) {
+	service, err := unlockguard.New(context.Background(), &core.UnlockGuardConfig{
+		FreeAttempts:    100,
+		LockoutAttempts: 3,
+		LockoutDuration: 50 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		require.True(t, service.Allowed("client1", "Wallet/Account"))
+		service.Failed("client1", "Wallet/Account")
+	}
+	assert.False(t, service.Allowed("client1", "Wallet/Account"))
+
+	// Lockout should expire.
+	time.Sleep(60 * time.Millisecond)
+	assert.True(t, service.Allowed("client1", "Wallet/Account"))
+	service.Failed("client1", "Wallet/Account")
+	assert.True(t, service.Allowed("client1", "Wallet/Account"))
+}
+
+func TestConcurrentAttempts(t *testing.T) {
+	service, err := unlockguard.New(context.Background(), &core.UnlockGuardConfig{
+		FreeAttempts:    2,
+		Backoff:         time.Hour,
+		LockoutAttempts: 10,
+		LockoutDuration: time.Hour,
+	})
+	require.NoError(t, err)
+
+	// Attempts made in parallel are reserved as they are allowed, so only the free attempts get through.
+	attempts := 20
+	var allowed int32
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if service.Allowed("client1", "Wallet/Account") {
+				atomic.AddInt32(&allowed, 1)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+	assert.Equal(t, int32(2), allowed)
+
+	// Beyond the free attempts a single attempt is allowed once the others are settled.
+	service.Failed("client1", "Wallet/Account")
+	assert.False(t, service.Allowed("client1", "Wallet/Account"))
+	service.Failed("client1", "Wallet/Account")
+	require.True(t, service.Allowed("client1", "Wallet/Account"))
+	assert.False(t, service.Allowed("client1", "Wallet/Account"))
+
+	// Its failure results in a backoff.
+	service.Failed("client1", "Wallet/Account")
+	assert.False(t, service.Allowed("client1", "Wallet/Account"))
+}
+
+func TestConcurrentAttemptsLockout(t *testing.T) {
+	service, err := unlockguard.New(context.Background(), &core.UnlockGuardConfig{
+		FreeAttempts:    100,
+		LockoutAttempts: 3,
+		LockoutDuration: time.Hour,
+	})
+	require.NoError(t, err)
+
+	// In-flight attempts count towards the lockout.
+	for i := 0; i < 3; i++ {
+		require.True(t, service.Allowed("client1", "Wallet/Account"))
+	}
+	assert.False(t, service.Allowed("client1", "Wallet/Account"))
+	assert.False(t, service.Allowed("client2", "Wallet/Account"))
+
+	// A successful attempt releases its reservation.
+	service.Succeeded("client1", "Wallet/Account")
+	service.Failed("client1", "Wallet/Account")
+	service.Failed("client1", "Wallet/Account")
+	assert.True(t, service.Allowed("client2", "Wallet/Account"))
+}
+
+func TestClear(t *testing.T) {
+	service, err := unlockguard.New(context.Background(), &core.UnlockGuardConfig{
+		FreeAttempts:    100,
+		LockoutAttempts: 3,
+		LockoutDuration: time.Hour,
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		service.Failed("client1", "Wallet/Account")
+	}
+	assert.False(t, service.Allowed("client2", "Wallet/Account"))
+	assert.False(t, service.Allowed("client1", "Wallet/Other account"))
+
+	// Clearing the account leaves the client locked out.
+	assert.True(t, service.Clear("", "Wallet/Account"))
+	assert.True(t, service.Allowed("client2", "Wallet/Account"))
+	assert.False(t, service.Allowed("client1", "Wallet/Other account"))
+
+	// Clearing the client as well.
+	assert.True(t, service.Clear("client1", "Wallet/Account"))
+	assert.True(t, service.Allowed("client1", "Wallet/Other account"))
+
+	// Nothing left to clear.
+	assert.False(t, service.Clear("client1", "Wallet/Account"))
+}
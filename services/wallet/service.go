@@ -17,11 +17,14 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/pem"
 	"fmt"
 	"io/ioutil"
 	"net"
+	"net/http"
 	"os"
 	"path/filepath"
+	"time"
 
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	grpc_ctxtags "github.com/grpc-ecosystem/go-grpc-middleware/tags"
@@ -30,22 +33,31 @@ import (
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	pb "github.com/wealdtech/eth2-signer-api/pb/v1"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
 	e2wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+	"github.com/wealdtech/walletd/api"
 	"github.com/wealdtech/walletd/core"
 	"github.com/wealdtech/walletd/handlers/grpc/accountmanager"
+	"github.com/wealdtech/walletd/handlers/grpc/admin"
 	"github.com/wealdtech/walletd/handlers/grpc/lister"
 	signerhandler "github.com/wealdtech/walletd/handlers/grpc/signer"
 	"github.com/wealdtech/walletd/handlers/grpc/walletmanager"
+	"github.com/wealdtech/walletd/handlers/http/web3signer"
 	"github.com/wealdtech/walletd/interceptors"
+	"github.com/wealdtech/walletd/services/approvals"
 	"github.com/wealdtech/walletd/services/autounlocker"
 	"github.com/wealdtech/walletd/services/checker"
 	"github.com/wealdtech/walletd/services/fetcher/memfetcher"
 	"github.com/wealdtech/walletd/services/locker"
+	"github.com/wealdtech/walletd/services/networks"
+	"github.com/wealdtech/walletd/services/relocker"
+	"github.com/wealdtech/walletd/services/revocation"
 	"github.com/wealdtech/walletd/services/ruler"
 	"github.com/wealdtech/walletd/services/ruler/golang"
 	"github.com/wealdtech/walletd/services/ruler/lua"
 	signersvc "github.com/wealdtech/walletd/services/signer"
 	"github.com/wealdtech/walletd/services/storage/badger"
+	"github.com/wealdtech/walletd/services/unlockguard"
 	"github.com/wealdtech/walletd/util"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
@@ -55,19 +67,32 @@ import (
 // Service provides the features and functions for the wallet daemon.
 type Service struct {
 	autounlocker autounlocker.Service
+	relocker     relocker.Service
+	guard        *unlockguard.Service
 	checker      checker.Service
+	networks     *networks.Service
 	stores       []e2wtypes.Store
 	rules        []*core.Rule
+	signConfig   *core.SignConfig
+	approvals    *approvals.Service
 	grpcServer   *grpc.Server
+	// tlsConfig and identitySource are shared by all listeners.
+	tlsConfig      *tls.Config
+	identitySource interceptors.IdentitySource
 }
 
 // New creates a new wallet daemon service.
-func New(ctx context.Context, autounlocker autounlocker.Service, checker checker.Service, stores []e2wtypes.Store, rules []*core.Rule) (*Service, error) {
+func New(ctx context.Context, autounlocker autounlocker.Service, relocker relocker.Service, guard *unlockguard.Service, checker checker.Service, networks *networks.Service, approvals *approvals.Service, stores []e2wtypes.Store, rules []*core.Rule, signConfig *core.SignConfig) (*Service, error) {
 	return &Service{
 		autounlocker: autounlocker,
+		relocker:     relocker,
+		guard:        guard,
 		checker:      checker,
+		networks:     networks,
+		approvals:    approvals,
 		stores:       stores,
 		rules:        rules,
+		signConfig:   signConfig,
 	}, nil
 }
 
@@ -76,7 +101,7 @@ func (s *Service) ServeGRPC(ctx context.Context, config *core.ServerConfig) erro
 	span, _ := opentracing.StartSpanFromContext(ctx, "wallet.service.ServeGRPC")
 	defer span.Finish()
 
-	if err := s.createServer(config); err != nil {
+	if err := s.createServer(ctx, config); err != nil {
 		return err
 	}
 
@@ -102,20 +127,43 @@ func (s *Service) ServeGRPC(ctx context.Context, config *core.ServerConfig) erro
 		return err
 	}
 
-	fetcher, err := memfetcher.New(s.stores)
+	fetcher, err := memfetcher.New(ctx, s.stores)
 	if err != nil {
 		return err
 	}
 
-	signerSvc, err := signersvc.New(s.autounlocker, s.checker, fetcher, ruler)
+	signerOpts := []signersvc.Option{signersvc.WithApprovals(s.approvals)}
+	if s.signConfig != nil {
+		domainTypes := make([]e2types.DomainType, len(s.signConfig.AllowedDomainTypes))
+		for i := range s.signConfig.AllowedDomainTypes {
+			domainTypes[i], err = signersvc.ParseDomainType(s.signConfig.AllowedDomainTypes[i])
+			if err != nil {
+				return err
+			}
+		}
+		signerOpts = append(signerOpts, signersvc.WithAllowedDomainTypes(domainTypes))
+		if s.signConfig.SignatureCache != nil {
+			signerOpts = append(signerOpts, signersvc.WithSignatureCache(s.signConfig.SignatureCache.Window, s.signConfig.SignatureCache.Size))
+		}
+	}
+	signerSvc, err := signersvc.New(s.autounlocker, s.relocker, s.checker, fetcher, ruler, s.networks, signerOpts...)
 	if err != nil {
 		return err
 	}
 
-	pb.RegisterWalletManagerServer(s.grpcServer, walletmanager.New(fetcher, ruler))
-	pb.RegisterAccountManagerServer(s.grpcServer, accountmanager.New(fetcher, ruler))
+	pb.RegisterWalletManagerServer(s.grpcServer, walletmanager.New(s.checker, fetcher, ruler, s.guard))
+	pb.RegisterAccountManagerServer(s.grpcServer, accountmanager.New(s.checker, fetcher, ruler, s.relocker, s.guard))
 	pb.RegisterListerServer(s.grpcServer, lister.New(s.checker, fetcher, ruler))
-	pb.RegisterSignerServer(s.grpcServer, signerhandler.New(signerSvc))
+	signerHandler := signerhandler.New(signerSvc)
+	pb.RegisterSignerServer(s.grpcServer, signerHandler)
+	api.RegisterSignerServer(s.grpcServer, signerHandler)
+	api.RegisterAdminServer(s.grpcServer, admin.New(s.checker, s.guard, s.approvals))
+
+	if config.Web3Signer != nil {
+		if err := s.serveWeb3Signer(config.Web3Signer, web3signer.New(signerSvc, s.checker, fetcher, ruler, s.networks, s.identitySource)); err != nil {
+			return err
+		}
+	}
 
 	err = s.Serve(config)
 	if err != nil {
@@ -125,17 +173,23 @@ func (s *Service) ServeGRPC(ctx context.Context, config *core.ServerConfig) erro
 }
 
 // createServer creates the GRPC server.
-func (s *Service) createServer(config *core.ServerConfig) error {
+func (s *Service) createServer(ctx context.Context, config *core.ServerConfig) error {
 	logger := zerolog.New(os.Stdout).With().Timestamp().Logger()
 	logger = logger.With().Str("module", "grpc-wallet").Logger()
 	grpclog.SetLoggerV2(util.NewLogShim(logger))
 
+	identitySource, err := interceptors.ParseIdentitySource(config.IdentitySource)
+	if err != nil {
+		return err
+	}
+
 	grpcOpts := []grpc.ServerOption{
 		grpc.UnaryInterceptor(
 			grpc_middleware.ChainUnaryServer(
 				grpc_ctxtags.UnaryServerInterceptor(grpc_ctxtags.WithFieldExtractor(grpc_ctxtags.CodeGenRequestFieldExtractor)),
 				interceptors.SourceIPInterceptor(),
-				interceptors.ClientInfoInterceptor(),
+				interceptors.ClientInfoInterceptor(identitySource),
+				interceptors.ApprovalIDInterceptor(),
 			)),
 	}
 
@@ -165,18 +219,57 @@ func (s *Service) createServer(config *core.ServerConfig) error {
 		return errors.Wrap(err, "Could not add CA certificate to pool")
 	}
 
-	serverCreds := credentials.NewTLS(&tls.Config{
+	tlsConfig := &tls.Config{
 		ClientAuth:   tls.RequireAndVerifyClientCert,
 		Certificates: []tls.Certificate{serverCert},
 		ClientCAs:    certPool,
-	})
+	}
+	if config.CRLPath != "" || config.DenyListPath != "" {
+		cas, err := parseCertificates(caCert)
+		if err != nil {
+			return errors.Wrap(err, "Could not parse CA certificate")
+		}
+		revocationSvc, err := revocation.New(ctx, cas, config.CRLPath, config.DenyListPath)
+		if err != nil {
+			return errors.Wrap(err, "Failed to initialise certificate revocation")
+		}
+		tlsConfig.VerifyPeerCertificate = revocationSvc.VerifyPeerCertificate
+	}
+
+	serverCreds := credentials.NewTLS(tlsConfig)
 	grpcOpts = append(grpcOpts, grpc.Creds(serverCreds))
 	grpcServer := grpc.NewServer(grpcOpts...)
 
 	s.grpcServer = grpcServer
+	s.tlsConfig = tlsConfig
+	s.identitySource = identitySource
 	return nil
 }
 
+// parseCertificates parses all certificates in PEM-encoded data.
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	certs := make([]*x509.Certificate, 0)
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no certificates found")
+	}
+	return certs, nil
+}
+
 // Serve serves the GRPC server.
 func (s *Service) Serve(config *core.ServerConfig) error {
 	listenAddress := fmt.Sprintf(":%d", config.Port)
@@ -191,3 +284,25 @@ func (s *Service) Serve(config *core.ServerConfig) error {
 	}
 	return nil
 }
+
+// serveWeb3Signer serves the Web3Signer-compatible HTTP API in the background.
+// Clients are authenticated with the same TLS configuration as the GRPC server.
+func (s *Service) serveWeb3Signer(config *core.Web3SignerConfig, handler http.Handler) error {
+	listenAddress := fmt.Sprintf(":%d", config.Port)
+	conn, err := net.Listen("tcp", listenAddress)
+	if err != nil {
+		return err
+	}
+	log.Info().Str("address", listenAddress).Msg("Listening for Web3Signer requests")
+
+	server := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := server.Serve(tls.NewListener(conn, s.tlsConfig)); err != nil {
+			log.Error().Err(err).Msg("Web3Signer server stopped")
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,295 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keystores
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// walletNamespace is the namespace used to generate deterministic wallet IDs from wallet names.
+var walletNamespace = uuid.MustParse("a9c5f3bd-6b0e-4c0d-9b2f-8d1f3b6e2c41")
+
+// errReadOnly is returned when an attempt is made to write to the store.
+var errReadOnly = errors.New("keystores store is read-only")
+
+// Store is a read-only store that presents a directory of EIP-2335 keystores as a single non-deterministic wallet.
+// Each keystore file in the directory becomes an account, named after the file without its extension.  The passphrase
+// for a keystore can optionally be supplied in a sidecar file with the same name and a ".txt" extension.
+type Store struct {
+	walletName string
+	walletID   uuid.UUID
+	location   string
+	files      map[uuid.UUID]string
+	filesMx    sync.RWMutex
+}
+
+// keystore is the subset of an EIP-2335 keystore that we require.
+type keystore struct {
+	Crypto  map[string]interface{} `json:"crypto"`
+	PubKey  string                 `json:"pubkey"`
+	UUID    uuid.UUID              `json:"uuid"`
+	Version uint                   `json:"version"`
+}
+
+// account is the representation of an account as expected by non-deterministic wallets.
+type account struct {
+	UUID      uuid.UUID              `json:"uuid"`
+	Name      string                 `json:"name"`
+	PubKey    string                 `json:"pubkey"`
+	Crypto    map[string]interface{} `json:"crypto"`
+	Encryptor string                 `json:"encryptor"`
+	Version   uint                   `json:"version"`
+}
+
+// New creates a new keystores store, presenting the keystores in the given location as a wallet with the given name.
+func New(walletName string, location string) (*Store, error) {
+	if walletName == "" {
+		return nil, errors.New("no wallet name provided")
+	}
+	if location == "" {
+		return nil, errors.New("no location provided")
+	}
+	info, err := os.Stat(location)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", location)
+	}
+
+	return &Store{
+		walletName: walletName,
+		walletID:   uuid.NewSHA1(walletNamespace, []byte(walletName)),
+		location:   location,
+		files:      make(map[uuid.UUID]string),
+	}, nil
+}
+
+// Name returns the name of this store.
+func (s *Store) Name() string {
+	return "keystores"
+}
+
+// Location returns the location of this store.
+func (s *Store) Location() string {
+	return s.location
+}
+
+// StoreWallet is not supported by this store.
+func (s *Store) StoreWallet(walletID uuid.UUID, walletName string, data []byte) error {
+	return errReadOnly
+}
+
+// RetrieveWallets retrieves wallet data for all wallets.
+func (s *Store) RetrieveWallets() <-chan []byte {
+	ch := make(chan []byte, 1)
+	ch <- s.walletData()
+	close(ch)
+	return ch
+}
+
+// RetrieveWallet retrieves wallet data for a wallet with a given name.
+func (s *Store) RetrieveWallet(walletName string) ([]byte, error) {
+	if walletName != s.walletName {
+		return nil, errors.New("wallet not found")
+	}
+	return s.walletData(), nil
+}
+
+// RetrieveWalletByID retrieves wallet data for a wallet with a given ID.
+func (s *Store) RetrieveWalletByID(walletID uuid.UUID) ([]byte, error) {
+	if walletID != s.walletID {
+		return nil, errors.New("wallet not found")
+	}
+	return s.walletData(), nil
+}
+
+// StoreAccount is not supported by this store.
+func (s *Store) StoreAccount(walletID uuid.UUID, accountID uuid.UUID, data []byte) error {
+	return errReadOnly
+}
+
+// RetrieveAccounts retrieves account information for all accounts.
+func (s *Store) RetrieveAccounts(walletID uuid.UUID) <-chan []byte {
+	ch := make(chan []byte, 1024)
+	go func() {
+		defer close(ch)
+		if walletID != s.walletID {
+			return
+		}
+		for _, account := range s.scan() {
+			data, err := json.Marshal(account)
+			if err != nil {
+				log.Warn().Err(err).Str("account", account.Name).Msg("Failed to encode account")
+				continue
+			}
+			ch <- data
+		}
+	}()
+	return ch
+}
+
+// RetrieveAccount retrieves account data for a wallet with a given ID.
+func (s *Store) RetrieveAccount(walletID uuid.UUID, accountID uuid.UUID) ([]byte, error) {
+	if walletID != s.walletID {
+		return nil, errors.New("wallet not found")
+	}
+
+	s.filesMx.RLock()
+	path, exists := s.files[accountID]
+	s.filesMx.RUnlock()
+	if !exists {
+		// Could be a new file.
+		s.scan()
+		s.filesMx.RLock()
+		path, exists = s.files[accountID]
+		s.filesMx.RUnlock()
+		if !exists {
+			return nil, errors.New("account not found")
+		}
+	}
+
+	account, err := readAccount(path)
+	if err != nil {
+		return nil, err
+	}
+	if account.UUID != accountID {
+		return nil, errors.New("account not found")
+	}
+	return json.Marshal(account)
+}
+
+// StoreAccountsIndex is not supported by this store.
+func (s *Store) StoreAccountsIndex(walletID uuid.UUID, data []byte) error {
+	return errReadOnly
+}
+
+// RetrieveAccountsIndex retrieves the index of accounts for a given wallet.
+func (s *Store) RetrieveAccountsIndex(walletID uuid.UUID) ([]byte, error) {
+	if walletID != s.walletID {
+		return nil, errors.New("wallet not found")
+	}
+
+	type indexEntry struct {
+		ID   uuid.UUID `json:"uuid"`
+		Name string    `json:"name"`
+	}
+	accounts := s.scan()
+	entries := make([]*indexEntry, len(accounts))
+	for i, account := range accounts {
+		entries[i] = &indexEntry{
+			ID:   account.UUID,
+			Name: account.Name,
+		}
+	}
+	return json.Marshal(entries)
+}
+
+// Passphrase returns the passphrase for an account from its sidecar file, if present.
+func (s *Store) Passphrase(walletName string, accountName string) ([]byte, error) {
+	if walletName != s.walletName {
+		return nil, errors.New("wallet not found")
+	}
+	if accountName == "" || strings.ContainsAny(accountName, `/\`) {
+		return nil, errors.New("invalid account name")
+	}
+	data, err := ioutil.ReadFile(filepath.Join(s.location, fmt.Sprintf("%s.txt", accountName)))
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(data, "\r\n"), nil
+}
+
+// walletData generates the data for the synthetic wallet.
+func (s *Store) walletData() []byte {
+	return []byte(fmt.Sprintf(`{"uuid":%q,"name":%q,"type":"non-deterministic","version":1}`, s.walletID.String(), s.walletName))
+}
+
+// scan reads all keystores in the store's location, returning them as accounts.
+func (s *Store) scan() []*account {
+	paths, err := filepath.Glob(filepath.Join(s.location, "*.json"))
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to list keystores")
+		return nil
+	}
+	sort.Strings(paths)
+
+	accounts := make([]*account, 0, len(paths))
+	files := make(map[uuid.UUID]string, len(paths))
+	for _, path := range paths {
+		account, err := readAccount(path)
+		if err != nil {
+			log.Warn().Err(err).Str("path", path).Msg("Failed to read keystore")
+			continue
+		}
+		if _, exists := files[account.UUID]; exists {
+			log.Warn().Str("path", path).Str("uuid", account.UUID.String()).Msg("Duplicate keystore UUID; ignoring")
+			continue
+		}
+		files[account.UUID] = path
+		accounts = append(accounts, account)
+	}
+
+	s.filesMx.Lock()
+	s.files = files
+	s.filesMx.Unlock()
+
+	return accounts
+}
+
+// readAccount reads a keystore file and returns it as an account.
+func readAccount(path string) (*account, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ks := &keystore{}
+	if err := json.Unmarshal(data, ks); err != nil {
+		return nil, err
+	}
+	if ks.Version != 4 {
+		return nil, fmt.Errorf("unsupported keystore version %d", ks.Version)
+	}
+	if ks.Crypto == nil {
+		return nil, errors.New("keystore crypto missing")
+	}
+	if ks.UUID == uuid.Nil {
+		return nil, errors.New("keystore UUID missing")
+	}
+	pubKey := strings.TrimPrefix(ks.PubKey, "0x")
+	if _, err := hex.DecodeString(pubKey); err != nil || len(pubKey) != 96 {
+		return nil, errors.New("keystore public key missing or invalid")
+	}
+
+	return &account{
+		UUID:      ks.UUID,
+		Name:      strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+		PubKey:    pubKey,
+		Crypto:    ks.Crypto,
+		Encryptor: "keystore",
+		Version:   ks.Version,
+	}, nil
+}
@@ -0,0 +1,160 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keystores_test
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+	e2wallet "github.com/wealdtech/go-eth2-wallet"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	keystoresautounlocker "github.com/wealdtech/walletd/services/autounlocker/keystores"
+	"github.com/wealdtech/walletd/stores/keystores"
+)
+
+func TestMain(m *testing.M) {
+	if err := e2types.InitBLS(); err != nil {
+		os.Exit(1)
+	}
+	os.Exit(m.Run())
+}
+
+// writeKeystore writes an EIP-2335 keystore to the given directory, optionally with a sidecar passphrase file.
+func writeKeystore(t *testing.T, dir string, name string, passphrase string, sidecar bool) []byte {
+	key, err := e2types.GenerateBLSPrivateKey()
+	require.NoError(t, err)
+	crypto, err := keystorev4.New().Encrypt(key.Marshal(), []byte(passphrase))
+	require.NoError(t, err)
+	pubKey := key.PublicKey().Marshal()
+	data, err := json.Marshal(map[string]interface{}{
+		"crypto":  crypto,
+		"pubkey":  hex.EncodeToString(pubKey),
+		"path":    "m/12381/3600/0/0/0",
+		"uuid":    uuid.New().String(),
+		"version": 4,
+	})
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf("%s.json", name)), data, 0600))
+	if sidecar {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf("%s.txt", name)), []byte(passphrase+"\n"), 0600))
+	}
+	return pubKey
+}
+
+func TestNew(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keystores")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "file")
+	require.NoError(t, ioutil.WriteFile(file, []byte("data"), 0600))
+
+	tests := []struct {
+		name       string
+		walletName string
+		location   string
+		err        string
+	}{
+		{
+			name:     "NoName",
+			location: dir,
+			err:      "no wallet name provided",
+		},
+		{
+			name:       "NoLocation",
+			walletName: "Keystores",
+			err:        "no location provided",
+		},
+		{
+			name:       "NotDirectory",
+			walletName: "Keystores",
+			location:   file,
+			err:        fmt.Sprintf("%s is not a directory", file),
+		},
+		{
+			name:       "Good",
+			walletName: "Keystores",
+			location:   dir,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := keystores.New(test.walletName, test.location)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestWallet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keystores")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	pubKey1 := writeKeystore(t, dir, "keystore-1", "secret1", true)
+	pubKey2 := writeKeystore(t, dir, "keystore-2", "secret2", false)
+	// Invalid files should be ignored.
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "bad.json"), []byte("{}"), 0600))
+
+	store, err := keystores.New("Keystores", dir)
+	require.NoError(t, err)
+
+	wallet, err := e2wallet.OpenWallet("Keystores", e2wallet.WithStore(store))
+	require.NoError(t, err)
+	assert.Equal(t, "Keystores", wallet.Name())
+
+	names := make(map[string]bool)
+	for account := range wallet.Accounts() {
+		names[account.Name()] = true
+	}
+	assert.Equal(t, map[string]bool{"keystore-1": true, "keystore-2": true}, names)
+
+	ctx := context.Background()
+	autounlocker, err := keystoresautounlocker.New(ctx)
+	require.NoError(t, err)
+
+	// Account with a sidecar passphrase should unlock.
+	account1, err := wallet.AccountByName("keystore-1")
+	require.NoError(t, err)
+	assert.Equal(t, pubKey1, account1.PublicKey().Marshal())
+	unlocked, err := autounlocker.Unlock(ctx, wallet, account1)
+	require.NoError(t, err)
+	assert.True(t, unlocked)
+	assert.True(t, account1.IsUnlocked())
+
+	// Account without a sidecar passphrase should not unlock.
+	account2, err := wallet.AccountByName("keystore-2")
+	require.NoError(t, err)
+	assert.Equal(t, pubKey2, account2.PublicKey().Marshal())
+	unlocked, err = autounlocker.Unlock(ctx, wallet, account2)
+	require.NoError(t, err)
+	assert.False(t, unlocked)
+	require.NoError(t, account2.Unlock([]byte("secret2")))
+
+	// Store is read-only.
+	assert.EqualError(t, store.StoreAccount(wallet.ID(), uuid.New(), []byte("{}")), "keystores store is read-only")
+}
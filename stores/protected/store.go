@@ -0,0 +1,152 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protected
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/google/uuid"
+	e2wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+// errProtected is returned when an attempt is made to write to the store.
+var errProtected = errors.New("store is protected")
+
+// Store is a wrapper around a store that rejects all attempts to alter its contents.
+// Wallets in a protected store cannot be managed remotely; see IsProtected().
+type Store struct {
+	store e2wtypes.Store
+}
+
+// New creates a new protected store wrapping the supplied store.
+func New(store e2wtypes.Store) (*Store, error) {
+	if store == nil {
+		return nil, errors.New("no store provided")
+	}
+	return &Store{
+		store: store,
+	}, nil
+}
+
+// IsProtected returns true if the wallet is held in a protected store.
+func IsProtected(wallet e2wtypes.Wallet) bool {
+	if wallet == nil {
+		return false
+	}
+	storeProvider, isProvider := wallet.(e2wtypes.StoreProvider)
+	if !isProvider {
+		return false
+	}
+	// The protected store can itself be wrapped, so check each store in turn.
+	store := storeProvider.Store()
+	for store != nil {
+		if _, isProtected := store.(*Store); isProtected {
+			return true
+		}
+		unwrapper, isUnwrapper := store.(storeUnwrapper)
+		if !isUnwrapper {
+			return false
+		}
+		store = unwrapper.Unwrap()
+	}
+	return false
+}
+
+// storeUnwrapper is implemented by stores that wrap another store.
+type storeUnwrapper interface {
+	Unwrap() e2wtypes.Store
+}
+
+// Unwrap returns the store wrapped by this store.
+func (s *Store) Unwrap() e2wtypes.Store {
+	return s.store
+}
+
+// Name returns the name of the wrapped store.
+func (s *Store) Name() string {
+	return s.store.Name()
+}
+
+// Location returns the location of the wrapped store, if available.
+func (s *Store) Location() string {
+	if locationProvider, isProvider := s.store.(e2wtypes.StoreLocationProvider); isProvider {
+		return locationProvider.Location()
+	}
+	return ""
+}
+
+// StoreWallet is not allowed for protected stores.
+func (s *Store) StoreWallet(walletID uuid.UUID, walletName string, data []byte) error {
+	return errProtected
+}
+
+// RetrieveWallets retrieves wallet data for all wallets.
+func (s *Store) RetrieveWallets() <-chan []byte {
+	return s.store.RetrieveWallets()
+}
+
+// RetrieveWallet retrieves wallet data for a wallet with a given name.
+func (s *Store) RetrieveWallet(walletName string) ([]byte, error) {
+	return s.store.RetrieveWallet(walletName)
+}
+
+// RetrieveWalletByID retrieves wallet data for a wallet with a given ID.
+func (s *Store) RetrieveWalletByID(walletID uuid.UUID) ([]byte, error) {
+	return s.store.RetrieveWalletByID(walletID)
+}
+
+// StoreAccount is not allowed for protected stores.
+func (s *Store) StoreAccount(walletID uuid.UUID, accountID uuid.UUID, data []byte) error {
+	return errProtected
+}
+
+// RetrieveAccounts retrieves account information for all accounts.
+func (s *Store) RetrieveAccounts(walletID uuid.UUID) <-chan []byte {
+	return s.store.RetrieveAccounts(walletID)
+}
+
+// RetrieveAccount retrieves account data for a wallet with a given ID.
+func (s *Store) RetrieveAccount(walletID uuid.UUID, accountID uuid.UUID) ([]byte, error) {
+	return s.store.RetrieveAccount(walletID, accountID)
+}
+
+// StoreAccountsIndex is not allowed for protected stores.
+func (s *Store) StoreAccountsIndex(walletID uuid.UUID, data []byte) error {
+	return errProtected
+}
+
+// RetrieveAccountsIndex retrieves the index of accounts for a given wallet.
+// Wallets will attempt to write a new index if one cannot be retrieved, which is not allowed for protected stores,
+// so if the wrapped store does not have an index one is generated here instead.
+func (s *Store) RetrieveAccountsIndex(walletID uuid.UUID) ([]byte, error) {
+	data, err := s.store.RetrieveAccountsIndex(walletID)
+	if err == nil {
+		return data, nil
+	}
+
+	type indexEntry struct {
+		ID   uuid.UUID `json:"uuid"`
+		Name string    `json:"name"`
+	}
+	entries := make([]*indexEntry, 0)
+	for data := range s.store.RetrieveAccounts(walletID) {
+		entry := &indexEntry{}
+		if err := json.Unmarshal(data, entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return json.Marshal(entries)
+}
@@ -0,0 +1,87 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protected_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+	e2wallet "github.com/wealdtech/go-eth2-wallet"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+	e2wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+	"github.com/wealdtech/walletd/stores/protected"
+)
+
+func TestMain(m *testing.M) {
+	if err := e2types.InitBLS(); err != nil {
+		os.Exit(1)
+	}
+	os.Exit(m.Run())
+}
+
+func TestNew(t *testing.T) {
+	_, err := protected.New(nil)
+	assert.EqualError(t, err, "no store provided")
+
+	_, err = protected.New(scratch.New())
+	assert.NoError(t, err)
+}
+
+func TestProtected(t *testing.T) {
+	store := scratch.New()
+	wallet, err := e2wallet.CreateWallet("Test wallet", e2wallet.WithStore(store))
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	_, err = wallet.CreateAccount("Test account", []byte("secret"))
+	require.NoError(t, err)
+	assert.False(t, protected.IsProtected(wallet))
+
+	protectedStore, err := protected.New(store)
+	require.NoError(t, err)
+	protectedWallet, err := e2wallet.OpenWallet("Test wallet", e2wallet.WithStore(protectedStore))
+	require.NoError(t, err)
+	assert.True(t, protected.IsProtected(protectedWallet))
+	assert.False(t, protected.IsProtected(nil))
+
+	// A protected store wrapped again is still protected.
+	wrappedWallet, err := e2wallet.OpenWallet("Test wallet", e2wallet.WithStore(&wrappingStore{Store: protectedStore}))
+	require.NoError(t, err)
+	assert.True(t, protected.IsProtected(wrappedWallet))
+	wrappedWallet, err = e2wallet.OpenWallet("Test wallet", e2wallet.WithStore(&wrappingStore{Store: store}))
+	require.NoError(t, err)
+	assert.False(t, protected.IsProtected(wrappedWallet))
+
+	// Existing accounts are available.
+	account, err := protectedWallet.AccountByName("Test account")
+	require.NoError(t, err)
+	assert.Equal(t, "Test account", account.Name())
+
+	// New accounts cannot be created.
+	require.NoError(t, protectedWallet.Unlock(nil))
+	_, err = protectedWallet.CreateAccount("Test account 2", []byte("secret"))
+	assert.Error(t, err)
+}
+
+// wrappingStore wraps another store.
+type wrappingStore struct {
+	e2wtypes.Store
+}
+
+// Unwrap returns the store wrapped by this store.
+func (s *wrappingStore) Unwrap() e2wtypes.Store {
+	return s.Store
+}
@@ -0,0 +1,96 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// StoreAccount stores an account.
+func (s *Store) StoreAccount(walletID uuid.UUID, accountID uuid.UUID, data []byte) error {
+	// Ensure the wallet exists.
+	if _, err := s.RetrieveWalletByID(walletID); err != nil {
+		return errors.New("unknown wallet")
+	}
+
+	// See if an account with this ID already exists.
+	existingAccount, err := s.RetrieveAccount(walletID, accountID)
+	if err == nil {
+		// It does; they need to have the same ID for us to overwrite it.
+		info := &struct {
+			ID string `json:"uuid"`
+		}{}
+		if err := json.Unmarshal(existingAccount, info); err != nil {
+			return err
+		}
+		if info.ID != accountID.String() {
+			return errors.New("account already exists")
+		}
+	}
+
+	data, err = s.encryptIfRequired(data)
+	if err != nil {
+		return err
+	}
+	if err := s.putObject(s.accountPath(walletID, accountID), data); err != nil {
+		return errors.Wrap(err, "failed to store key")
+	}
+	return nil
+}
+
+// RetrieveAccount retrieves account-level data for a given account.
+func (s *Store) RetrieveAccount(walletID uuid.UUID, accountID uuid.UUID) ([]byte, error) {
+	data, err := s.getObject(s.accountPath(walletID, accountID))
+	if err != nil {
+		return nil, err
+	}
+	return s.decryptIfRequired(data)
+}
+
+// RetrieveAccounts retrieves all account-level data for a wallet.
+func (s *Store) RetrieveAccounts(walletID uuid.UUID) <-chan []byte {
+	ch := make(chan []byte, 1024)
+	go func() {
+		defer close(ch)
+		walletPath := s.walletPath(walletID)
+		keys, err := s.listObjects(walletPath + "/")
+		if err != nil {
+			log.Warn().Err(err).Str("bucket", s.bucket).Msg("Failed to list accounts")
+			return
+		}
+		for _, key := range keys {
+			name := strings.TrimPrefix(key, walletPath+"/")
+			if _, err := uuid.Parse(name); err != nil || name == walletPath {
+				// Not an account.
+				continue
+			}
+			data, err := s.getObject(key)
+			if err != nil {
+				log.Warn().Err(err).Str("key", key).Msg("Failed to retrieve account")
+				continue
+			}
+			data, err = s.decryptIfRequired(data)
+			if err != nil {
+				log.Warn().Err(err).Str("key", key).Msg("Failed to decrypt account")
+				continue
+			}
+			ch <- data
+		}
+	}()
+	return ch
+}
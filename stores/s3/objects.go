@@ -0,0 +1,67 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"bytes"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// putObject stores an object in the bucket.
+func (s *Store) putObject(key string, data []byte) error {
+	uploader := s3manager.NewUploader(s.session)
+	_, err := uploader.Upload(&s3manager.UploadInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(data),
+	})
+	return err
+}
+
+// getObject retrieves an object from the bucket.
+func (s *Store) getObject(key string) ([]byte, error) {
+	buf := aws.NewWriteAtBuffer([]byte{})
+	downloader := s3manager.NewDownloader(s.session)
+	if _, err := downloader.Download(buf, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// listObjects lists the keys of all objects in the bucket with the given prefix.
+func (s *Store) listObjects(prefix string) ([]string, error) {
+	keys := make([]string, 0)
+	conn := s3.New(s.session)
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+	}
+	if prefix != "" {
+		input.Prefix = aws.String(prefix)
+	}
+	if err := conn.ListObjectsV2Pages(input, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, item := range page.Contents {
+			keys = append(keys, aws.StringValue(item.Key))
+		}
+		return true
+	}); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
@@ -0,0 +1,186 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/pkg/errors"
+	util "github.com/wealdtech/go-eth2-util"
+)
+
+// Store is a wallet store held in an S3 bucket.
+// The layout of the bucket, and its default name, are compatible with those of go-eth2-wallet-store-s3.  This store
+// exists because the release of go-eth2-wallet-store-s3 in use (v1.7.0) cannot be configured with an endpoint, bucket
+// or credentials; it should be replaced by the upstream store once a release that supports these is adopted.
+type Store struct {
+	session    *session.Session
+	bucket     string
+	passphrase []byte
+}
+
+type options struct {
+	id              []byte
+	region          string
+	bucket          string
+	endpoint        string
+	accessKeyID     string
+	secretAccessKey string
+	passphrase      []byte
+	readOnly        bool
+}
+
+// Option is an option for the S3 store.
+type Option func(*options)
+
+// WithPassphrase sets the passphrase used to encrypt the contents of the store.
+func WithPassphrase(passphrase []byte) Option {
+	return func(o *options) {
+		o.passphrase = passphrase
+	}
+}
+
+// WithID sets the ID used when generating the default bucket name.
+func WithID(id []byte) Option {
+	return func(o *options) {
+		o.id = id
+	}
+}
+
+// WithRegion sets the region of the store.
+func WithRegion(region string) Option {
+	return func(o *options) {
+		o.region = region
+	}
+}
+
+// WithBucket sets the bucket of the store.
+// If not supplied the bucket name is generated from the access key ID and store ID.
+func WithBucket(bucket string) Option {
+	return func(o *options) {
+		o.bucket = bucket
+	}
+}
+
+// WithEndpoint sets the endpoint of the store, for use with S3-compatible servers.
+// Path-style addressing is used when an endpoint is supplied.
+func WithEndpoint(endpoint string) Option {
+	return func(o *options) {
+		o.endpoint = endpoint
+	}
+}
+
+// WithCredentials sets the credentials used to access the store.
+// If not supplied credentials are obtained from the environment in the same way as other AWS tools.
+func WithCredentials(accessKeyID string, secretAccessKey string) Option {
+	return func(o *options) {
+		o.accessKeyID = accessKeyID
+		o.secretAccessKey = secretAccessKey
+	}
+}
+
+// WithReadOnly sets if the store is only to be read.
+// The bucket of a read-only store is not created if it does not exist.
+func WithReadOnly(readOnly bool) Option {
+	return func(o *options) {
+		o.readOnly = readOnly
+	}
+}
+
+// New creates a new S3 store, creating its bucket if it does not already exist unless the store is read-only.
+func New(opts ...Option) (*Store, error) {
+	options := &options{
+		region: "us-east-1",
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	config := &aws.Config{
+		Region: aws.String(options.region),
+	}
+	if options.endpoint != "" {
+		config.Endpoint = aws.String(options.endpoint)
+		config.S3ForcePathStyle = aws.Bool(true)
+	}
+	if options.accessKeyID != "" || options.secretAccessKey != "" {
+		if options.accessKeyID == "" {
+			return nil, errors.New("secret access key supplied without access key ID")
+		}
+		if options.secretAccessKey == "" {
+			return nil, errors.New("access key ID supplied without secret access key")
+		}
+		config.Credentials = credentials.NewStaticCredentials(options.accessKeyID, options.secretAccessKey, "")
+	}
+
+	session, err := session.NewSession(config)
+	if err != nil {
+		return nil, err
+	}
+
+	bucket := options.bucket
+	if bucket == "" {
+		creds, err := session.Config.Credentials.Get()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to obtain credentials")
+		}
+		bucket = defaultBucketName(creds.AccessKeyID, options.id)
+	}
+
+	// Check the bucket exists; if not create it.
+	conn := s3.New(session)
+	if _, err := conn.HeadBucket(&s3.HeadBucketInput{Bucket: aws.String(bucket)}); err != nil {
+		if aerr, isAWSErr := err.(awserr.Error); !isAWSErr || (aerr.Code() != s3.ErrCodeNoSuchBucket && aerr.Code() != "NotFound") {
+			return nil, errors.Wrap(err, "unable to access bucket")
+		}
+		if options.readOnly {
+			return nil, fmt.Errorf("bucket %s does not exist", bucket)
+		}
+		if _, err := conn.CreateBucket(&s3.CreateBucketInput{Bucket: aws.String(bucket)}); err != nil {
+			return nil, errors.Wrap(err, "unable to create bucket")
+		}
+		if err := conn.WaitUntilBucketExists(&s3.HeadBucketInput{Bucket: aws.String(bucket)}); err != nil {
+			return nil, errors.Wrap(err, "failed to confirm bucket creation")
+		}
+	}
+
+	return &Store{
+		session:    session,
+		bucket:     bucket,
+		passphrase: options.passphrase,
+	}, nil
+}
+
+// Name returns the name of this store.
+func (s *Store) Name() string {
+	return "s3"
+}
+
+// Location returns the location of this store.
+func (s *Store) Location() string {
+	return s.bucket
+}
+
+// defaultBucketName generates a bucket name from the access key ID and store ID.  This is the SHA256 hash of a
+// string unique to the account, as a hex string truncated to the 63 characters that S3 allows for bucket names.
+func defaultBucketName(accessKeyID string, id []byte) string {
+	hash := util.SHA256([]byte(fmt.Sprintf("Ethereum 2 wallet:%s", accessKeyID)), id)
+	return hex.EncodeToString(hash)[:63]
+}
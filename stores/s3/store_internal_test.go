@@ -0,0 +1,69 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultBucketName(t *testing.T) {
+	tests := []struct {
+		name        string
+		accessKeyID string
+		id          []byte
+		bucket      string
+	}{
+		{
+			name:        "NoID",
+			accessKeyID: "AKIAEXAMPLE",
+			bucket:      "63308b510491fc5b874a7d8d7b3703caf4940157dcff9daaea0108ede5e8ddb",
+		},
+		{
+			name:        "ID",
+			accessKeyID: "AKIAEXAMPLE",
+			id:          []byte("second"),
+			bucket:      "ffe6062d7898b8132425358605cd0b1f9fbff6f0c18f99eabbaf8bc079253b6",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.bucket, defaultBucketName(test.accessKeyID, test.id))
+		})
+	}
+}
+
+func TestEncryption(t *testing.T) {
+	data := []byte(`{"uuid":"c9958061-63d4-4a80-bcf3-25f3dda22340","name":"test wallet"}`)
+
+	// No passphrase; data should pass through.
+	store := &Store{}
+	encrypted, err := store.encryptIfRequired(data)
+	require.NoError(t, err)
+	assert.Equal(t, data, encrypted)
+
+	store = &Store{passphrase: []byte("secret")}
+	encrypted, err = store.encryptIfRequired(data)
+	require.NoError(t, err)
+	assert.NotEqual(t, data, encrypted)
+	decrypted, err := store.decryptIfRequired(encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, data, decrypted)
+
+	_, err = store.encryptIfRequired([]byte("short"))
+	assert.EqualError(t, err, "data must be at least 16 bytes")
+}
@@ -0,0 +1,89 @@
+// Copyright © 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// StoreWallet stores wallet-level data.
+func (s *Store) StoreWallet(id uuid.UUID, name string, data []byte) error {
+	data, err := s.encryptIfRequired(data)
+	if err != nil {
+		return errors.Wrap(err, "failed to encrypt wallet")
+	}
+	if err := s.putObject(s.walletHeaderPath(id), data); err != nil {
+		return errors.Wrap(err, "failed to store wallet")
+	}
+	return nil
+}
+
+// RetrieveWallet retrieves wallet-level data for a wallet with a given name.
+func (s *Store) RetrieveWallet(walletName string) ([]byte, error) {
+	for data := range s.RetrieveWallets() {
+		info := &struct {
+			Name string `json:"name"`
+		}{}
+		err := json.Unmarshal(data, info)
+		if err == nil && info.Name == walletName {
+			return data, nil
+		}
+	}
+	return nil, errors.New("wallet not found")
+}
+
+// RetrieveWalletByID retrieves wallet-level data for a wallet with a given ID.
+func (s *Store) RetrieveWalletByID(walletID uuid.UUID) ([]byte, error) {
+	data, err := s.getObject(s.walletHeaderPath(walletID))
+	if err != nil {
+		return nil, errors.New("wallet not found")
+	}
+	return s.decryptIfRequired(data)
+}
+
+// RetrieveWallets retrieves wallet-level data for all wallets.
+func (s *Store) RetrieveWallets() <-chan []byte {
+	ch := make(chan []byte, 1024)
+	go func() {
+		defer close(ch)
+		keys, err := s.listObjects("")
+		if err != nil {
+			log.Warn().Err(err).Str("bucket", s.bucket).Msg("Failed to list wallets")
+			return
+		}
+		for _, key := range keys {
+			// Wallets are stored at <id>/<id>.
+			parts := strings.Split(key, "/")
+			if len(parts) != 2 || parts[0] != parts[1] {
+				continue
+			}
+			data, err := s.getObject(key)
+			if err != nil {
+				log.Warn().Err(err).Str("key", key).Msg("Failed to retrieve wallet")
+				continue
+			}
+			data, err = s.decryptIfRequired(data)
+			if err != nil {
+				log.Warn().Err(err).Str("key", key).Msg("Failed to decrypt wallet")
+				continue
+			}
+			ch <- data
+		}
+	}()
+	return ch
+}
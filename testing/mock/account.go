@@ -16,6 +16,7 @@ package mock
 import (
 	"bytes"
 	"errors"
+	"sync"
 
 	"github.com/google/uuid"
 	e2types "github.com/wealdtech/go-eth2-types/v2"
@@ -27,6 +28,8 @@ type Account struct {
 	privateKey *e2types.BLSPrivateKey
 	unlocked   bool
 	passphrase []byte
+	attempts   int
+	mutex      sync.RWMutex
 }
 
 // NewAccount creates a new account.
@@ -70,11 +73,16 @@ func (a *Account) Path() string {
 
 // Lock locks the account.  A locked account cannot sign.
 func (a *Account) Lock() {
+	a.mutex.Lock()
 	a.unlocked = false
+	a.mutex.Unlock()
 }
 
 // Unlock unlocks the account.  An unlocked account can sign.
 func (a *Account) Unlock(passphrase []byte) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.attempts++
 	if bytes.Equal(a.passphrase, passphrase) {
 		a.unlocked = true
 		return nil
@@ -82,8 +90,17 @@ func (a *Account) Unlock(passphrase []byte) error {
 	return errors.New("invalid passphrase")
 }
 
+// UnlockAttempts returns the number of attempts that have been made to unlock the account.
+func (a *Account) UnlockAttempts() int {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	return a.attempts
+}
+
 // IsUnlocked returns true if the account is unlocked.
 func (a *Account) IsUnlocked() bool {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
 	return a.unlocked
 }
 